@@ -18,6 +18,7 @@ package mock
 import (
 	"context"
 	"hash/fnv"
+	"sync/atomic"
 )
 
 // MockEmbedder is a test double for ai.Embedder.
@@ -31,7 +32,7 @@ type MockEmbedder struct {
 	// If nil, uses default deterministic behavior.
 	EmbedTextsFunc func(ctx context.Context, texts []string) ([][]float32, error)
 
-	callCount int
+	callCount atomic.Int64 // Atomic so searches can call the mock from concurrent stages
 }
 
 // NewMockEmbedder creates a mock embedder with default deterministic behavior.
@@ -42,7 +43,7 @@ func NewMockEmbedder() *MockEmbedder {
 
 // EmbedText generates a deterministic embedding based on text hash.
 func (m *MockEmbedder) EmbedText(ctx context.Context, text string) ([]float32, error) {
-	m.callCount++
+	m.callCount.Add(1)
 
 	if m.EmbedTextFunc != nil {
 		return m.EmbedTextFunc(ctx, text)
@@ -54,7 +55,7 @@ func (m *MockEmbedder) EmbedText(ctx context.Context, text string) ([]float32, e
 
 // EmbedTexts generates deterministic embeddings for multiple texts.
 func (m *MockEmbedder) EmbedTexts(ctx context.Context, texts []string) ([][]float32, error) {
-	m.callCount++
+	m.callCount.Add(1)
 
 	if m.EmbedTextsFunc != nil {
 		return m.EmbedTextsFunc(ctx, texts)
@@ -70,12 +71,12 @@ func (m *MockEmbedder) EmbedTexts(ctx context.Context, texts []string) ([][]floa
 
 // CallCount returns the number of times any method was called.
 func (m *MockEmbedder) CallCount() int {
-	return m.callCount
+	return int(m.callCount.Load())
 }
 
 // Reset clears the call count.
 func (m *MockEmbedder) Reset() {
-	m.callCount = 0
+	m.callCount.Store(0)
 	m.EmbedTextFunc = nil
 	m.EmbedTextsFunc = nil
 }
@@ -18,6 +18,7 @@ package mock
 import (
 	"context"
 	"strings"
+	"sync/atomic"
 
 	"github.com/poiesic/memorit/ai"
 )
@@ -29,7 +30,7 @@ type MockConceptExtractor struct {
 	// If nil, uses default simple word extraction.
 	ExtractConceptsFunc func(ctx context.Context, text string) ([]ai.ExtractedConcept, error)
 
-	callCount int
+	callCount atomic.Int64 // Atomic so searches can call the mock from concurrent stages
 }
 
 // NewMockConceptExtractor creates a mock concept extractor with default behavior.
@@ -41,7 +42,7 @@ func NewMockConceptExtractor() *MockConceptExtractor {
 // ExtractConcepts extracts simple mock concepts from text.
 // Default behavior: splits text by spaces and creates concepts from words.
 func (m *MockConceptExtractor) ExtractConcepts(ctx context.Context, text string) ([]ai.ExtractedConcept, error) {
-	m.callCount++
+	m.callCount.Add(1)
 
 	if m.ExtractConceptsFunc != nil {
 		return m.ExtractConceptsFunc(ctx, text)
@@ -90,11 +91,11 @@ func (m *MockConceptExtractor) ExtractConcepts(ctx context.Context, text string)
 
 // CallCount returns the number of times ExtractConcepts was called.
 func (m *MockConceptExtractor) CallCount() int {
-	return m.callCount
+	return int(m.callCount.Load())
 }
 
 // Reset clears the call count and custom functions.
 func (m *MockConceptExtractor) Reset() {
-	m.callCount = 0
+	m.callCount.Store(0)
 	m.ExtractConceptsFunc = nil
 }
@@ -39,4 +39,12 @@
 //	// Use the services
 //	embeddings, err := provider.Embedder().EmbedText(ctx, "sample text")
 //	concepts, err := provider.ConceptExtractor().ExtractConcepts(ctx, "The Eiffel Tower is in Paris")
+//
+// # Reranking
+//
+// Reranker asks the classifier model to judge how relevant search candidates
+// are to a query. It satisfies search.Reranker:
+//
+//	reranker, err := openai.NewReranker(config, openai.WithRerankMode(openai.RerankPointwise))
+//	searcher, err := db.NewSearcher(search.WithReranker(reranker), search.WithRerankTimeout(2*time.Second))
 package openai
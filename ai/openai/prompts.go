@@ -156,3 +156,20 @@ func buildSystemPrompt() string {
 		classificationResponseSchema,
 		strings.Join(ai.ConceptTypes, ", "))
 }
+
+const listwisePrompt = `You are a search relevance judge. Given a query and a numbered list of remembered chat messages, rate how useful each message is for answering the query.
+
+Output ONLY valid JSON of the form {"scores":[{"id":1,"score":7},{"id":2,"score":0}]} with one entry per message.
+Do not include any preamble or explanation.
+
+Rules:
+- id is the number shown in brackets before the message.
+- score is an integer from 0 (irrelevant) to 10 (directly answers the query).
+- Judge each message on its own content; ignore its position in the list.`
+
+const pointwisePrompt = `You are a search relevance judge. Given a query and a remembered chat message, rate how useful the message is for answering the query.
+
+Output ONLY valid JSON of the form {"score":7}. Do not include any preamble or explanation.
+
+Rules:
+- score is an integer from 0 (irrelevant) to 10 (directly answers the query).`
@@ -0,0 +1,229 @@
+// Copyright 2025 Poiesic Systems
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+
+	"github.com/poiesic/memorit/ai"
+	"github.com/poiesic/memorit/core"
+	"github.com/tmc/langchaingo/llms"
+	"github.com/tmc/langchaingo/llms/openai"
+)
+
+// RerankMode selects how the LLM is asked to judge relevance.
+type RerankMode int
+
+const (
+	// RerankListwise judges every candidate in a single request. This is the default.
+	RerankListwise RerankMode = iota
+	// RerankPointwise judges each candidate in its own request, concurrently.
+	// It costs more requests but keeps each prompt short.
+	RerankPointwise
+)
+
+// maxRelevance is the top of the 0-10 relevance scale used in the prompts.
+const maxRelevance = 10
+
+// Reranker scores search candidates with relevance judgments from an
+// OpenAI-compatible chat model. It satisfies search.Reranker.
+type Reranker struct {
+	client llms.Model
+	mode   RerankMode
+	logger *slog.Logger
+}
+
+// RerankerOption configures a Reranker.
+type RerankerOption func(*Reranker)
+
+// WithRerankMode sets how candidates are judged. Default is RerankListwise.
+func WithRerankMode(mode RerankMode) RerankerOption {
+	return func(r *Reranker) {
+		r.mode = mode
+	}
+}
+
+// WithRerankModel replaces the chat model, for example to use a different
+// model than the concept classifier.
+func WithRerankModel(client llms.Model) RerankerOption {
+	return func(r *Reranker) {
+		r.client = client
+	}
+}
+
+// NewReranker creates a reranker that uses the classifier host and model from config.
+func NewReranker(config *ai.Config, opts ...RerankerOption) (*Reranker, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	client, err := openai.New(
+		openai.WithBaseURL(config.ClassifierHost),
+		openai.WithToken("none"),
+		openai.WithModel(config.ClassifierModel),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Reranker{
+		client: client,
+		logger: slog.Default().With("component", "openai-reranker"),
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r, nil
+}
+
+// Rerank returns a relevance score in [0, 1] for each record, in order.
+func (r *Reranker) Rerank(ctx context.Context, query string, records []*core.ChatRecord) ([]float32, error) {
+	if len(records) == 0 {
+		return []float32{}, nil
+	}
+	if r.mode == RerankPointwise {
+		return r.rerankPointwise(ctx, query, records)
+	}
+	return r.rerankListwise(ctx, query, records)
+}
+
+// listwiseJudgment is the expected listwise response.
+type listwiseJudgment struct {
+	Scores []struct {
+		ID    int `json:"id"`
+		Score int `json:"score"`
+	} `json:"scores"`
+}
+
+// pointwiseJudgment is the expected pointwise response.
+type pointwiseJudgment struct {
+	Score int `json:"score"`
+}
+
+func (r *Reranker) rerankListwise(ctx context.Context, query string, records []*core.ChatRecord) ([]float32, error) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Query: %s\n\nMessages:\n", query)
+	for i, record := range records {
+		fmt.Fprintf(&b, "[%d] %s\n", i+1, formatCandidate(record))
+	}
+
+	var judgment listwiseJudgment
+	if err := r.judge(ctx, listwisePrompt, b.String(), &judgment); err != nil {
+		return nil, err
+	}
+
+	// Candidates the model skipped score zero
+	scores := make([]float32, len(records))
+	for _, s := range judgment.Scores {
+		if s.ID < 1 || s.ID > len(records) {
+			r.logger.Warn("reranker returned unknown candidate", "id", s.ID)
+			continue
+		}
+		scores[s.ID-1] = normalizeRelevance(s.Score)
+	}
+	return scores, nil
+}
+
+func (r *Reranker) rerankPointwise(ctx context.Context, query string, records []*core.ChatRecord) ([]float32, error) {
+	scores := make([]float32, len(records))
+	errs := make([]error, len(records))
+
+	var wg sync.WaitGroup
+	for i, record := range records {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			prompt := fmt.Sprintf("Query: %s\n\nMessage: %s", query, formatCandidate(record))
+			var judgment pointwiseJudgment
+			if err := r.judge(ctx, pointwisePrompt, prompt, &judgment); err != nil {
+				errs[i] = err
+				return
+			}
+			scores[i] = normalizeRelevance(judgment.Score)
+		}()
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return scores, nil
+}
+
+// judge sends the prompts and decodes the JSON response into v,
+// retrying up to 3 times in case of malformed JSON.
+func (r *Reranker) judge(ctx context.Context, systemPrompt, userPrompt string, v any) error {
+	content := []llms.MessageContent{
+		{
+			Role:  llms.ChatMessageTypeSystem,
+			Parts: []llms.ContentPart{llms.TextPart(systemPrompt)},
+		},
+		{
+			Role:  llms.ChatMessageTypeHuman,
+			Parts: []llms.ContentPart{llms.TextPart(userPrompt)},
+		},
+	}
+
+	var lastErr error
+	for attempt := 0; attempt < 3; attempt++ {
+		response, err := r.client.GenerateContent(ctx, content, llms.WithTemperature(0.0), llms.WithJSONMode())
+		if err != nil {
+			r.logger.Error("failed to generate content", "attempt", attempt+1, "err", err)
+			return err
+		}
+		if len(response.Choices) < 1 {
+			return fmt.Errorf("no choices returned from model")
+		}
+
+		responseText := strings.TrimSpace(response.Choices[0].Content)
+		responseText = strings.TrimPrefix(responseText, "```json")
+		responseText = strings.TrimPrefix(responseText, "```")
+		responseText = strings.TrimSuffix(responseText, "```")
+		responseText = repairJSON(strings.TrimSpace(responseText))
+
+		if err := json.Unmarshal([]byte(responseText), v); err != nil {
+			lastErr = err
+			r.logger.Warn("error parsing reranker response", "attempt", attempt+1, "response", responseText, "err", err)
+			continue
+		}
+		return nil
+	}
+
+	r.logger.Error("failed to parse reranker response after retries", "err", lastErr)
+	return lastErr
+}
+
+// formatCandidate renders a record for a prompt on a single line.
+func formatCandidate(record *core.ChatRecord) string {
+	speaker := "user"
+	if record.Speaker == core.SpeakerTypeAI {
+		speaker = "assistant"
+	}
+	contents := strings.Join(strings.Fields(record.Contents), " ")
+	return fmt.Sprintf("(%s, %s) %s", speaker, record.Timestamp.Format("2006-01-02"), contents)
+}
+
+// normalizeRelevance maps a 0-10 judgment to [0, 1], clamping out-of-range values.
+func normalizeRelevance(score int) float32 {
+	return float32(min(max(score, 0), maxRelevance)) / maxRelevance
+}
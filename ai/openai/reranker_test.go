@@ -0,0 +1,88 @@
+package openai
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/poiesic/memorit/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tmc/langchaingo/llms"
+)
+
+// fakeModel answers every request with respond(userPrompt).
+type fakeModel struct {
+	respond func(prompt string) string
+	calls   atomic.Int32
+}
+
+func (m *fakeModel) GenerateContent(ctx context.Context, messages []llms.MessageContent, options ...llms.CallOption) (*llms.ContentResponse, error) {
+	m.calls.Add(1)
+	prompt := messages[len(messages)-1].Parts[0].(llms.TextContent).Text
+	return &llms.ContentResponse{Choices: []*llms.ContentChoice{{Content: m.respond(prompt)}}}, nil
+}
+
+func (m *fakeModel) Call(ctx context.Context, prompt string, options ...llms.CallOption) (string, error) {
+	return m.respond(prompt), nil
+}
+
+func testLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+func testRecords() []*core.ChatRecord {
+	return []*core.ChatRecord{
+		{Id: 1, Speaker: core.SpeakerTypeHuman, Contents: "I moved to Lisbon"},
+		{Id: 2, Speaker: core.SpeakerTypeAI, Contents: "Noted, you like\n  hiking"},
+		{Id: 3, Speaker: core.SpeakerTypeHuman, Contents: "Lisbon rent is high"},
+	}
+}
+
+func TestReranker_Listwise(t *testing.T) {
+	model := &fakeModel{respond: func(prompt string) string {
+		return "```json\n" + `{"scores":[{"id":1,"score":9},{"id":3,"score":14},{"id":7,"score":5}]}` + "\n```"
+	}}
+	reranker := &Reranker{client: model, logger: testLogger()}
+
+	scores, err := reranker.Rerank(context.Background(), "where do I live", testRecords())
+	require.NoError(t, err)
+	// Skipped candidates score zero, out-of-range scores are clamped, unknown IDs are ignored
+	assert.Equal(t, []float32{0.9, 0, 1}, scores)
+	assert.Equal(t, int32(1), model.calls.Load())
+}
+
+func TestReranker_Pointwise(t *testing.T) {
+	model := &fakeModel{respond: func(prompt string) string {
+		if strings.Contains(prompt, "Lisbon") {
+			return `{"score":8}`
+		}
+		return `{"score":1}`
+	}}
+	reranker := &Reranker{client: model, logger: testLogger()}
+	WithRerankMode(RerankPointwise)(reranker)
+
+	scores, err := reranker.Rerank(context.Background(), "where do I live", testRecords())
+	require.NoError(t, err)
+	assert.Equal(t, []float32{0.8, 0.1, 0.8}, scores)
+	assert.Equal(t, int32(3), model.calls.Load())
+}
+
+func TestReranker_MalformedResponse(t *testing.T) {
+	model := &fakeModel{respond: func(prompt string) string {
+		return "not json"
+	}}
+	reranker := &Reranker{client: model, logger: testLogger()}
+
+	_, err := reranker.Rerank(context.Background(), "query", testRecords())
+	assert.Error(t, err)
+	assert.Equal(t, int32(3), model.calls.Load())
+}
+
+func TestFormatCandidate(t *testing.T) {
+	record := testRecords()[1]
+	assert.Equal(t, "(assistant, 0001-01-01) Noted, you like hiking", formatCandidate(record))
+}
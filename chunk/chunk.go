@@ -0,0 +1,177 @@
+// Copyright 2025 Poiesic Systems
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+
+package chunk
+
+import (
+	"errors"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/poiesic/memorit/core"
+)
+
+// Mode selects the unit used to measure chunk size and overlap.
+type Mode int
+
+const (
+	// ModeTokens measures chunks in whitespace-delimited tokens.
+	// This approximates model tokens without depending on a specific tokenizer.
+	ModeTokens Mode = iota + 1
+	// ModeSentences measures chunks in sentences.
+	// Sentences end at '.', '!' or '?' followed by whitespace, or at a line break.
+	ModeSentences
+)
+
+// Config controls how text is split into chunks.
+type Config struct {
+	// Mode selects tokens or sentences as the unit for Size and Overlap.
+	// Default: ModeTokens
+	Mode Mode
+
+	// Size is the maximum number of units in a chunk.
+	// Text with no more than Size units is not chunked.
+	// Default: 128
+	Size int
+
+	// Overlap is the number of units shared by consecutive chunks.
+	// Must be smaller than Size.
+	// Default: 32
+	Overlap int
+}
+
+// DefaultConfig returns a Config that chunks by tokens with a modest overlap.
+func DefaultConfig() *Config {
+	return &Config{
+		Mode:    ModeTokens,
+		Size:    128,
+		Overlap: 32,
+	}
+}
+
+// Validate checks that the configuration is usable.
+func (c *Config) Validate() error {
+	if c.Mode != ModeTokens && c.Mode != ModeSentences {
+		return errors.New("chunk config: Mode must be ModeTokens or ModeSentences")
+	}
+	if c.Size < 1 {
+		return errors.New("chunk config: Size must be greater than 0")
+	}
+	if c.Overlap < 0 || c.Overlap >= c.Size {
+		return errors.New("chunk config: Overlap must be between 0 and Size-1")
+	}
+	return nil
+}
+
+// Split divides text into overlapping spans according to cfg.
+// Returns nil if the text fits in a single chunk, since the record's
+// whole-message vector already covers it.
+func Split(text string, cfg *Config) []core.Span {
+	var units []core.Span
+	if cfg.Mode == ModeSentences {
+		units = Sentences(text)
+	} else {
+		units = Tokens(text)
+	}
+	if len(units) <= cfg.Size {
+		return nil
+	}
+
+	step := cfg.Size - cfg.Overlap
+	spans := make([]core.Span, 0, len(units)/step+1)
+	for start := 0; start < len(units); start += step {
+		end := min(start+cfg.Size, len(units))
+		spans = append(spans, core.Span{Start: units[start].Start, End: units[end-1].End})
+		if end == len(units) {
+			break
+		}
+	}
+	return spans
+}
+
+// Tokens returns the spans of the whitespace-delimited tokens in text.
+func Tokens(text string) []core.Span {
+	var spans []core.Span
+	start := -1
+	for i, r := range text {
+		if unicode.IsSpace(r) {
+			if start >= 0 {
+				spans = append(spans, core.Span{Start: start, End: i})
+				start = -1
+			}
+			continue
+		}
+		if start < 0 {
+			start = i
+		}
+	}
+	if start >= 0 {
+		spans = append(spans, core.Span{Start: start, End: len(text)})
+	}
+	return spans
+}
+
+// Sentences returns the spans of the sentences in text.
+// Leading and trailing whitespace is excluded from each span.
+func Sentences(text string) []core.Span {
+	var spans []core.Span
+	start := 0
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		next := i + size
+
+		boundary := r == '\n'
+		if r == '.' || r == '!' || r == '?' {
+			if next == len(text) {
+				boundary = true
+			} else {
+				following, _ := utf8.DecodeRuneInString(text[next:])
+				boundary = unicode.IsSpace(following)
+			}
+		}
+
+		if boundary {
+			if span, ok := trimSpan(text, start, next); ok {
+				spans = append(spans, span)
+			}
+			start = next
+		}
+		i = next
+	}
+	if span, ok := trimSpan(text, start, len(text)); ok {
+		spans = append(spans, span)
+	}
+	return spans
+}
+
+// trimSpan narrows [start, end) to exclude surrounding whitespace.
+// Returns false if nothing but whitespace remains.
+func trimSpan(text string, start, end int) (core.Span, bool) {
+	for start < end {
+		r, size := utf8.DecodeRuneInString(text[start:end])
+		if !unicode.IsSpace(r) {
+			break
+		}
+		start += size
+	}
+	for end > start {
+		r, size := utf8.DecodeLastRuneInString(text[start:end])
+		if !unicode.IsSpace(r) {
+			break
+		}
+		end -= size
+	}
+	return core.Span{Start: start, End: end}, end > start
+}
@@ -0,0 +1,132 @@
+package chunk
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/poiesic/memorit/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func spanTexts(text string, spans []core.Span) []string {
+	texts := make([]string, len(spans))
+	for i, span := range spans {
+		texts[i] = span.Of(text)
+	}
+	return texts
+}
+
+func TestTokens(t *testing.T) {
+	text := "  hello   wide\tworld\n"
+	spans := Tokens(text)
+	assert.Equal(t, []string{"hello", "wide", "world"}, spanTexts(text, spans))
+	assert.Empty(t, Tokens("   "))
+}
+
+func TestSentences(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		expected []string
+	}{
+		{
+			name:     "terminal punctuation",
+			text:     "First one. Second one! Third one?",
+			expected: []string{"First one.", "Second one!", "Third one?"},
+		},
+		{
+			name:     "decimal points do not split",
+			text:     "Version 1.5 shipped. It works.",
+			expected: []string{"Version 1.5 shipped.", "It works."},
+		},
+		{
+			name:     "line breaks split",
+			text:     "func main() {\n\treturn\n}",
+			expected: []string{"func main() {", "return", "}"},
+		},
+		{
+			name:     "trailing text without punctuation",
+			text:     "Done. and then",
+			expected: []string{"Done.", "and then"},
+		},
+		{
+			name:     "multibyte runes",
+			text:     "Héllo wörld. Ça va?",
+			expected: []string{"Héllo wörld.", "Ça va?"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, spanTexts(tt.text, Sentences(tt.text)))
+		})
+	}
+}
+
+func TestSplit_ShortTextNotChunked(t *testing.T) {
+	cfg := &Config{Mode: ModeTokens, Size: 5, Overlap: 1}
+	assert.Nil(t, Split("one two three four five", cfg))
+	assert.Nil(t, Split("", cfg))
+}
+
+func TestSplit_TokensWithOverlap(t *testing.T) {
+	text := "a b c d e f g h"
+	cfg := &Config{Mode: ModeTokens, Size: 4, Overlap: 1}
+
+	spans := Split(text, cfg)
+	assert.Equal(t, []string{"a b c d", "d e f g", "g h"}, spanTexts(text, spans))
+}
+
+func TestSplit_Sentences(t *testing.T) {
+	text := "One. Two. Three. Four. Five."
+	cfg := &Config{Mode: ModeSentences, Size: 2, Overlap: 0}
+
+	spans := Split(text, cfg)
+	assert.Equal(t, []string{"One. Two.", "Three. Four.", "Five."}, spanTexts(text, spans))
+}
+
+func TestSplit_CoversEntireText(t *testing.T) {
+	words := make([]string, 1000)
+	for i := range words {
+		words[i] = "word"
+	}
+	text := strings.Join(words, " ")
+	cfg := DefaultConfig()
+
+	spans := Split(text, cfg)
+	require.NotEmpty(t, spans)
+	assert.Equal(t, 0, spans[0].Start)
+	assert.Equal(t, len(text), spans[len(spans)-1].End)
+	for i := 1; i < len(spans); i++ {
+		// Consecutive chunks overlap
+		assert.Less(t, spans[i].Start, spans[i-1].End)
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr bool
+	}{
+		{name: "default", cfg: *DefaultConfig()},
+		{name: "sentences", cfg: Config{Mode: ModeSentences, Size: 3, Overlap: 1}},
+		{name: "no overlap", cfg: Config{Mode: ModeTokens, Size: 3}},
+		{name: "invalid mode", cfg: Config{Size: 3}, wantErr: true},
+		{name: "zero size", cfg: Config{Mode: ModeTokens}, wantErr: true},
+		{name: "overlap equals size", cfg: Config{Mode: ModeTokens, Size: 3, Overlap: 3}, wantErr: true},
+		{name: "negative overlap", cfg: Config{Mode: ModeTokens, Size: 3, Overlap: -1}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.cfg.Validate()
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
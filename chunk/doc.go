@@ -0,0 +1,27 @@
+// Copyright 2025 Poiesic Systems
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+
+// Package chunk splits long text into overlapping spans for multi-vector embedding.
+//
+// A single embedding for a long message (a pasted document or code block)
+// is either truncated by the embedding model or diluted across many topics.
+// Splitting the message into chunks and embedding each one lets a query
+// match the part of the message it is actually about.
+//
+// Chunks are measured either in whitespace-delimited tokens or in sentences,
+// and consecutive chunks share a configurable overlap so that content near a
+// boundary is fully represented in at least one chunk. Spans are byte offsets
+// into the original text, so callers can recover the exact matched passage.
+package chunk
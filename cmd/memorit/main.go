@@ -17,16 +17,23 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
 	"log"
 	"log/slog"
 	"os"
 	"strings"
 	"time"
 
+	"github.com/poiesic/memorit"
 	"github.com/poiesic/memorit/ai"
+	"github.com/poiesic/memorit/ai/mock"
 	"github.com/poiesic/memorit/ai/openai"
+	"github.com/poiesic/memorit/core"
+	"github.com/poiesic/memorit/eval"
 	"github.com/poiesic/memorit/reembed"
+	"github.com/poiesic/memorit/search"
 	"github.com/poiesic/memorit/storage/badger"
 	"github.com/urfave/cli/v2"
 )
@@ -183,6 +190,168 @@ func main() {
 					},
 				},
 			},
+			{
+				Name:   "quantize",
+				Usage:  "Quantize stored vectors for faster similarity search",
+				Action: quantizeCommand,
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:     "db",
+						Aliases:  []string{"d"},
+						Usage:    "Path to BadgerDB database directory",
+						Required: true,
+					},
+					&cli.StringFlag{
+						Name:  "mode",
+						Usage: "Quantization mode: int8, binary, or none to remove quantized vectors",
+						Value: "int8",
+					},
+				},
+			},
+			{
+				Name:      "search",
+				Usage:     "Search chat records, e.g. kubernetes speaker:human after:2025-03-01 -\"helm chart\"",
+				ArgsUsage: "QUERY",
+				Action:    searchCommand,
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:     "db",
+						Aliases:  []string{"d"},
+						Usage:    "Path to BadgerDB database directory",
+						Required: true,
+					},
+					&cli.StringFlag{
+						Name:     "classifier-host",
+						Usage:    "Classifier service host URL for query concept extraction",
+						Required: true,
+					},
+					&cli.StringFlag{
+						Name:     "classifier-model",
+						Usage:    "Classifier model name for query concept extraction",
+						Required: true,
+					},
+					&cli.StringFlag{
+						Name:  "embedding-host",
+						Usage: "Embedding service host URL (defaults to classifier-host if not specified)",
+					},
+					&cli.StringFlag{
+						Name:     "embedding-model",
+						Usage:    "Embedding model name for query embeddings",
+						Required: true,
+					},
+					&cli.IntFlag{
+						Name:  "limit",
+						Usage: "Maximum number of results",
+						Value: 5,
+					},
+					&cli.BoolFlag{
+						Name:  "explain",
+						Usage: "Print how each result's score was computed",
+					},
+					&cli.StringFlag{
+						Name:  "dates",
+						Usage: "How dates like \"last tuesday\" in the query are applied: filter, boost or ignore",
+						Value: "filter",
+					},
+				},
+			},
+			{
+				Name:   "eval",
+				Usage:  "Measure search quality against a labeled dataset of queries",
+				Action: evalCommand,
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:     "db",
+						Aliases:  []string{"d"},
+						Usage:    "Path to BadgerDB database directory",
+						Required: true,
+					},
+					&cli.StringFlag{
+						Name:     "dataset",
+						Usage:    "Path to a JSON Lines file of queries and their relevant records",
+						Required: true,
+					},
+					&cli.StringFlag{
+						Name:  "provider",
+						Usage: "AI provider: mock, openai, record (openai, saving responses to the cassette) or replay (from the cassette)",
+						Value: "mock",
+					},
+					&cli.StringFlag{
+						Name:  "cassette",
+						Usage: "Path to the cassette of recorded AI responses for the record and replay providers",
+					},
+					&cli.StringFlag{
+						Name:  "classifier-host",
+						Usage: "Classifier service host URL for the openai and record providers",
+					},
+					&cli.StringFlag{
+						Name:  "classifier-model",
+						Usage: "Classifier model name for the openai and record providers",
+					},
+					&cli.StringFlag{
+						Name:  "embedding-host",
+						Usage: "Embedding service host URL (defaults to classifier-host if not specified)",
+					},
+					&cli.StringFlag{
+						Name:  "embedding-model",
+						Usage: "Embedding model name for the openai and record providers",
+					},
+					&cli.IntSliceFlag{
+						Name:  "k",
+						Usage: "Rank cutoffs for recall and nDCG",
+						Value: cli.NewIntSlice(eval.DefaultCutoffs...),
+					},
+					&cli.StringFlag{
+						Name:  "fusion",
+						Usage: "Fusion mode: ranker, rrf or weighted-sum",
+						Value: "ranker",
+					},
+					&cli.Float64Flag{
+						Name:  "min-similarity",
+						Usage: "Minimum cosine similarity for semantic hits",
+						Value: float64(search.DefaultMinSimilarity),
+					},
+					&cli.DurationFlag{
+						Name:  "recency-half-life",
+						Usage: "Half-life of exponential time decay (0 disables decay)",
+					},
+					&cli.Float64Flag{
+						Name:  "recency-weight",
+						Usage: "Share of the score subject to time decay",
+						Value: 0.5,
+					},
+					&cli.Float64Flag{
+						Name:  "mmr",
+						Usage: "Maximal marginal relevance lambda, trading relevance (1) against diversity (0); 0 disables",
+					},
+					&cli.Float64Flag{
+						Name:  "collapse",
+						Usage: "Similarity at which near-duplicate results are collapsed (0 disables)",
+					},
+					&cli.Float64Flag{
+						Name:  "concept-expansion",
+						Usage: "Minimum similarity for expanding query concepts to related concepts (0 disables)",
+					},
+					&cli.IntFlag{
+						Name:  "concept-expansion-limit",
+						Usage: "Stored concepts each query concept may expand to",
+						Value: 5,
+					},
+					&cli.BoolFlag{
+						Name:  "rerank",
+						Usage: "Rerank the top candidates with the classifier model (openai and record providers)",
+					},
+					&cli.IntFlag{
+						Name:  "rerank-pool",
+						Usage: "Number of top candidates sent to the reranker",
+						Value: search.DefaultRerankPoolSize,
+					},
+					&cli.BoolFlag{
+						Name:  "json",
+						Usage: "Print the report as JSON, including the search options",
+					},
+				},
+			},
 		},
 	}
 
@@ -477,3 +646,351 @@ func setupLogger(c *cli.Context) error {
 
 	return nil
 }
+
+func quantizeCommand(c *cli.Context) error {
+	ctx := context.Background()
+
+	// Validate flags
+	dbPath := c.String("db")
+	if dbPath == "" {
+		return fmt.Errorf("database path is required")
+	}
+
+	mode, err := badger.ParseQuantizationMode(c.String("mode"))
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "Database: %s\n", dbPath)
+	fmt.Fprintf(os.Stderr, "Quantization mode: %s\n", mode)
+
+	// Opening the backend quantizes any vectors whose codes are missing or stale
+	backend, err := badger.OpenBackend(dbPath, false, badger.WithQuantization(mode, 0))
+	if err != nil {
+		return fmt.Errorf("failed to quantize database: %w", err)
+	}
+	defer backend.Close()
+
+	// Opening without quantization only marks codes stale, so remove them explicitly
+	if mode == badger.QuantizationNone {
+		if _, err := backend.QuantizeVectors(ctx); err != nil {
+			return fmt.Errorf("failed to remove quantized vectors: %w", err)
+		}
+	}
+
+	fmt.Fprintln(os.Stderr, "Quantization complete")
+	return nil
+}
+
+func searchCommand(c *cli.Context) error {
+	ctx := context.Background()
+
+	// Validate flags
+	dbPath := c.String("db")
+	if dbPath == "" {
+		return fmt.Errorf("database path is required")
+	}
+	query := strings.Join(c.Args().Slice(), " ")
+	if query == "" {
+		return fmt.Errorf("search query is required")
+	}
+	if c.Int("limit") <= 0 {
+		return fmt.Errorf("limit must be greater than 0")
+	}
+	req, err := search.ParseQuery(query)
+	if err != nil {
+		return err
+	}
+	req.MaxHits = c.Int("limit")
+	req.Explain = c.Bool("explain")
+	timeMode, err := search.ParseTimeMode(c.String("dates"))
+	if err != nil {
+		return err
+	}
+
+	// Get embedding host (defaults to classifier host if not specified)
+	classifierHost := c.String("classifier-host")
+	embeddingHost := c.String("embedding-host")
+	if embeddingHost == "" {
+		embeddingHost = classifierHost
+	}
+
+	// Create AI config
+	aiConfig := ai.NewConfig(
+		ai.WithEmbeddingHost(embeddingHost),
+		ai.WithEmbeddingModel(c.String("embedding-model")),
+		ai.WithClassifierHost(classifierHost),
+		ai.WithClassifierModel(c.String("classifier-model")),
+	)
+
+	if err := aiConfig.Validate(); err != nil {
+		return fmt.Errorf("invalid AI configuration: %w", err)
+	}
+
+	db, err := memorit.NewDatabase(dbPath, memorit.WithAIConfig(aiConfig))
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer db.Close()
+
+	searcher, err := db.NewSearcher(search.WithTimeExpressions(timeMode))
+	if err != nil {
+		return fmt.Errorf("failed to create searcher: %w", err)
+	}
+
+	response, err := searcher.Search(ctx, req)
+	if err != nil {
+		return fmt.Errorf("search failed: %w", err)
+	}
+	for _, expression := range response.TimeExpressions {
+		fmt.Fprintf(os.Stderr, "Interpreting %q as %s\n", expression.Text, formatTimeRange(expression.Start, expression.End))
+	}
+
+	printResults(os.Stdout, response.Results, c.Bool("explain"))
+	return nil
+}
+
+// formatTimeRange describes a time range with zero bounds unbounded.
+func formatTimeRange(start, end time.Time) string {
+	const layout = "2006-01-02 15:04 MST"
+	switch {
+	case start.IsZero():
+		return "before " + end.Format(layout)
+	case end.IsZero():
+		return "since " + start.Format(layout)
+	default:
+		return start.Format(layout) + " to " + end.Format(layout)
+	}
+}
+
+// printResults writes search results, and optionally their score breakdowns, to w.
+func printResults(w io.Writer, results []*core.SearchResult, explain bool) {
+	fmt.Fprintf(w, "Found %d hits\n", len(results))
+	for i, result := range results {
+		fmt.Fprintf(w, "%d: [%.4f] %s (%d) %s\n", i+1, result.Score,
+			result.Record.Timestamp.Format(time.RFC3339), result.Record.Id, result.Record.Contents)
+		if explain && result.Breakdown != nil {
+			printBreakdown(w, result.Breakdown)
+		}
+	}
+}
+
+// printBreakdown writes the signals and formula behind a result's score to w.
+func printBreakdown(w io.Writer, breakdown *core.ScoreBreakdown) {
+	if breakdown.Semantic {
+		fmt.Fprintf(w, "    similarity: %.4f\n", breakdown.Similarity)
+	} else {
+		fmt.Fprintln(w, "    similarity: not a semantic hit")
+	}
+	for _, concept := range breakdown.Concepts {
+		fmt.Fprintf(w, "    concept:    (%s,%s) importance %d, similarity %.4f\n",
+			concept.Type, concept.Name, concept.Importance, concept.Similarity)
+	}
+	if len(breakdown.KeywordMatches) > 0 {
+		fmt.Fprintf(w, "    keywords:   %s\n", strings.Join(breakdown.KeywordMatches, ", "))
+	}
+	fmt.Fprintf(w, "    verbatim:   %t\n", breakdown.Verbatim)
+	fmt.Fprintf(w, "    recency:    %.4f (weight %.2f)\n", breakdown.Recency, breakdown.RecencyWeight)
+	if breakdown.Formula != "" {
+		fmt.Fprintf(w, "    score:      %s\n", breakdown.Formula)
+	}
+}
+
+func evalCommand(c *cli.Context) error {
+	ctx := context.Background()
+
+	// Validate flags
+	dbPath := c.String("db")
+	if dbPath == "" {
+		return fmt.Errorf("database path is required")
+	}
+	fusion, err := search.ParseFusionMode(c.String("fusion"))
+	if err != nil {
+		return err
+	}
+	options, settings, err := evalSearchOptions(c)
+	if err != nil {
+		return err
+	}
+	dataset, err := eval.LoadDataset(c.String("dataset"))
+	if err != nil {
+		return fmt.Errorf("failed to load dataset: %w", err)
+	}
+
+	provider, cassette, err := evalProvider(c)
+	if err != nil {
+		return err
+	}
+
+	db, err := memorit.NewDatabase(dbPath, memorit.WithAIProvider(provider))
+	if err != nil {
+		provider.Close()
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer db.Close()
+
+	searcher, err := db.NewSearcher(options...)
+	if err != nil {
+		return fmt.Errorf("failed to create searcher: %w", err)
+	}
+
+	evaluator, err := eval.NewEvaluator(searcher,
+		eval.WithCutoffs(c.IntSlice("k")...),
+		eval.WithRequest(search.SearchRequest{Fusion: fusion}))
+	if err != nil {
+		return err
+	}
+
+	report, err := evaluator.Run(ctx, dataset)
+	if err != nil {
+		return fmt.Errorf("evaluation failed: %w", err)
+	}
+
+	if c.String("provider") == "record" {
+		if err := cassette.Save(c.String("cassette")); err != nil {
+			return fmt.Errorf("failed to save cassette: %w", err)
+		}
+	}
+
+	if c.Bool("json") {
+		encoder := json.NewEncoder(c.App.Writer)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(struct {
+			Options evalOptions
+			*eval.Report
+		}{*settings, report})
+	}
+	printReport(c.App.Writer, report)
+	return nil
+}
+
+// evalOptions records the search settings of an eval run so reports from
+// different runs can be compared. Zero values mean the setting is disabled.
+type evalOptions struct {
+	Fusion                string
+	MinSimilarity         float64
+	RecencyHalfLife       time.Duration
+	RecencyWeight         float64
+	MMRLambda             float64
+	CollapseThreshold     float64
+	ConceptExpansion      float64
+	ConceptExpansionLimit int
+	RerankPoolSize        int
+}
+
+// evalSearchOptions returns the searcher options selected by the eval
+// command's flags, along with a record of them for the report.
+func evalSearchOptions(c *cli.Context) ([]search.Option, *evalOptions, error) {
+	settings := &evalOptions{
+		Fusion:        c.String("fusion"),
+		MinSimilarity: c.Float64("min-similarity"),
+	}
+	options := []search.Option{search.WithMinSimilarity(float32(settings.MinSimilarity))}
+
+	if halfLife := c.Duration("recency-half-life"); halfLife > 0 {
+		settings.RecencyHalfLife = halfLife
+		settings.RecencyWeight = c.Float64("recency-weight")
+		options = append(options, search.WithRecency(search.ExponentialDecay(halfLife, float32(settings.RecencyWeight))))
+	}
+	if lambda := c.Float64("mmr"); lambda > 0 {
+		settings.MMRLambda = lambda
+		options = append(options, search.WithMMR(float32(lambda)))
+	}
+	if threshold := c.Float64("collapse"); threshold > 0 {
+		settings.CollapseThreshold = threshold
+		options = append(options, search.WithDuplicateCollapse(float32(threshold)))
+	}
+	if minSimilarity := c.Float64("concept-expansion"); minSimilarity > 0 {
+		settings.ConceptExpansion = minSimilarity
+		settings.ConceptExpansionLimit = c.Int("concept-expansion-limit")
+		options = append(options, search.WithConceptExpansion(float32(minSimilarity), settings.ConceptExpansionLimit))
+	}
+	if c.Bool("rerank") {
+		provider := c.String("provider")
+		if provider != "openai" && provider != "record" {
+			return nil, nil, fmt.Errorf("reranking requires the openai or record provider, not %q", provider)
+		}
+		reranker, err := openai.NewReranker(evalAIConfig(c))
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create reranker: %w", err)
+		}
+		settings.RerankPoolSize = c.Int("rerank-pool")
+		options = append(options, search.WithReranker(reranker), search.WithRerankPoolSize(settings.RerankPoolSize))
+	}
+	return options, settings, nil
+}
+
+// evalProvider creates the AI provider selected by the eval command's flags.
+// The cassette is non-nil for the record and replay providers.
+func evalProvider(c *cli.Context) (ai.AIProvider, *eval.Cassette, error) {
+	name := c.String("provider")
+	switch name {
+	case "mock":
+		return mock.NewMockProvider(), nil, nil
+	case "replay":
+		if c.String("cassette") == "" {
+			return nil, nil, fmt.Errorf("cassette is required for the replay provider")
+		}
+		cassette, err := eval.LoadCassette(c.String("cassette"))
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to load cassette: %w", err)
+		}
+		return eval.NewReplayProvider(cassette), cassette, nil
+	case "openai", "record":
+		if name == "record" && c.String("cassette") == "" {
+			return nil, nil, fmt.Errorf("cassette is required for the record provider")
+		}
+
+		aiConfig := evalAIConfig(c)
+		if err := aiConfig.Validate(); err != nil {
+			return nil, nil, fmt.Errorf("invalid AI configuration: %w", err)
+		}
+		provider, err := openai.NewProvider(aiConfig)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create AI provider: %w", err)
+		}
+		if name == "openai" {
+			return provider, nil, nil
+		}
+		cassette := eval.NewCassette()
+		return eval.NewRecordingProvider(provider, cassette), cassette, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown provider %q: must be one of mock, openai, record, replay", name)
+	}
+}
+
+// evalAIConfig returns the AI configuration selected by the eval command's flags.
+func evalAIConfig(c *cli.Context) *ai.Config {
+	// Get embedding host (defaults to classifier host if not specified)
+	classifierHost := c.String("classifier-host")
+	embeddingHost := c.String("embedding-host")
+	if embeddingHost == "" {
+		embeddingHost = classifierHost
+	}
+
+	return ai.NewConfig(
+		ai.WithEmbeddingHost(embeddingHost),
+		ai.WithEmbeddingModel(c.String("embedding-model")),
+		ai.WithClassifierHost(classifierHost),
+		ai.WithClassifierModel(c.String("classifier-model")),
+	)
+}
+
+// printReport writes an evaluation report to w.
+func printReport(w io.Writer, report *eval.Report) {
+	fmt.Fprintf(w, "Queries: %d\n", report.Queries)
+	for _, k := range report.Cutoffs {
+		fmt.Fprintf(w, "Recall@%-3d %.4f\n", k, report.Recall[k])
+	}
+	for _, k := range report.Cutoffs {
+		fmt.Fprintf(w, "nDCG@%-5d %.4f\n", k, report.NDCG[k])
+	}
+	fmt.Fprintf(w, "MRR        %.4f\n", report.MRR)
+	fmt.Fprintf(w, "Latency    p50 %s, p90 %s, p99 %s, mean %s, max %s\n",
+		report.Latency.P50, report.Latency.P90, report.Latency.P99, report.Latency.Mean, report.Latency.Max)
+	for _, result := range report.Results {
+		if len(result.Skipped) > 0 {
+			fmt.Fprintf(w, "Query %s skipped stages: %v\n", result.ID, result.Skipped)
+		}
+	}
+}
@@ -1,10 +1,17 @@
 package main
 
 import (
+	"bytes"
+	"encoding/json"
 	"log/slog"
 	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
+	"github.com/poiesic/memorit/core"
+	"github.com/poiesic/memorit/eval"
+	"github.com/poiesic/memorit/search"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/urfave/cli/v2"
@@ -354,3 +361,202 @@ func TestMain(m *testing.M) {
 	code := m.Run()
 	os.Exit(code)
 }
+
+func TestQuantizeCommand(t *testing.T) {
+	app := &cli.App{
+		Name: "memorit",
+		Commands: []*cli.Command{
+			{
+				Name:   "quantize",
+				Action: quantizeCommand,
+				Flags: []cli.Flag{
+					&cli.StringFlag{Name: "db", Required: true},
+					&cli.StringFlag{Name: "mode", Value: "int8"},
+				},
+			},
+		},
+	}
+
+	t.Run("quantizes database", func(t *testing.T) {
+		err := app.Run([]string{"memorit", "quantize", "--db", t.TempDir(), "--mode", "binary"})
+		assert.NoError(t, err)
+	})
+
+	t.Run("invalid mode", func(t *testing.T) {
+		err := app.Run([]string{"memorit", "quantize", "--db", t.TempDir(), "--mode", "int4"})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "int4")
+	})
+}
+
+func TestPrintResults(t *testing.T) {
+	results := []*core.SearchResult{
+		{
+			Record: &core.ChatRecord{
+				Id:        7,
+				Contents:  "Machine learning is fascinating",
+				Timestamp: time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC),
+			},
+			Score: 1.8,
+			Breakdown: &core.ScoreBreakdown{
+				Relevance:      1.8,
+				Recency:        1,
+				Semantic:       true,
+				Similarity:     1,
+				Concepts:       []core.ConceptContribution{{Name: "machine", Type: "thing", Importance: 8, Similarity: 1}},
+				KeywordMatches: []string{"machine", "learning"},
+				Verbatim:       true,
+				Formula:        "1.5000 × 1.0000 [hybrid] + 0.3000 [verbatim] = 1.8000",
+			},
+		},
+	}
+
+	t.Run("without explain", func(t *testing.T) {
+		var buf bytes.Buffer
+		printResults(&buf, results, false)
+		assert.Equal(t, "Found 1 hits\n1: [1.8000] 2025-06-01T00:00:00Z (7) Machine learning is fascinating\n", buf.String())
+	})
+
+	t.Run("with explain", func(t *testing.T) {
+		var buf bytes.Buffer
+		printResults(&buf, results, true)
+		output := buf.String()
+		assert.Contains(t, output, "similarity: 1.0000")
+		assert.Contains(t, output, "concept:    (thing,machine) importance 8, similarity 1.0000")
+		assert.Contains(t, output, "keywords:   machine, learning")
+		assert.Contains(t, output, "verbatim:   true")
+		assert.Contains(t, output, "recency:    1.0000 (weight 0.00)")
+		assert.Contains(t, output, "score:      1.5000 × 1.0000 [hybrid] + 0.3000 [verbatim] = 1.8000")
+	})
+}
+
+func TestEvalCommand(t *testing.T) {
+	app := &cli.App{
+		Name: "memorit",
+		Commands: []*cli.Command{
+			{
+				Name:   "eval",
+				Action: evalCommand,
+				Flags: []cli.Flag{
+					&cli.StringFlag{Name: "db", Required: true},
+					&cli.StringFlag{Name: "dataset", Required: true},
+					&cli.StringFlag{Name: "provider", Value: "mock"},
+					&cli.StringFlag{Name: "cassette"},
+					&cli.StringFlag{Name: "classifier-host"},
+					&cli.StringFlag{Name: "classifier-model"},
+					&cli.StringFlag{Name: "embedding-host"},
+					&cli.StringFlag{Name: "embedding-model"},
+					&cli.IntSliceFlag{Name: "k", Value: cli.NewIntSlice(eval.DefaultCutoffs...)},
+					&cli.StringFlag{Name: "fusion", Value: "ranker"},
+					&cli.Float64Flag{Name: "min-similarity", Value: float64(search.DefaultMinSimilarity)},
+					&cli.DurationFlag{Name: "recency-half-life"},
+					&cli.Float64Flag{Name: "recency-weight", Value: 0.5},
+					&cli.Float64Flag{Name: "mmr"},
+					&cli.Float64Flag{Name: "collapse"},
+					&cli.Float64Flag{Name: "concept-expansion"},
+					&cli.IntFlag{Name: "concept-expansion-limit", Value: 5},
+					&cli.BoolFlag{Name: "rerank"},
+					&cli.IntFlag{Name: "rerank-pool", Value: search.DefaultRerankPoolSize},
+					&cli.BoolFlag{Name: "json"},
+				},
+			},
+		},
+	}
+	var output bytes.Buffer
+	app.Writer = &output
+
+	dataset := filepath.Join(t.TempDir(), "queries.jsonl")
+	require.NoError(t, os.WriteFile(dataset, []byte(`{"id": "q1", "query": "machine learning", "relevant_ids": [1]}`+"\n"), 0o644))
+
+	t.Run("evaluates with mock provider", func(t *testing.T) {
+		err := app.Run([]string{"memorit", "eval", "--db", t.TempDir(), "--dataset", dataset, "--fusion", "rrf", "--json"})
+		assert.NoError(t, err)
+	})
+
+	t.Run("records search options in the report", func(t *testing.T) {
+		output.Reset()
+		err := app.Run([]string{"memorit", "eval", "--db", t.TempDir(), "--dataset", dataset, "--json",
+			"--min-similarity", "0.4", "--recency-half-life", "24h", "--mmr", "0.7", "--collapse", "0.95", "--concept-expansion", "0.8"})
+		require.NoError(t, err)
+
+		var report struct {
+			Options evalOptions
+			Queries int
+		}
+		require.NoError(t, json.Unmarshal(output.Bytes(), &report))
+		assert.Equal(t, 1, report.Queries)
+		assert.Equal(t, evalOptions{
+			Fusion:                "ranker",
+			MinSimilarity:         0.4,
+			RecencyHalfLife:       24 * time.Hour,
+			RecencyWeight:         0.5,
+			MMRLambda:             0.7,
+			CollapseThreshold:     0.95,
+			ConceptExpansion:      0.8,
+			ConceptExpansionLimit: 5,
+		}, report.Options)
+	})
+
+	t.Run("invalid search option", func(t *testing.T) {
+		err := app.Run([]string{"memorit", "eval", "--db", t.TempDir(), "--dataset", dataset, "--mmr", "2"})
+		assert.ErrorIs(t, err, search.ErrInvalidLambda)
+	})
+
+	t.Run("rerank requires a model provider", func(t *testing.T) {
+		err := app.Run([]string{"memorit", "eval", "--db", t.TempDir(), "--dataset", dataset, "--rerank"})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "rerank")
+	})
+
+	t.Run("replay requires cassette", func(t *testing.T) {
+		err := app.Run([]string{"memorit", "eval", "--db", t.TempDir(), "--dataset", dataset, "--provider", "replay"})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "cassette")
+	})
+
+	t.Run("invalid provider", func(t *testing.T) {
+		err := app.Run([]string{"memorit", "eval", "--db", t.TempDir(), "--dataset", dataset, "--provider", "bogus"})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "bogus")
+	})
+
+	t.Run("invalid fusion", func(t *testing.T) {
+		err := app.Run([]string{"memorit", "eval", "--db", t.TempDir(), "--dataset", dataset, "--fusion", "borda"})
+		assert.ErrorIs(t, err, search.ErrInvalidFusion)
+	})
+}
+
+func TestPrintReport(t *testing.T) {
+	report := &eval.Report{
+		Queries: 2,
+		Cutoffs: []int{1, 10},
+		Recall:  map[int]float64{1: 0.5, 10: 1},
+		NDCG:    map[int]float64{1: 0.5, 10: 0.8155},
+		MRR:     0.75,
+		Latency: eval.Latency{P50: time.Millisecond, P90: 2 * time.Millisecond, P99: 2 * time.Millisecond, Mean: 1500 * time.Microsecond, Max: 2 * time.Millisecond},
+		Results: []eval.QueryResult{
+			{ID: "q1"},
+			{ID: "q2", Skipped: []search.Stage{search.StageConceptual}},
+		},
+	}
+
+	var buf bytes.Buffer
+	printReport(&buf, report)
+	output := buf.String()
+	assert.Contains(t, output, "Queries: 2\n")
+	assert.Contains(t, output, "Recall@1   0.5000\n")
+	assert.Contains(t, output, "Recall@10  1.0000\n")
+	assert.Contains(t, output, "nDCG@10    0.8155\n")
+	assert.Contains(t, output, "MRR        0.7500\n")
+	assert.Contains(t, output, "p50 1ms, p90 2ms, p99 2ms, mean 1.5ms, max 2ms")
+	assert.Contains(t, output, "Query q2 skipped stages: [conceptual]")
+	assert.NotContains(t, output, "Query q1")
+}
+
+func TestFormatTimeRange(t *testing.T) {
+	start := time.Date(2025, time.October, 14, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 0, 1)
+	assert.Equal(t, "2025-10-14 00:00 UTC to 2025-10-15 00:00 UTC", formatTimeRange(start, end))
+	assert.Equal(t, "since 2025-10-14 00:00 UTC", formatTimeRange(start, time.Time{}))
+	assert.Equal(t, "before 2025-10-15 00:00 UTC", formatTimeRange(time.Time{}, end))
+}
@@ -82,6 +82,16 @@ func main() {
 		panic(err)
 	}
 
+	err = g.AddStruct(reflect.TypeFor[core.Chunk](),
+		structops.WithField(),
+		structops.WithField(),
+		structops.WithField(),
+		structops.WithField(),
+		structops.WithField())
+	if err != nil {
+		panic(err)
+	}
+
 	err = g.AddStruct(reflect.TypeFor[core.Checkpoint](),
 		structops.WithField(),
 		structops.WithField(),
@@ -0,0 +1,266 @@
+// Copyright 2025 Poiesic Systems
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+
+package context
+
+import (
+	"cmp"
+	gocontext "context"
+	"log/slog"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/poiesic/memorit/core"
+	"github.com/poiesic/memorit/search"
+)
+
+// DefaultCandidates is the number of search results considered for a context.
+const DefaultCandidates = 50
+
+// Default speaker labels.
+const (
+	DefaultHumanLabel = "User"
+	DefaultAILabel    = "Assistant"
+)
+
+// Tokenizer returns the number of tokens in text, as counted by the model
+// the context is built for.
+type Tokenizer func(text string) int
+
+// ApproximateTokens estimates the tokens in text at one per four characters.
+// Use a model's own tokenizer when budgets must be exact.
+func ApproximateTokens(text string) int {
+	return (utf8.RuneCountInString(text) + 3) / 4
+}
+
+// Searcher runs searches. *search.Searcher implements it.
+type Searcher interface {
+	Search(ctx gocontext.Context, req *search.SearchRequest) (*search.SearchResponse, error)
+}
+
+var _ Searcher = (*search.Searcher)(nil)
+
+// OmitReason explains why a search result was left out of a context.
+type OmitReason int
+
+const (
+	// OmitDuplicate means the result repeats the contents of a higher-scoring result.
+	OmitDuplicate OmitReason = iota + 1
+	// OmitBudget means including the result would exceed the token budget.
+	OmitBudget
+)
+
+// String returns the name of the reason.
+func (r OmitReason) String() string {
+	switch r {
+	case OmitDuplicate:
+		return "duplicate"
+	case OmitBudget:
+		return "budget"
+	default:
+		return "unknown"
+	}
+}
+
+// Omission is a search result left out of a context.
+type Omission struct {
+	Result *core.SearchResult
+	Reason OmitReason
+}
+
+// Context is prompt text built from memories.
+type Context struct {
+	// Text is the formatted memories. Empty if no memory fit the budget.
+	Text string
+	// Tokens is the number of tokens in Text.
+	Tokens int
+	// Conversations are the selected memories as passed to the template.
+	Conversations []Conversation
+	// Omitted lists the results that were left out, highest score first.
+	Omitted []Omission
+}
+
+// Builder selects, orders and formats memories for an LLM prompt within a token budget.
+type Builder struct {
+	searcher   Searcher
+	tokenizer  Tokenizer
+	template   Template
+	candidates int
+	humanLabel string
+	aiLabel    string
+	logger     *slog.Logger
+}
+
+// Option configures a Builder.
+type Option func(*Builder) error
+
+// WithTemplate sets how memories are formatted. Default is DefaultTemplate.
+func WithTemplate(template Template) Option {
+	return func(b *Builder) error {
+		if template == nil {
+			return ErrTemplateRequired
+		}
+		b.template = template
+		return nil
+	}
+}
+
+// WithCandidates sets the number of search results considered for a context.
+// Default is DefaultCandidates.
+func WithCandidates(n int) Option {
+	return func(b *Builder) error {
+		if n <= 0 {
+			return ErrInvalidCandidates
+		}
+		b.candidates = n
+		return nil
+	}
+}
+
+// WithSpeakerLabels sets the labels for human and AI speakers.
+// Defaults are DefaultHumanLabel and DefaultAILabel.
+func WithSpeakerLabels(human, ai string) Option {
+	return func(b *Builder) error {
+		b.humanLabel = human
+		b.aiLabel = ai
+		return nil
+	}
+}
+
+// WithLogger sets a custom logger.
+// Default is slog.Default().
+func WithLogger(logger *slog.Logger) Option {
+	return func(b *Builder) error {
+		if logger == nil {
+			logger = slog.Default()
+		}
+		b.logger = logger
+		return nil
+	}
+}
+
+// NewBuilder creates a context builder that searches with searcher and
+// measures text with tokenizer.
+func NewBuilder(searcher Searcher, tokenizer Tokenizer, opts ...Option) (*Builder, error) {
+	if searcher == nil {
+		return nil, ErrSearcherRequired
+	}
+	if tokenizer == nil {
+		return nil, ErrTokenizerRequired
+	}
+
+	b := &Builder{
+		searcher:   searcher,
+		tokenizer:  tokenizer,
+		template:   DefaultTemplate,
+		candidates: DefaultCandidates,
+		humanLabel: DefaultHumanLabel,
+		aiLabel:    DefaultAILabel,
+		logger:     slog.Default(),
+	}
+	for _, opt := range opts {
+		if err := opt(b); err != nil {
+			return nil, err
+		}
+	}
+	return b, nil
+}
+
+// Build searches for memories relevant to query and formats as many as fit
+// in budget tokens.
+func (b *Builder) Build(ctx gocontext.Context, query string, budget int) (*Context, error) {
+	return b.BuildRequest(ctx, &search.SearchRequest{Query: query}, budget)
+}
+
+// BuildRequest is like Build but runs a custom search request, such as one
+// from search.ParseQuery or with a filter. A zero MaxHits uses the builder's
+// candidate count.
+func (b *Builder) BuildRequest(ctx gocontext.Context, req *search.SearchRequest, budget int) (*Context, error) {
+	if budget < 0 {
+		return nil, ErrInvalidBudget
+	}
+
+	r := *req
+	if r.MaxHits == 0 {
+		r.MaxHits = b.candidates
+	}
+	response, err := b.searcher.Search(ctx, &r)
+	if err != nil {
+		b.logger.Error("error searching for memories", "query", r.Query, "err", err)
+		return nil, err
+	}
+	return b.Select(response.Results, budget)
+}
+
+// Select formats as many of results as fit in budget tokens, for callers
+// that already have results, such as from Searcher.FindRelated.
+// Results are considered highest score first. Duplicates of a higher-scoring
+// result are dropped, and a result that would exceed the budget is skipped
+// so that shorter, lower-scoring results can still fill it. The selected
+// memories are grouped by conversation and ordered chronologically.
+func (b *Builder) Select(results []*core.SearchResult, budget int) (*Context, error) {
+	if budget < 0 {
+		return nil, ErrInvalidBudget
+	}
+
+	ordered := slices.Clone(results)
+	slices.SortStableFunc(ordered, func(x, y *core.SearchResult) int {
+		return cmp.Compare(y.Score, x.Score)
+	})
+
+	out := &Context{}
+	seen := make(map[string]bool, len(ordered))
+	var selected []Memory
+	for _, result := range ordered {
+		key := normalizeContents(result.Record.Contents)
+		if seen[key] {
+			out.Omitted = append(out.Omitted, Omission{Result: result, Reason: OmitDuplicate})
+			continue
+		}
+		seen[key] = true
+
+		candidate := append(slices.Clip(selected), b.memory(result))
+		text, err := b.template.Format(groupConversations(candidate))
+		if err != nil {
+			return nil, err
+		}
+		tokens := b.tokenizer(text)
+		if tokens > budget {
+			out.Omitted = append(out.Omitted, Omission{Result: result, Reason: OmitBudget})
+			continue
+		}
+		selected = candidate
+		out.Text = text
+		out.Tokens = tokens
+	}
+	out.Conversations = groupConversations(selected)
+	return out, nil
+}
+
+// memory labels a search result's speaker.
+func (b *Builder) memory(result *core.SearchResult) Memory {
+	label := b.humanLabel
+	if result.Record.Speaker == core.SpeakerTypeAI {
+		label = b.aiLabel
+	}
+	return Memory{Record: result.Record, Score: result.Score, Speaker: label}
+}
+
+// normalizeContents lowercases contents and collapses whitespace, so that
+// trivially different copies of a message are treated as duplicates.
+func normalizeContents(contents string) string {
+	return strings.ToLower(strings.Join(strings.Fields(contents), " "))
+}
@@ -0,0 +1,200 @@
+package context
+
+import (
+	gocontext "context"
+	"errors"
+	"strings"
+	"testing"
+	"text/template"
+	"time"
+
+	"github.com/poiesic/memorit/core"
+	"github.com/poiesic/memorit/search"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSearcher returns fixed results and records the last request.
+type fakeSearcher struct {
+	results []*core.SearchResult
+	err     error
+	req     *search.SearchRequest
+}
+
+func (f *fakeSearcher) Search(ctx gocontext.Context, req *search.SearchRequest) (*search.SearchResponse, error) {
+	f.req = req
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &search.SearchResponse{Results: f.results}, nil
+}
+
+// wordCount counts whitespace-separated words, so budgets are easy to reason about.
+func wordCount(text string) int {
+	return len(strings.Fields(text))
+}
+
+var base = time.Date(2025, 3, 1, 14, 0, 0, 0, time.UTC)
+
+func hit(id core.ID, conversation string, minute int, speaker core.SpeakerType, contents string, score float32) *core.SearchResult {
+	record := &core.ChatRecord{
+		Id:        id,
+		Speaker:   speaker,
+		Contents:  contents,
+		Timestamp: base.Add(time.Duration(minute) * time.Minute),
+	}
+	if conversation != "" {
+		record.Metadata = map[string]string{core.MetadataConversation: conversation}
+	}
+	return &core.SearchResult{Record: record, Score: score}
+}
+
+func TestNewBuilder(t *testing.T) {
+	searcher := &fakeSearcher{}
+
+	_, err := NewBuilder(nil, wordCount)
+	assert.Equal(t, ErrSearcherRequired, err)
+	_, err = NewBuilder(searcher, nil)
+	assert.Equal(t, ErrTokenizerRequired, err)
+	_, err = NewBuilder(searcher, wordCount, WithTemplate(nil))
+	assert.Equal(t, ErrTemplateRequired, err)
+	_, err = NewBuilder(searcher, wordCount, WithCandidates(0))
+	assert.Equal(t, ErrInvalidCandidates, err)
+
+	builder, err := NewBuilder(searcher, wordCount)
+	require.NoError(t, err)
+	assert.Equal(t, DefaultCandidates, builder.candidates)
+	assert.Equal(t, DefaultHumanLabel, builder.humanLabel)
+	assert.Equal(t, DefaultAILabel, builder.aiLabel)
+}
+
+func TestBuilder_Build(t *testing.T) {
+	ctx := gocontext.Background()
+	searcher := &fakeSearcher{results: []*core.SearchResult{
+		hit(1, "b", 30, core.SpeakerTypeHuman, "The helm upgrade failed again", 0.9),
+		hit(2, "a", 5, core.SpeakerTypeAI, "Check the release history first", 0.8),
+		hit(3, "a", 1, core.SpeakerTypeHuman, "How do I roll back a release?", 0.7),
+		hit(4, "b", 31, core.SpeakerTypeHuman, "the helm  upgrade failed AGAIN", 0.6),
+		hit(5, "", 60, core.SpeakerTypeHuman, "Kubernetes is on version 1.31", 0.5),
+	}}
+	builder, err := NewBuilder(searcher, wordCount)
+	require.NoError(t, err)
+
+	t.Run("everything fits", func(t *testing.T) {
+		memories, err := builder.Build(ctx, "helm", 1000)
+		require.NoError(t, err)
+		assert.Equal(t, "helm", searcher.req.Query)
+		assert.Equal(t, DefaultCandidates, searcher.req.MaxHits)
+
+		expected := "Conversation a:\n" +
+			"[2025-03-01 14:01] User: How do I roll back a release?\n" +
+			"[2025-03-01 14:05] Assistant: Check the release history first\n" +
+			"\n" +
+			"Conversation b:\n" +
+			"[2025-03-01 14:30] User: The helm upgrade failed again\n" +
+			"\n" +
+			"Other memories:\n" +
+			"[2025-03-01 15:00] User: Kubernetes is on version 1.31\n"
+		assert.Equal(t, expected, memories.Text)
+		assert.Equal(t, wordCount(expected), memories.Tokens)
+		require.Len(t, memories.Conversations, 3)
+		assert.Equal(t, "a", memories.Conversations[0].ID)
+		require.Len(t, memories.Omitted, 1)
+		assert.Equal(t, core.ID(4), memories.Omitted[0].Result.Record.Id)
+		assert.Equal(t, OmitDuplicate, memories.Omitted[0].Reason)
+	})
+
+	t.Run("cut off at budget", func(t *testing.T) {
+		// The top two results cost exactly 20 words; each remaining result would add 10 more
+		memories, err := builder.Build(ctx, "helm", 20)
+		require.NoError(t, err)
+		assert.LessOrEqual(t, memories.Tokens, 20)
+		assert.Contains(t, memories.Text, "The helm upgrade failed again")
+		assert.Contains(t, memories.Text, "Check the release history first")
+		assert.NotContains(t, memories.Text, "roll back")
+
+		omitted := make(map[core.ID]OmitReason)
+		for _, omission := range memories.Omitted {
+			omitted[omission.Result.Record.Id] = omission.Reason
+		}
+		assert.Equal(t, map[core.ID]OmitReason{3: OmitBudget, 4: OmitDuplicate, 5: OmitBudget}, omitted)
+	})
+
+	t.Run("nothing fits", func(t *testing.T) {
+		memories, err := builder.Build(ctx, "helm", 3)
+		require.NoError(t, err)
+		assert.Empty(t, memories.Text)
+		assert.Zero(t, memories.Tokens)
+		assert.Empty(t, memories.Conversations)
+		assert.Len(t, memories.Omitted, 5)
+	})
+
+	t.Run("invalid budget", func(t *testing.T) {
+		_, err := builder.Build(ctx, "helm", -1)
+		assert.Equal(t, ErrInvalidBudget, err)
+	})
+
+	t.Run("search error", func(t *testing.T) {
+		failing, err := NewBuilder(&fakeSearcher{err: errors.New("search failed")}, wordCount)
+		require.NoError(t, err)
+		_, err = failing.Build(ctx, "helm", 100)
+		assert.EqualError(t, err, "search failed")
+	})
+}
+
+func TestBuilder_BuildRequest(t *testing.T) {
+	searcher := &fakeSearcher{}
+	builder, err := NewBuilder(searcher, wordCount, WithCandidates(7))
+	require.NoError(t, err)
+
+	req := &search.SearchRequest{Query: "helm"}
+	_, err = builder.BuildRequest(gocontext.Background(), req, 100)
+	require.NoError(t, err)
+	assert.Equal(t, 7, searcher.req.MaxHits)
+	assert.Equal(t, 0, req.MaxHits, "caller's request should not be modified")
+
+	_, err = builder.BuildRequest(gocontext.Background(), &search.SearchRequest{Query: "helm", MaxHits: 3}, 100)
+	require.NoError(t, err)
+	assert.Equal(t, 3, searcher.req.MaxHits)
+}
+
+func TestBuilder_Templates(t *testing.T) {
+	results := []*core.SearchResult{
+		hit(1, "a", 2, core.SpeakerTypeAI, "Try a smaller image", 0.9),
+		hit(2, "a", 1, core.SpeakerTypeHuman, "The build is slow", 0.8),
+	}
+
+	t.Run("speaker labels", func(t *testing.T) {
+		builder, err := NewBuilder(&fakeSearcher{}, wordCount, WithSpeakerLabels("Me", "Bot"))
+		require.NoError(t, err)
+		memories, err := builder.Select(results, 100)
+		require.NoError(t, err)
+		assert.Contains(t, memories.Text, "Me: The build is slow")
+		assert.Contains(t, memories.Text, "Bot: Try a smaller image")
+	})
+
+	t.Run("text template", func(t *testing.T) {
+		tmpl := template.Must(template.New("memories").Parse(
+			`{{range .}}<conversation id="{{.ID}}">{{range .Memories}}<{{.Speaker}}>{{.Record.Contents}}</{{.Speaker}}>{{end}}</conversation>{{end}}`))
+		builder, err := NewBuilder(&fakeSearcher{}, wordCount, WithTemplate(TextTemplate(tmpl)))
+		require.NoError(t, err)
+		memories, err := builder.Select(results, 100)
+		require.NoError(t, err)
+		assert.Equal(t, `<conversation id="a"><User>The build is slow</User><Assistant>Try a smaller image</Assistant></conversation>`, memories.Text)
+	})
+
+	t.Run("template error", func(t *testing.T) {
+		builder, err := NewBuilder(&fakeSearcher{}, wordCount, WithTemplate(TemplateFunc(func([]Conversation) (string, error) {
+			return "", errors.New("bad template")
+		})))
+		require.NoError(t, err)
+		_, err = builder.Select(results, 100)
+		assert.EqualError(t, err, "bad template")
+	})
+}
+
+func TestApproximateTokens(t *testing.T) {
+	assert.Equal(t, 0, ApproximateTokens(""))
+	assert.Equal(t, 1, ApproximateTokens("abc"))
+	assert.Equal(t, 2, ApproximateTokens("héllo"))
+}
@@ -0,0 +1,47 @@
+// Copyright 2025 Poiesic Systems
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+
+// Package context builds token-budgeted memory context for LLM prompts.
+//
+// A Builder searches for memories relevant to a query, then selects, orders
+// and formats them to fit a token budget measured by a caller-supplied
+// Tokenizer:
+//
+//   - results are considered highest score first, and repeats of a
+//     higher-scoring message are dropped
+//   - a result that would exceed the budget is skipped, so shorter results
+//     can still use the remaining tokens
+//   - the selected memories are grouped by conversation, ordered
+//     chronologically, and labeled by speaker
+//   - a Template formats them; DefaultTemplate writes timestamped lines, and
+//     TextTemplate adapts a text/template
+//
+// The returned Context reports which results were left out and why.
+//
+// # Usage
+//
+//	builder, err := context.NewBuilder(searcher, context.ApproximateTokens)
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	memories, err := builder.Build(ctx, "what did I say about my diet", 1000)
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	prompt := "Relevant memories:\n" + memories.Text + "\n" + question
+//
+// The package name shadows the standard library's context package, so
+// importers that need both should rename one of them.
+package context
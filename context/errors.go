@@ -0,0 +1,35 @@
+// Copyright 2025 Poiesic Systems
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+
+package context
+
+import "errors"
+
+var (
+	// ErrSearcherRequired is returned when a searcher is not provided.
+	ErrSearcherRequired = errors.New("searcher required")
+
+	// ErrTokenizerRequired is returned when a tokenizer is not provided.
+	ErrTokenizerRequired = errors.New("tokenizer required")
+
+	// ErrTemplateRequired is returned when a nil template is provided.
+	ErrTemplateRequired = errors.New("template required")
+
+	// ErrInvalidCandidates is returned when the number of search candidates is not positive.
+	ErrInvalidCandidates = errors.New("candidate count must be positive")
+
+	// ErrInvalidBudget is returned when a token budget is negative.
+	ErrInvalidBudget = errors.New("token budget must not be negative")
+)
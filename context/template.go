@@ -0,0 +1,129 @@
+// Copyright 2025 Poiesic Systems
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+
+package context
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+	"text/template"
+
+	"github.com/poiesic/memorit/core"
+)
+
+// Memory is a search result selected for the context.
+type Memory struct {
+	Record  *core.ChatRecord
+	Score   float32
+	Speaker string // Label for the record's speaker
+}
+
+// Conversation is a group of memories from the same conversation, in
+// chronological order. ID is empty for records without a conversation.
+type Conversation struct {
+	ID       string
+	Memories []Memory
+}
+
+// Template formats the selected memories as prompt text.
+// Conversations are ordered by their earliest memory.
+type Template interface {
+	Format(conversations []Conversation) (string, error)
+}
+
+// TemplateFunc adapts a function to the Template interface.
+type TemplateFunc func(conversations []Conversation) (string, error)
+
+// Format calls f.
+func (f TemplateFunc) Format(conversations []Conversation) (string, error) {
+	return f(conversations)
+}
+
+// DefaultTemplate formats each conversation as a block of timestamped,
+// speaker-labeled lines:
+//
+//	Conversation support-42:
+//	[2025-03-01 14:02] User: The helm upgrade failed again
+//	[2025-03-01 14:03] Assistant: Check the release history first
+//
+// Blocks are separated by a blank line. Records without a conversation are
+// grouped under "Other memories:".
+var DefaultTemplate Template = TemplateFunc(formatDefault)
+
+func formatDefault(conversations []Conversation) (string, error) {
+	var b strings.Builder
+	for i, conversation := range conversations {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		if conversation.ID != "" {
+			fmt.Fprintf(&b, "Conversation %s:\n", conversation.ID)
+		} else {
+			b.WriteString("Other memories:\n")
+		}
+		for _, memory := range conversation.Memories {
+			fmt.Fprintf(&b, "[%s] %s: %s\n",
+				memory.Record.Timestamp.Format("2006-01-02 15:04"), memory.Speaker, memory.Record.Contents)
+		}
+	}
+	return b.String(), nil
+}
+
+// TextTemplate formats memories with a text/template, executed with the
+// []Conversation as its data.
+func TextTemplate(tmpl *template.Template) Template {
+	return TemplateFunc(func(conversations []Conversation) (string, error) {
+		var b strings.Builder
+		if err := tmpl.Execute(&b, conversations); err != nil {
+			return "", err
+		}
+		return b.String(), nil
+	})
+}
+
+// groupConversations groups memories by conversation, orders each group
+// chronologically, and orders the groups by their earliest memory.
+func groupConversations(memories []Memory) []Conversation {
+	index := make(map[string]int)
+	var conversations []Conversation
+	for _, memory := range memories {
+		id := memory.Record.Metadata[core.MetadataConversation]
+		i, ok := index[id]
+		if !ok {
+			i = len(conversations)
+			index[id] = i
+			conversations = append(conversations, Conversation{ID: id})
+		}
+		conversations[i].Memories = append(conversations[i].Memories, memory)
+	}
+
+	for i := range conversations {
+		slices.SortStableFunc(conversations[i].Memories, compareMemories)
+	}
+	slices.SortStableFunc(conversations, func(a, b Conversation) int {
+		return compareMemories(a.Memories[0], b.Memories[0])
+	})
+	return conversations
+}
+
+// compareMemories orders memories by timestamp, then by record ID.
+func compareMemories(a, b Memory) int {
+	if c := a.Record.Timestamp.Compare(b.Record.Timestamp); c != 0 {
+		return c
+	}
+	return cmp.Compare(a.Record.Id, b.Record.Id)
+}
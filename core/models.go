@@ -19,6 +19,7 @@ package core
 
 import (
 	"encoding/binary"
+	"strings"
 	"time"
 
 	"github.com/go-crypt/x/blake2b"
@@ -47,6 +48,10 @@ const (
 	SpeakerTypeAI
 )
 
+// MetadataConversation is the ChatRecord metadata key that identifies
+// the conversation a message belongs to.
+const MetadataConversation = "conversation"
+
 // ChatRecord represents a single message in a conversation.
 // It may be enriched with embeddings and concepts during processing.
 type ChatRecord struct {
@@ -83,6 +88,36 @@ type ConceptRef struct {
 	Importance int // Importance score from 1-10
 }
 
+// Span identifies a byte range within a chat record's Contents.
+type Span struct {
+	Start int // Byte offset of the first byte in the span
+	End   int // Byte offset one past the last byte in the span
+}
+
+// Of returns the text covered by the span within contents. A span reaching
+// past the end of contents, such as one stored before the contents changed,
+// is clipped to it.
+func (s Span) Of(contents string) string {
+	end := min(max(s.End, 0), len(contents))
+	start := min(max(s.Start, 0), end)
+	return contents[start:end]
+}
+
+// Chunk is an embedded span of a chat record's contents.
+// Long messages are split into chunks so each part can be matched independently.
+type Chunk struct {
+	RecordId ID
+	Index    int       // Position of the chunk within the record
+	Start    int       // Byte offset of the chunk start within the record's Contents
+	End      int       // Byte offset one past the chunk end within the record's Contents
+	Vector   []float32 // Embedding vector for the chunk text
+}
+
+// Span returns the byte range of the record's Contents covered by the chunk.
+func (c *Chunk) Span() Span {
+	return Span{Start: c.Start, End: c.End}
+}
+
 // SimilarityMatch represents a chat record match from vector similarity search.
 type SimilarityMatch struct {
 	RecordId ID
@@ -91,8 +126,82 @@ type SimilarityMatch struct {
 
 // SearchResult represents a search result with the full record and relevance score.
 type SearchResult struct {
-	Record *ChatRecord
-	Score  float32
+	Record    *ChatRecord
+	Score     float32
+	Span      *Span           // Chunk of Record.Contents that produced the match; nil for whole-record matches
+	Breakdown *ScoreBreakdown // How Score was computed; nil for raw similarity matches
+	Snippet   *Snippet        // Passage showing why the record matched; nil unless snippets are enabled
+}
+
+// SnippetKind describes how a snippet's passage was chosen.
+type SnippetKind int
+
+const (
+	// SnippetLeading is the start of the contents, used when no passage matched.
+	SnippetLeading SnippetKind = iota
+	// SnippetKeyword is the passage containing the most query terms.
+	SnippetKeyword
+	// SnippetSemantic is the sentence most similar to the query.
+	SnippetSemantic
+)
+
+// Snippet is a passage of a search result's contents explaining why it matched.
+type Snippet struct {
+	Kind       SnippetKind
+	Span       Span     // Passage of Record.Contents
+	Highlights []Span   // Query terms within the passage, as byte ranges of Record.Contents
+	Concepts   []string // Names of the query concepts matched on the record
+}
+
+// Text returns the passage within contents.
+func (s *Snippet) Text(contents string) string {
+	return s.Span.Of(contents)
+}
+
+// Highlight returns the passage within contents with each highlight wrapped
+// in open and close, such as "<mark>" and "</mark>".
+func (s *Snippet) Highlight(contents, open, close string) string {
+	var b strings.Builder
+	pos := s.Span.Start
+	for _, highlight := range s.Highlights {
+		b.WriteString(Span{Start: pos, End: highlight.Start}.Of(contents))
+		b.WriteString(open)
+		b.WriteString(highlight.Of(contents))
+		b.WriteString(close)
+		pos = highlight.End
+	}
+	b.WriteString(Span{Start: pos, End: s.Span.End}.Of(contents))
+	return b.String()
+}
+
+// ConceptSearchResult is a concept matched by vector similarity.
+type ConceptSearchResult struct {
+	Concept *Concept
+	Score   float32
+}
+
+// ScoreBreakdown describes the components of a search result's score.
+// The signal fields and Formula are only filled in when a search is run in explain mode.
+type ScoreBreakdown struct {
+	Relevance     float32 // Score from ranking or fusion, before time decay
+	Recency       float32 // Recency factor in [0, 1]; 1 when time decay is disabled
+	RecencyWeight float32 // Share of Relevance subject to time decay
+	Reranked      bool    // Relevance was assigned by a reranker rather than retrieval scoring
+
+	Semantic       bool                  // Found by vector search
+	Similarity     float32               // Raw cosine similarity to the query; zero if not Semantic
+	Concepts       []ConceptContribution // Query concepts matched on the record
+	KeywordMatches []string              // Query words found in the record
+	Verbatim       bool                  // Every query word was found, so the verbatim boost applied
+	Formula        string                // Human-readable computation of the final score
+}
+
+// ConceptContribution is a query concept matched on a search result.
+type ConceptContribution struct {
+	Name       string
+	Type       string
+	Importance int     // The record's importance score for the concept (1-10)
+	Similarity float32 // 1 for an exact match, the cosine similarity for an expanded match
 }
 
 // Checkpoint represents the processing state for a processor type.
@@ -90,6 +90,44 @@ func TestConcept_Tuple(t *testing.T) {
 	}
 }
 
+func TestSnippet_Highlight(t *testing.T) {
+	contents := "We talked about machine learning and then more machine talk."
+	snippet := &Snippet{
+		Kind:       SnippetKeyword,
+		Span:       Span{Start: 16, End: 59},
+		Highlights: []Span{{Start: 16, End: 32}, {Start: 47, End: 54}},
+	}
+
+	if got, want := snippet.Text(contents), "machine learning and then more machine talk"; got != want {
+		t.Errorf("Snippet.Text() = %q, want %q", got, want)
+	}
+	if got, want := snippet.Highlight(contents, "<mark>", "</mark>"),
+		"<mark>machine learning</mark> and then more <mark>machine</mark> talk"; got != want {
+		t.Errorf("Snippet.Highlight() = %q, want %q", got, want)
+	}
+}
+
+func TestSpan_Of(t *testing.T) {
+	tests := []struct {
+		name string
+		span Span
+		want string
+	}{
+		{"within contents", Span{Start: 2, End: 5}, "llo"},
+		{"end past contents", Span{Start: 2, End: 50}, "llo"},
+		{"start past contents", Span{Start: 20, End: 50}, ""},
+		{"reversed", Span{Start: 4, End: 2}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.span.Of("hello"); got != tt.want {
+				t.Errorf("Span.Of() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
 // Test MUS serialization for ID
 func TestIDMUS_MarshalUnmarshal(t *testing.T) {
 	tests := []struct {
@@ -319,6 +319,78 @@ func (s conceptRefMUS) Skip(bs []byte) (n int, err error) {
 	return
 }
 
+var ChunkMUS = chunkMUS{}
+
+type chunkMUS struct{}
+
+func (s chunkMUS) Marshal(v Chunk, bs []byte) (n int) {
+	n = IDMUS.Marshal(v.RecordId, bs)
+	n += varint.Int.Marshal(v.Index, bs[n:])
+	n += varint.Int.Marshal(v.Start, bs[n:])
+	n += varint.Int.Marshal(v.End, bs[n:])
+	return n + sliceaΔ7Tr04CnuL9w2IzwpltxQΞΞ.Marshal(v.Vector, bs[n:])
+}
+
+func (s chunkMUS) Unmarshal(bs []byte) (v Chunk, n int, err error) {
+	v.RecordId, n, err = IDMUS.Unmarshal(bs)
+	if err != nil {
+		return
+	}
+	var n1 int
+	v.Index, n1, err = varint.Int.Unmarshal(bs[n:])
+	n += n1
+	if err != nil {
+		return
+	}
+	v.Start, n1, err = varint.Int.Unmarshal(bs[n:])
+	n += n1
+	if err != nil {
+		return
+	}
+	v.End, n1, err = varint.Int.Unmarshal(bs[n:])
+	n += n1
+	if err != nil {
+		return
+	}
+	v.Vector, n1, err = sliceaΔ7Tr04CnuL9w2IzwpltxQΞΞ.Unmarshal(bs[n:])
+	n += n1
+	return
+}
+
+func (s chunkMUS) Size(v Chunk) (size int) {
+	size = IDMUS.Size(v.RecordId)
+	size += varint.Int.Size(v.Index)
+	size += varint.Int.Size(v.Start)
+	size += varint.Int.Size(v.End)
+	return size + sliceaΔ7Tr04CnuL9w2IzwpltxQΞΞ.Size(v.Vector)
+}
+
+func (s chunkMUS) Skip(bs []byte) (n int, err error) {
+	n, err = IDMUS.Skip(bs)
+	if err != nil {
+		return
+	}
+	var n1 int
+	n1, err = varint.Int.Skip(bs[n:])
+	n += n1
+	if err != nil {
+		return
+	}
+	n1, err = varint.Int.Skip(bs[n:])
+	n += n1
+	if err != nil {
+		return
+	}
+	n1, err = varint.Int.Skip(bs[n:])
+	n += n1
+	if err != nil {
+		return
+	}
+	n1, err = sliceaΔ7Tr04CnuL9w2IzwpltxQΞΞ.Skip(bs[n:])
+	n += n1
+	return
+}
+
 var CheckpointMUS = checkpointMUS{}
 
 type checkpointMUS struct{}
@@ -35,23 +35,25 @@ type Database struct {
 	logger         *slog.Logger
 }
 
-// DatabaseOption configures a Database.
-type DatabaseOption func(*databaseOptions)
-
-type databaseOptions struct {
-	aiConfig *ai.Config
-}
-
 func NewDatabase(filePath string, opts ...DatabaseOption) (*Database, error) {
 	// Apply options
 	options := &databaseOptions{
 		aiConfig: ai.DefaultConfig(), // Default if not provided
+		logger:   slog.Default(),
 	}
 	for _, opt := range opts {
 		opt(options)
 	}
+	if options.aiConfig == nil {
+		options.aiConfig = ai.DefaultConfig()
+	}
+	if options.logger == nil {
+		options.logger = slog.Default()
+	}
+
 	// Open backend
-	backend, err := badger.OpenBackend(filePath, false)
+	backendOpts := append([]badger.BackendOption{badger.WithLogger(options.logger)}, options.backendOpts...)
+	backend, err := badger.OpenBackend(filePath, options.inMemory, backendOpts...)
 	if err != nil {
 		return nil, err
 	}
@@ -75,7 +77,7 @@ func NewDatabase(filePath string, opts ...DatabaseOption) (*Database, error) {
 	checkpointRepo := badger.NewCheckpointRepository(backend)
 
 	// Create AI provider with configured settings
-	provider, err := openai.NewProvider(options.aiConfig)
+	provider, err := newProvider(options)
 	if err != nil {
 		conceptRepo.Close()
 		chatRepo.Close()
@@ -89,7 +91,7 @@ func NewDatabase(filePath string, opts ...DatabaseOption) (*Database, error) {
 		conceptRepo:    conceptRepo,
 		checkpointRepo: checkpointRepo,
 		provider:       provider,
-		logger:         slog.Default(),
+		logger:         options.logger,
 	}, nil
 }
 
@@ -136,3 +138,57 @@ func (db *Database) CheckpointRepository() storage.CheckpointRepository {
 func (db *Database) NewSearcher(opts ...search.Option) (*search.Searcher, error) {
 	return search.NewSearcher(db.chatRepo, db.conceptRepo, db.provider, opts...)
 }
+
+// newProvider returns the AI provider selected by the options. Without an
+// injected provider, or both an embedder and a concept extractor, an
+// OpenAI-compatible provider is created from the AI config.
+func newProvider(options *databaseOptions) (ai.AIProvider, error) {
+	provider := options.provider
+	if provider == nil && (options.embedder == nil || options.extractor == nil) {
+		var err error
+		provider, err = openai.NewProvider(options.aiConfig)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if options.embedder == nil && options.extractor == nil {
+		return provider, nil
+	}
+
+	composed := &composedProvider{
+		embedder:  options.embedder,
+		extractor: options.extractor,
+		base:      provider,
+	}
+	if composed.embedder == nil {
+		composed.embedder = provider.Embedder()
+	}
+	if composed.extractor == nil {
+		composed.extractor = provider.ConceptExtractor()
+	}
+	return composed, nil
+}
+
+// composedProvider combines individually supplied AI services into an ai.AIProvider.
+type composedProvider struct {
+	embedder  ai.Embedder
+	extractor ai.ConceptExtractor
+	base      ai.AIProvider // provider supplying any services not overridden, may be nil
+}
+
+var _ ai.AIProvider = (*composedProvider)(nil)
+
+func (p *composedProvider) Embedder() ai.Embedder {
+	return p.embedder
+}
+
+func (p *composedProvider) ConceptExtractor() ai.ConceptExtractor {
+	return p.extractor
+}
+
+func (p *composedProvider) Close() error {
+	if p.base == nil {
+		return nil
+	}
+	return p.base.Close()
+}
@@ -1,10 +1,19 @@
 package memorit
 
 import (
+	"context"
+	"io"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/poiesic/memorit/ai"
+	"github.com/poiesic/memorit/ai/mock"
+	"github.com/poiesic/memorit/core"
+	"github.com/poiesic/memorit/storage/badger"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -67,3 +76,130 @@ func TestDatabase_FactoryMethods(t *testing.T) {
 		require.NotNil(t, searcher)
 	})
 }
+
+func TestNewDatabase_Options(t *testing.T) {
+	t.Run("in memory", func(t *testing.T) {
+		db, err := NewDatabase("", WithInMemory())
+		require.NoError(t, err)
+		require.NotNil(t, db)
+		defer db.Close()
+
+		assert.False(t, db.backend.IsClosed())
+	})
+
+	t.Run("logger and AI config", func(t *testing.T) {
+		logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+		db, err := NewDatabase("",
+			WithInMemory(),
+			WithLogger(logger),
+			WithAIConfig(ai.NewConfig(ai.WithEmbeddingModel("custom-model"))),
+		)
+		require.NoError(t, err)
+		defer db.Close()
+
+		assert.Same(t, logger, db.logger)
+	})
+
+	t.Run("storage tuning", func(t *testing.T) {
+		tmpDir := filepath.Join(t.TempDir(), "tuned_db")
+		db, err := NewDatabase(tmpDir,
+			WithBlockCacheSize(8<<20),
+			WithIndexCacheSize(4<<20),
+			WithCompression(badger.CompressionSnappy),
+			WithSyncWrites(true),
+			WithGCInterval(time.Minute),
+			WithGCDiscardRatio(0.6),
+			WithValueLogFileSize(8<<20),
+			WithMemTableSize(8<<20),
+			WithVectorCache(2),
+			WithQuantization(badger.QuantizationInt8, 2),
+		)
+		require.NoError(t, err)
+		defer db.Close()
+
+		assert.Equal(t, badger.QuantizationInt8, db.backend.Quantization())
+	})
+
+	t.Run("invalid tuning", func(t *testing.T) {
+		db, err := NewDatabase("", WithInMemory(), WithGCDiscardRatio(2))
+		assert.Error(t, err)
+		assert.Nil(t, db)
+	})
+}
+
+// closeTrackingProvider records whether Close was called and counts embedding calls.
+type closeTrackingProvider struct {
+	ai.AIProvider
+	closed bool
+	embeds atomic.Int32
+}
+
+func (p *closeTrackingProvider) Embedder() ai.Embedder {
+	return countingEmbedder{Embedder: p.AIProvider.Embedder(), calls: &p.embeds}
+}
+
+type countingEmbedder struct {
+	ai.Embedder
+	calls *atomic.Int32
+}
+
+func (e countingEmbedder) EmbedTexts(ctx context.Context, texts []string) ([][]float32, error) {
+	e.calls.Add(1)
+	return e.Embedder.EmbedTexts(ctx, texts)
+}
+
+func (p *closeTrackingProvider) Close() error {
+	p.closed = true
+	return p.AIProvider.Close()
+}
+
+func TestNewDatabase_CustomAI(t *testing.T) {
+	t.Run("custom provider", func(t *testing.T) {
+		provider := &closeTrackingProvider{AIProvider: mock.NewMockProvider()}
+		db, err := NewDatabase("", WithInMemory(), WithAIProvider(provider))
+		require.NoError(t, err)
+
+		pipeline, err := db.NewIngestionPipeline()
+		require.NoError(t, err)
+		defer pipeline.Release()
+
+		ctx := context.Background()
+		err = pipeline.Ingest(ctx, core.SpeakerTypeHuman, []string{"offline ingestion works"}, nil)
+		require.NoError(t, err)
+
+		// Embeddings are generated by the injected provider
+		require.Eventually(t, func() bool {
+			return provider.embeds.Load() > 0
+		}, 5*time.Second, 10*time.Millisecond)
+
+		searcher, err := db.NewSearcher()
+		require.NoError(t, err)
+		_, err = searcher.FindSimilar(ctx, "offline ingestion", 5)
+		require.NoError(t, err)
+
+		require.NoError(t, db.Close())
+		assert.True(t, provider.closed)
+	})
+
+	t.Run("separate embedder and extractor", func(t *testing.T) {
+		embedder := mock.NewMockEmbedder()
+		extractor := mock.NewMockConceptExtractor()
+		db, err := NewDatabase("", WithInMemory(), WithEmbedder(embedder), WithConceptExtractor(extractor))
+		require.NoError(t, err)
+		defer db.Close()
+
+		assert.Same(t, embedder, db.provider.Embedder())
+		assert.Same(t, extractor, db.provider.ConceptExtractor())
+	})
+
+	t.Run("embedder overrides provider", func(t *testing.T) {
+		provider := mock.NewMockProvider()
+		embedder := mock.NewMockEmbedder()
+		db, err := NewDatabase("", WithInMemory(), WithAIProvider(provider), WithEmbedder(embedder))
+		require.NoError(t, err)
+		defer db.Close()
+
+		assert.Same(t, embedder, db.provider.Embedder())
+		assert.Same(t, provider.ConceptExtractor(), db.provider.ConceptExtractor())
+	})
+}
@@ -0,0 +1,114 @@
+// Copyright 2025 Poiesic Systems
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+
+package eval
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/poiesic/memorit/core"
+)
+
+// Query is a labeled evaluation query. Relevant records are identified by
+// ID, by contents, or both; list each relevant record only once.
+type Query struct {
+	ID               string    `json:"id"`
+	Text             string    `json:"query"`
+	RelevantIDs      []core.ID `json:"relevant_ids,omitempty"`
+	RelevantContents []string  `json:"relevant_contents,omitempty"`
+}
+
+// Dataset is a set of labeled queries.
+type Dataset struct {
+	Queries []Query
+}
+
+// LoadDataset reads a dataset from a JSON Lines file.
+func LoadDataset(path string) (*Dataset, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadDataset(f)
+}
+
+// ReadDataset reads a dataset in JSON Lines format, one Query per line:
+//
+//	{"id": "diet", "query": "what did I say about my diet", "relevant_ids": [42]}
+//	{"id": "pets", "query": "my dog's name", "relevant_contents": ["Our dog is called Biscuit"]}
+//
+// Blank lines are ignored. Queries without an ID are numbered by line.
+func ReadDataset(r io.Reader) (*Dataset, error) {
+	dataset := &Dataset{}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		var query Query
+		if err := json.Unmarshal([]byte(text), &query); err != nil {
+			return nil, fmt.Errorf("%w: line %d: %v", ErrInvalidDataset, line, err)
+		}
+		if query.ID == "" {
+			query.ID = fmt.Sprintf("line-%d", line)
+		}
+		if query.Text == "" {
+			return nil, fmt.Errorf("%w: query %s has no text", ErrInvalidDataset, query.ID)
+		}
+		if len(query.RelevantIDs) == 0 && len(query.RelevantContents) == 0 {
+			return nil, fmt.Errorf("%w: query %s has no relevant records", ErrInvalidDataset, query.ID)
+		}
+		dataset.Queries = append(dataset.Queries, query)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return dataset, nil
+}
+
+// relevant returns the number of relevant records for the query.
+func (q *Query) relevant() int {
+	return len(q.RelevantIDs) + len(q.RelevantContents)
+}
+
+// judge returns the index of the relevance judgment a record satisfies, or -1.
+// IDs are numbered before contents. Contents match ignoring case and whitespace.
+func (q *Query) judge(record *core.ChatRecord) int {
+	for i, id := range q.RelevantIDs {
+		if record.Id == id {
+			return i
+		}
+	}
+	contents := normalizeContents(record.Contents)
+	for i, relevant := range q.RelevantContents {
+		if contents == normalizeContents(relevant) {
+			return len(q.RelevantIDs) + i
+		}
+	}
+	return -1
+}
+
+// normalizeContents lowercases contents and collapses whitespace.
+func normalizeContents(contents string) string {
+	return strings.ToLower(strings.Join(strings.Fields(contents), " "))
+}
@@ -0,0 +1,45 @@
+// Copyright 2025 Poiesic Systems
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+
+// Package eval measures search quality offline against a labeled dataset.
+//
+// A Dataset is a JSON Lines file of queries, each with the IDs or contents
+// of the records that should be found. An Evaluator runs every query through
+// a Searcher configured with the options under test and reports, averaged
+// over queries:
+//
+//   - recall@k: the fraction of relevant records in the top k results
+//   - MRR: the mean reciprocal rank of the first relevant result
+//   - nDCG@k: normalized discounted cumulative gain with binary relevance
+//   - latency percentiles
+//
+// To make runs deterministic in CI, search with the ai/mock provider, or
+// record a real model's responses once with NewRecordingProvider and replay
+// them from the saved Cassette with NewReplayProvider.
+//
+// # Usage
+//
+//	dataset, err := eval.LoadDataset("queries.jsonl")
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	evaluator, err := eval.NewEvaluator(searcher, eval.WithCutoffs(1, 5, 10))
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	report, err := evaluator.Run(ctx, dataset)
+//
+// The memorit eval command wraps this for a database on disk.
+package eval
@@ -0,0 +1,32 @@
+// Copyright 2025 Poiesic Systems
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+
+package eval
+
+import "errors"
+
+var (
+	// ErrSearcherRequired is returned when a searcher is not provided.
+	ErrSearcherRequired = errors.New("searcher required")
+
+	// ErrInvalidDataset is returned when a dataset query has no text or no relevance judgments.
+	ErrInvalidDataset = errors.New("invalid dataset")
+
+	// ErrInvalidCutoff is returned when no rank cutoffs are given or a cutoff is not positive.
+	ErrInvalidCutoff = errors.New("rank cutoff must be positive")
+
+	// ErrNotRecorded is returned by a replay provider for an input missing from its cassette.
+	ErrNotRecorded = errors.New("response not recorded")
+)
@@ -0,0 +1,202 @@
+// Copyright 2025 Poiesic Systems
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+
+package eval
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/poiesic/memorit/search"
+)
+
+// DefaultCutoffs are the default ranks at which recall and nDCG are reported.
+var DefaultCutoffs = []int{1, 5, 10}
+
+// Searcher runs searches. *search.Searcher implements it.
+type Searcher interface {
+	Search(ctx context.Context, req *search.SearchRequest) (*search.SearchResponse, error)
+}
+
+var _ Searcher = (*search.Searcher)(nil)
+
+// Latency summarizes search latencies.
+type Latency struct {
+	P50  time.Duration
+	P90  time.Duration
+	P99  time.Duration
+	Mean time.Duration
+	Max  time.Duration
+}
+
+// QueryResult holds the metrics of a single query.
+type QueryResult struct {
+	ID             string
+	Recall         map[int]float64 // Recall at each cutoff
+	NDCG           map[int]float64 // nDCG at each cutoff
+	ReciprocalRank float64
+	Latency        time.Duration
+	Skipped        []search.Stage // Stages skipped because they failed
+}
+
+// Report holds the metrics of an evaluation run, averaged over queries.
+type Report struct {
+	Queries int
+	Cutoffs []int
+	Recall  map[int]float64 // Mean recall at each cutoff
+	NDCG    map[int]float64 // Mean nDCG at each cutoff
+	MRR     float64
+	Latency Latency
+	Results []QueryResult
+}
+
+// Evaluator runs a dataset's queries against a searcher and scores the results.
+type Evaluator struct {
+	searcher Searcher
+	cutoffs  []int
+	request  search.SearchRequest
+	logger   *slog.Logger
+	now      func() time.Time
+}
+
+// Option configures an Evaluator.
+type Option func(*Evaluator) error
+
+// WithCutoffs sets the ranks at which recall and nDCG are reported.
+// At least one cutoff is required. Default is DefaultCutoffs.
+func WithCutoffs(cutoffs ...int) Option {
+	return func(e *Evaluator) error {
+		if len(cutoffs) == 0 {
+			return ErrInvalidCutoff
+		}
+		for _, k := range cutoffs {
+			if k <= 0 {
+				return ErrInvalidCutoff
+			}
+		}
+		e.cutoffs = slices.Clone(cutoffs)
+		slices.Sort(e.cutoffs)
+		e.cutoffs = slices.Compact(e.cutoffs)
+		return nil
+	}
+}
+
+// WithRequest sets the search options, such as Fusion or Filter, used for
+// every query. Query and MaxHits are set for each query.
+func WithRequest(req search.SearchRequest) Option {
+	return func(e *Evaluator) error {
+		e.request = req
+		return nil
+	}
+}
+
+// WithLogger sets a custom logger.
+// Default is slog.Default().
+func WithLogger(logger *slog.Logger) Option {
+	return func(e *Evaluator) error {
+		if logger == nil {
+			logger = slog.Default()
+		}
+		e.logger = logger
+		return nil
+	}
+}
+
+// NewEvaluator creates an evaluator for searcher.
+func NewEvaluator(searcher Searcher, opts ...Option) (*Evaluator, error) {
+	if searcher == nil {
+		return nil, ErrSearcherRequired
+	}
+	e := &Evaluator{
+		searcher: searcher,
+		cutoffs:  slices.Clone(DefaultCutoffs),
+		logger:   slog.Default(),
+		now:      time.Now,
+	}
+	for _, opt := range opts {
+		if err := opt(e); err != nil {
+			return nil, err
+		}
+	}
+	return e, nil
+}
+
+// Run searches for every query in the dataset, retrieving as many results
+// as the largest cutoff, and reports recall@k, MRR, nDCG@k and latency.
+// Queries are run one at a time so latencies are not skewed by contention.
+func (e *Evaluator) Run(ctx context.Context, dataset *Dataset) (*Report, error) {
+	maxHits := e.cutoffs[len(e.cutoffs)-1]
+	report := &Report{
+		Queries: len(dataset.Queries),
+		Cutoffs: e.cutoffs,
+		Recall:  make(map[int]float64, len(e.cutoffs)),
+		NDCG:    make(map[int]float64, len(e.cutoffs)),
+		Results: make([]QueryResult, 0, len(dataset.Queries)),
+	}
+	latencies := make([]time.Duration, 0, len(dataset.Queries))
+
+	for i := range dataset.Queries {
+		query := &dataset.Queries[i]
+		req := e.request
+		req.Query = query.Text
+		req.MaxHits = maxHits
+
+		start := e.now()
+		response, err := e.searcher.Search(ctx, &req)
+		if err != nil {
+			e.logger.Error("evaluation query failed", "query", query.ID, "err", err)
+			return nil, err
+		}
+		latency := e.now().Sub(start)
+
+		judgments := make([]int, len(response.Results))
+		for j, result := range response.Results {
+			judgments[j] = query.judge(result.Record)
+		}
+		relevant := gains(judgments)
+
+		result := QueryResult{
+			ID:             query.ID,
+			Recall:         make(map[int]float64, len(e.cutoffs)),
+			NDCG:           make(map[int]float64, len(e.cutoffs)),
+			ReciprocalRank: reciprocalRank(relevant),
+			Latency:        latency,
+		}
+		for _, k := range e.cutoffs {
+			result.Recall[k] = recallAt(relevant, query.relevant(), k)
+			result.NDCG[k] = ndcgAt(relevant, query.relevant(), k)
+			report.Recall[k] += result.Recall[k]
+			report.NDCG[k] += result.NDCG[k]
+		}
+		for _, skipped := range response.Skipped {
+			result.Skipped = append(result.Skipped, skipped.Stage)
+		}
+		report.MRR += result.ReciprocalRank
+		report.Results = append(report.Results, result)
+		latencies = append(latencies, latency)
+	}
+
+	if n := float64(len(dataset.Queries)); n > 0 {
+		for _, k := range e.cutoffs {
+			report.Recall[k] /= n
+			report.NDCG[k] /= n
+		}
+		report.MRR /= n
+	}
+	report.Latency = latencyStats(latencies)
+	return report, nil
+}
@@ -0,0 +1,153 @@
+package eval
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/poiesic/memorit/core"
+	"github.com/poiesic/memorit/search"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSearcher returns canned results per query and records the requests it receives.
+type fakeSearcher struct {
+	results  map[string][]*core.ChatRecord
+	skipped  map[string][]search.SkippedStage
+	requests []search.SearchRequest
+	err      error
+}
+
+func (f *fakeSearcher) Search(ctx context.Context, req *search.SearchRequest) (*search.SearchResponse, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	f.requests = append(f.requests, *req)
+	response := &search.SearchResponse{Skipped: f.skipped[req.Query]}
+	for _, record := range f.results[req.Query] {
+		response.Results = append(response.Results, &core.SearchResult{Record: record})
+	}
+	return response, nil
+}
+
+func TestReadDataset(t *testing.T) {
+	dataset, err := ReadDataset(strings.NewReader(`
+{"id": "diet", "query": "what did I say about my diet", "relevant_ids": [42]}
+
+{"query": "my dog's name", "relevant_contents": ["Our dog is called Biscuit"]}
+`))
+	require.NoError(t, err)
+	require.Len(t, dataset.Queries, 2)
+	assert.Equal(t, "diet", dataset.Queries[0].ID)
+	assert.Equal(t, []core.ID{42}, dataset.Queries[0].RelevantIDs)
+	assert.Equal(t, "line-4", dataset.Queries[1].ID)
+
+	_, err = ReadDataset(strings.NewReader(`{"id": "x", "query": "no judgments"}`))
+	assert.ErrorIs(t, err, ErrInvalidDataset)
+	_, err = ReadDataset(strings.NewReader(`{"id": "x", "relevant_ids": [1]}`))
+	assert.ErrorIs(t, err, ErrInvalidDataset)
+	_, err = ReadDataset(strings.NewReader(`not json`))
+	assert.ErrorIs(t, err, ErrInvalidDataset)
+}
+
+func TestNewEvaluator(t *testing.T) {
+	_, err := NewEvaluator(nil)
+	assert.ErrorIs(t, err, ErrSearcherRequired)
+
+	_, err = NewEvaluator(&fakeSearcher{}, WithCutoffs())
+	assert.ErrorIs(t, err, ErrInvalidCutoff)
+	_, err = NewEvaluator(&fakeSearcher{}, WithCutoffs(5, 0))
+	assert.ErrorIs(t, err, ErrInvalidCutoff)
+
+	evaluator, err := NewEvaluator(&fakeSearcher{}, WithCutoffs(10, 3, 10))
+	require.NoError(t, err)
+	assert.Equal(t, []int{3, 10}, evaluator.cutoffs)
+}
+
+func TestEvaluator_Run(t *testing.T) {
+	searcher := &fakeSearcher{
+		results: map[string][]*core.ChatRecord{
+			"diet": {
+				{Id: 7, Contents: "I had pizza"},
+				{Id: 42, Contents: "I'm cutting carbs"},
+			},
+			"dog": {
+				{Id: 3, Contents: "Our  dog is called BISCUIT"},
+				{Id: 4, Contents: "Our dog is called Biscuit"},
+			},
+			"cat": {
+				{Id: 9, Contents: "Cats are great"},
+			},
+		},
+		skipped: map[string][]search.SkippedStage{
+			"cat": {{Stage: search.StageConceptual, Err: errors.New("extractor down")}},
+		},
+	}
+	dataset := &Dataset{Queries: []Query{
+		{ID: "diet", Text: "diet", RelevantIDs: []core.ID{42, 43}},
+		{ID: "dog", Text: "dog", RelevantContents: []string{"our dog is called biscuit"}},
+		{ID: "cat", Text: "cat", RelevantIDs: []core.ID{1}},
+	}}
+
+	evaluator, err := NewEvaluator(searcher,
+		WithCutoffs(1, 2),
+		WithRequest(search.SearchRequest{Fusion: search.FusionRRF, Query: "ignored", MaxHits: 99}))
+	require.NoError(t, err)
+
+	// Each query takes one more millisecond than the last
+	var tick time.Duration
+	clock := time.Unix(0, 0)
+	evaluator.now = func() time.Time {
+		tick += 500 * time.Microsecond
+		clock = clock.Add(tick)
+		return clock
+	}
+
+	report, err := evaluator.Run(context.Background(), dataset)
+	require.NoError(t, err)
+
+	require.Len(t, searcher.requests, 3)
+	for i, req := range searcher.requests {
+		assert.Equal(t, dataset.Queries[i].Text, req.Query)
+		assert.Equal(t, 2, req.MaxHits)
+		assert.Equal(t, search.FusionRRF, req.Fusion)
+	}
+
+	require.Len(t, report.Results, 3)
+	diet, dog, cat := report.Results[0], report.Results[1], report.Results[2]
+
+	assert.Equal(t, 0.0, diet.Recall[1])
+	assert.Equal(t, 0.5, diet.Recall[2])
+	assert.Equal(t, 0.5, diet.ReciprocalRank)
+
+	// Only the first of two records with matching contents counts
+	assert.Equal(t, 1.0, dog.Recall[1])
+	assert.Equal(t, 1.0, dog.NDCG[2])
+	assert.Equal(t, 1.0, dog.ReciprocalRank)
+
+	assert.Equal(t, 0.0, cat.ReciprocalRank)
+	assert.Equal(t, []search.Stage{search.StageConceptual}, cat.Skipped)
+
+	assert.Equal(t, 3, report.Queries)
+	assert.Equal(t, []int{1, 2}, report.Cutoffs)
+	assert.InDelta(t, 1.0/3, report.Recall[1], 1e-9)
+	assert.InDelta(t, 1.5/3, report.Recall[2], 1e-9)
+	assert.InDelta(t, 1.5/3, report.MRR, 1e-9)
+
+	assert.Equal(t, []time.Duration{time.Millisecond, 2 * time.Millisecond, 3 * time.Millisecond},
+		[]time.Duration{diet.Latency, dog.Latency, cat.Latency})
+	assert.Equal(t, 2*time.Millisecond, report.Latency.P50)
+	assert.Equal(t, 3*time.Millisecond, report.Latency.Max)
+}
+
+func TestEvaluator_RunError(t *testing.T) {
+	searchErr := errors.New("search failed")
+	evaluator, err := NewEvaluator(&fakeSearcher{err: searchErr})
+	require.NoError(t, err)
+
+	_, err = evaluator.Run(context.Background(), &Dataset{Queries: []Query{{ID: "q", Text: "q", RelevantIDs: []core.ID{1}}}})
+	assert.ErrorIs(t, err, searchErr)
+}
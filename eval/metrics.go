@@ -0,0 +1,107 @@
+// Copyright 2025 Poiesic Systems
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+
+package eval
+
+import (
+	"math"
+	"slices"
+	"time"
+)
+
+// gains marks which ranked results are relevant. A result counts only the
+// first time it satisfies a judgment, so duplicate hits earn no credit.
+func gains(judgments []int) []bool {
+	satisfied := make(map[int]bool, len(judgments))
+	relevant := make([]bool, len(judgments))
+	for i, judgment := range judgments {
+		if judgment >= 0 && !satisfied[judgment] {
+			satisfied[judgment] = true
+			relevant[i] = true
+		}
+	}
+	return relevant
+}
+
+// recallAt returns the fraction of the total relevant records found in the top k results.
+func recallAt(relevant []bool, total, k int) float64 {
+	if total == 0 {
+		return 0
+	}
+	found := 0
+	for _, ok := range relevant[:min(k, len(relevant))] {
+		if ok {
+			found++
+		}
+	}
+	return float64(found) / float64(total)
+}
+
+// reciprocalRank returns 1 / the rank of the first relevant result, or 0 if there is none.
+func reciprocalRank(relevant []bool) float64 {
+	for i, ok := range relevant {
+		if ok {
+			return 1 / float64(i+1)
+		}
+	}
+	return 0
+}
+
+// ndcgAt returns the normalized discounted cumulative gain of the top k
+// results with binary relevance.
+func ndcgAt(relevant []bool, total, k int) float64 {
+	var dcg, ideal float64
+	for i, ok := range relevant[:min(k, len(relevant))] {
+		if ok {
+			dcg += 1 / math.Log2(float64(i+2))
+		}
+	}
+	for i := range min(k, total) {
+		ideal += 1 / math.Log2(float64(i+2))
+	}
+	if ideal == 0 {
+		return 0
+	}
+	return dcg / ideal
+}
+
+// percentile returns the nearest-rank p-th percentile (0-100) of sorted durations.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	return sorted[min(max(rank, 1), len(sorted))-1]
+}
+
+// latencyStats summarizes search latencies.
+func latencyStats(latencies []time.Duration) Latency {
+	sorted := slices.Clone(latencies)
+	slices.Sort(sorted)
+	var total time.Duration
+	for _, latency := range sorted {
+		total += latency
+	}
+	stats := Latency{
+		P50: percentile(sorted, 50),
+		P90: percentile(sorted, 90),
+		P99: percentile(sorted, 99),
+	}
+	if len(sorted) > 0 {
+		stats.Mean = total / time.Duration(len(sorted))
+		stats.Max = sorted[len(sorted)-1]
+	}
+	return stats
+}
@@ -0,0 +1,45 @@
+package eval
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGains(t *testing.T) {
+	// The second hit on judgment 0 is a duplicate and earns nothing
+	assert.Equal(t, []bool{false, true, true, false}, gains([]int{-1, 0, 1, 0}))
+}
+
+func TestMetrics(t *testing.T) {
+	relevant := []bool{false, true, false, true}
+
+	assert.Equal(t, 0.0, recallAt(relevant, 2, 1))
+	assert.Equal(t, 0.5, recallAt(relevant, 2, 2))
+	assert.Equal(t, 1.0, recallAt(relevant, 2, 10))
+	assert.Equal(t, 0.0, recallAt(relevant, 0, 10))
+
+	assert.Equal(t, 0.5, reciprocalRank(relevant))
+	assert.Equal(t, 0.0, reciprocalRank([]bool{false, false}))
+
+	assert.Equal(t, 1.0, ndcgAt([]bool{true, true}, 2, 2))
+	assert.Equal(t, 0.0, ndcgAt([]bool{false}, 1, 1))
+	// dcg = 1/log2(3) + 1/log2(5), ideal = 1 + 1/log2(3)
+	assert.InDelta(t, 0.6509, ndcgAt(relevant, 2, 4), 1e-4)
+}
+
+func TestLatencyStats(t *testing.T) {
+	var latencies []time.Duration
+	for i := 100; i >= 1; i-- {
+		latencies = append(latencies, time.Duration(i)*time.Millisecond)
+	}
+	stats := latencyStats(latencies)
+	assert.Equal(t, 50*time.Millisecond, stats.P50)
+	assert.Equal(t, 90*time.Millisecond, stats.P90)
+	assert.Equal(t, 99*time.Millisecond, stats.P99)
+	assert.Equal(t, 100*time.Millisecond, stats.Max)
+	assert.Equal(t, 50500*time.Microsecond, stats.Mean)
+
+	assert.Equal(t, Latency{}, latencyStats(nil))
+}
@@ -0,0 +1,203 @@
+// Copyright 2025 Poiesic Systems
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+
+package eval
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"sync"
+
+	"github.com/poiesic/memorit/ai"
+)
+
+// Cassette holds recorded AI responses keyed by input text, so evaluation
+// runs can replay a real model's embeddings and concepts deterministically.
+// It is safe for concurrent use.
+type Cassette struct {
+	mu         sync.Mutex
+	embeddings map[string][]float32
+	concepts   map[string][]ai.ExtractedConcept
+}
+
+// cassetteFile is the JSON form of a Cassette.
+type cassetteFile struct {
+	Embeddings map[string][]float32             `json:"embeddings"`
+	Concepts   map[string][]ai.ExtractedConcept `json:"concepts"`
+}
+
+// NewCassette creates an empty cassette.
+func NewCassette() *Cassette {
+	return &Cassette{
+		embeddings: make(map[string][]float32),
+		concepts:   make(map[string][]ai.ExtractedConcept),
+	}
+}
+
+// LoadCassette reads a cassette saved with Save.
+func LoadCassette(path string) (*Cassette, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file cassetteFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("invalid cassette %s: %w", path, err)
+	}
+	c := NewCassette()
+	for text, embedding := range file.Embeddings {
+		c.embeddings[text] = embedding
+	}
+	for text, concepts := range file.Concepts {
+		c.concepts[text] = concepts
+	}
+	return c, nil
+}
+
+// Save writes the cassette to path as JSON.
+func (c *Cassette) Save(path string) error {
+	c.mu.Lock()
+	data, err := json.MarshalIndent(cassetteFile{Embeddings: c.embeddings, Concepts: c.concepts}, "", "  ")
+	c.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+func (c *Cassette) embedding(text string) ([]float32, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	embedding, ok := c.embeddings[text]
+	return slices.Clone(embedding), ok
+}
+
+func (c *Cassette) recordEmbedding(text string, embedding []float32) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.embeddings[text] = slices.Clone(embedding)
+}
+
+func (c *Cassette) extracted(text string) ([]ai.ExtractedConcept, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	concepts, ok := c.concepts[text]
+	return slices.Clone(concepts), ok
+}
+
+func (c *Cassette) recordConcepts(text string, concepts []ai.ExtractedConcept) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.concepts[text] = slices.Clone(concepts)
+}
+
+// NewRecordingProvider returns a provider that passes calls through to
+// provider and records every response in cassette.
+func NewRecordingProvider(provider ai.AIProvider, cassette *Cassette) ai.AIProvider {
+	return &cassetteProvider{cassette: cassette, base: provider}
+}
+
+// NewReplayProvider returns a provider that answers only from cassette.
+// Inputs that were not recorded fail with ErrNotRecorded.
+func NewReplayProvider(cassette *Cassette) ai.AIProvider {
+	return &cassetteProvider{cassette: cassette}
+}
+
+// cassetteProvider records responses from base, or replays them when base is nil.
+type cassetteProvider struct {
+	cassette *Cassette
+	base     ai.AIProvider
+}
+
+var (
+	_ ai.AIProvider       = (*cassetteProvider)(nil)
+	_ ai.Embedder         = (*cassetteProvider)(nil)
+	_ ai.ConceptExtractor = (*cassetteProvider)(nil)
+)
+
+func (p *cassetteProvider) Embedder() ai.Embedder {
+	return p
+}
+
+func (p *cassetteProvider) ConceptExtractor() ai.ConceptExtractor {
+	return p
+}
+
+func (p *cassetteProvider) Close() error {
+	if p.base == nil {
+		return nil
+	}
+	return p.base.Close()
+}
+
+func (p *cassetteProvider) EmbedText(ctx context.Context, text string) ([]float32, error) {
+	if p.base == nil {
+		embedding, ok := p.cassette.embedding(text)
+		if !ok {
+			return nil, fmt.Errorf("%w: embedding for %q", ErrNotRecorded, text)
+		}
+		return embedding, nil
+	}
+	embedding, err := p.base.Embedder().EmbedText(ctx, text)
+	if err != nil {
+		return nil, err
+	}
+	p.cassette.recordEmbedding(text, embedding)
+	return embedding, nil
+}
+
+func (p *cassetteProvider) EmbedTexts(ctx context.Context, texts []string) ([][]float32, error) {
+	if p.base == nil {
+		embeddings := make([][]float32, len(texts))
+		for i, text := range texts {
+			embedding, err := p.EmbedText(ctx, text)
+			if err != nil {
+				return nil, err
+			}
+			embeddings[i] = embedding
+		}
+		return embeddings, nil
+	}
+	embeddings, err := p.base.Embedder().EmbedTexts(ctx, texts)
+	if err != nil {
+		return nil, err
+	}
+	if len(embeddings) != len(texts) {
+		return nil, fmt.Errorf("embedding result mismatch. expected %d, received %d", len(texts), len(embeddings))
+	}
+	for i, text := range texts {
+		p.cassette.recordEmbedding(text, embeddings[i])
+	}
+	return embeddings, nil
+}
+
+func (p *cassetteProvider) ExtractConcepts(ctx context.Context, text string) ([]ai.ExtractedConcept, error) {
+	if p.base == nil {
+		concepts, ok := p.cassette.extracted(text)
+		if !ok {
+			return nil, fmt.Errorf("%w: concepts for %q", ErrNotRecorded, text)
+		}
+		return concepts, nil
+	}
+	concepts, err := p.base.ConceptExtractor().ExtractConcepts(ctx, text)
+	if err != nil {
+		return nil, err
+	}
+	p.cassette.recordConcepts(text, concepts)
+	return concepts, nil
+}
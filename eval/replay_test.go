@@ -0,0 +1,77 @@
+package eval
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/poiesic/memorit/ai"
+	"github.com/poiesic/memorit/ai/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	ctx := context.Background()
+	embedder := mock.NewMockEmbedder()
+	extractor := mock.NewMockConceptExtractor()
+	extractor.ExtractConceptsFunc = func(ctx context.Context, text string) ([]ai.ExtractedConcept, error) {
+		return []ai.ExtractedConcept{{Name: "dog", Type: "animal", Importance: 7}}, nil
+	}
+
+	cassette := NewCassette()
+	recorder := NewRecordingProvider(mock.NewMockProviderWithServices(embedder, extractor), cassette)
+
+	single, err := recorder.Embedder().EmbedText(ctx, "my dog")
+	require.NoError(t, err)
+	batch, err := recorder.Embedder().EmbedTexts(ctx, []string{"a cat", "a bird"})
+	require.NoError(t, err)
+	concepts, err := recorder.ConceptExtractor().ExtractConcepts(ctx, "my dog")
+	require.NoError(t, err)
+	require.NoError(t, recorder.Close())
+
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	require.NoError(t, cassette.Save(path))
+	loaded, err := LoadCassette(path)
+	require.NoError(t, err)
+
+	embedder.Reset()
+	extractor.Reset()
+	replay := NewReplayProvider(loaded)
+
+	replayed, err := replay.Embedder().EmbedText(ctx, "my dog")
+	require.NoError(t, err)
+	assert.Equal(t, single, replayed)
+
+	replayedBatch, err := replay.Embedder().EmbedTexts(ctx, []string{"a bird", "a cat"})
+	require.NoError(t, err)
+	assert.Equal(t, [][]float32{batch[1], batch[0]}, replayedBatch)
+
+	replayedConcepts, err := replay.ConceptExtractor().ExtractConcepts(ctx, "my dog")
+	require.NoError(t, err)
+	assert.Equal(t, concepts, replayedConcepts)
+
+	// Replay never calls a model
+	assert.Zero(t, embedder.CallCount())
+	assert.Zero(t, extractor.CallCount())
+
+	_, err = replay.Embedder().EmbedText(ctx, "unseen")
+	assert.ErrorIs(t, err, ErrNotRecorded)
+	_, err = replay.Embedder().EmbedTexts(ctx, []string{"my dog", "unseen"})
+	assert.ErrorIs(t, err, ErrNotRecorded)
+	_, err = replay.ConceptExtractor().ExtractConcepts(ctx, "unseen")
+	assert.ErrorIs(t, err, ErrNotRecorded)
+	assert.NoError(t, replay.Close())
+}
+
+func TestRecordingProvider_EmbeddingMismatch(t *testing.T) {
+	embedder := mock.NewMockEmbedder()
+	embedder.EmbedTextsFunc = func(ctx context.Context, texts []string) ([][]float32, error) {
+		return [][]float32{{1, 0}}, nil
+	}
+	cassette := NewCassette()
+	recorder := NewRecordingProvider(mock.NewMockProviderWithServices(embedder, mock.NewMockConceptExtractor()), cassette)
+
+	_, err := recorder.Embedder().EmbedTexts(context.Background(), []string{"a cat", "a bird"})
+	assert.ErrorContains(t, err, "mismatch")
+}
@@ -23,6 +23,7 @@ import (
 	"time"
 
 	"github.com/poiesic/memorit/ai"
+	"github.com/poiesic/memorit/chunk"
 	"github.com/poiesic/memorit/core"
 	"github.com/poiesic/memorit/storage"
 )
@@ -35,6 +36,7 @@ type embeddingProcessor struct {
 	chatRepository       storage.ChatRepository
 	checkpointRepository storage.CheckpointRepository
 	embedder             ai.Embedder
+	chunking             *chunk.Config // Chunking settings for long messages (nil = disabled)
 	lastID               core.ID
 	logger               *slog.Logger
 }
@@ -46,6 +48,7 @@ func newEmbeddingProcessor(
 	chatRepository storage.ChatRepository,
 	checkpointRepository storage.CheckpointRepository,
 	embedder ai.Embedder,
+	chunking *chunk.Config,
 	logger *slog.Logger,
 ) (processor, error) {
 	if chatRepository == nil {
@@ -64,6 +67,7 @@ func newEmbeddingProcessor(
 		chatRepository:       chatRepository,
 		checkpointRepository: checkpointRepository,
 		embedder:             embedder,
+		chunking:             chunking,
 		logger:               logger.With("processor", "embeddings"),
 	}, nil
 }
@@ -106,6 +110,12 @@ func (ep *embeddingProcessor) process(ctx context.Context, ids ...core.ID) error
 		return err
 	}
 
+	if ep.chunking != nil {
+		if err := ep.embedChunks(ctx, updated); err != nil {
+			return err
+		}
+	}
+
 	highestID := updated[len(updated)-1].Id
 	if highestID > ep.lastID {
 		ep.lastID = highestID
@@ -114,6 +124,55 @@ func (ep *embeddingProcessor) process(ctx context.Context, ids ...core.ID) error
 	return nil
 }
 
+// embedChunks splits long records into chunks and stores a vector for each chunk.
+func (ep *embeddingProcessor) embedChunks(ctx context.Context, records []*core.ChatRecord) error {
+	var chunks []core.Chunk
+	var texts []string
+	for _, record := range records {
+		for i, span := range chunk.Split(record.Contents, ep.chunking) {
+			chunks = append(chunks, core.Chunk{
+				RecordId: record.Id,
+				Index:    i,
+				Start:    span.Start,
+				End:      span.End,
+			})
+			texts = append(texts, span.Of(record.Contents))
+		}
+	}
+	if len(chunks) == 0 {
+		return nil
+	}
+
+	ep.logger.Debug("generating embeddings for chunks", "chunks", len(texts))
+	embeddings, err := ep.embedder.EmbedTexts(ctx, texts)
+	if err != nil {
+		ep.logger.Error("error generating chunk embeddings", "err", err)
+		return err
+	}
+
+	if len(embeddings) != len(chunks) {
+		return fmt.Errorf("chunk embedding result mismatch. expected %d, received %d", len(chunks), len(embeddings))
+	}
+
+	for i := range embeddings {
+		chunks[i].Vector = embeddings[i]
+	}
+
+	// Chunks are grouped by record, store each group
+	for start := 0; start < len(chunks); {
+		end := start
+		for end < len(chunks) && chunks[end].RecordId == chunks[start].RecordId {
+			end++
+		}
+		if err := ep.chatRepository.ReplaceChunks(ctx, chunks[start].RecordId, chunks[start:end]); err != nil {
+			return err
+		}
+		start = end
+	}
+
+	return nil
+}
+
 // checkpoint saves the processor's current state.
 func (ep *embeddingProcessor) checkpoint() error {
 	if ep.lastID == 0 {
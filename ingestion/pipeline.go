@@ -23,6 +23,7 @@ import (
 
 	"github.com/panjf2000/ants/v2"
 	"github.com/poiesic/memorit/ai"
+	"github.com/poiesic/memorit/chunk"
 	"github.com/poiesic/memorit/core"
 	"github.com/poiesic/memorit/storage"
 )
@@ -40,7 +41,8 @@ type Pipeline struct {
 	conceptPool          *ants.Pool
 	embeddingProc        processor
 	conceptProc          processor
-	contextTurns         int // Number of previous turns to include for concept extraction context
+	contextTurns         int           // Number of previous turns to include for concept extraction context
+	chunking             *chunk.Config // Chunking settings for long messages (nil = disabled)
 	logger               *slog.Logger
 }
 
@@ -93,6 +95,22 @@ func WithLogger(logger *slog.Logger) Option {
 	}
 }
 
+// WithChunking enables multi-vector embeddings for long messages.
+// Messages longer than a single chunk are split according to cfg, and each
+// chunk is embedded and stored alongside the record's whole-message vector.
+// Passing nil disables chunking. Default is disabled.
+func WithChunking(cfg *chunk.Config) Option {
+	return func(p *Pipeline) error {
+		if cfg != nil {
+			if err := cfg.Validate(); err != nil {
+				return err
+			}
+		}
+		p.chunking = cfg
+		return nil
+	}
+}
+
 // NewPipeline creates a new ingestion pipeline.
 // On startup, it loads checkpoints and synchronously processes any pending records
 // before returning. This ensures the pipeline is in a consistent state.
@@ -156,7 +174,7 @@ func NewPipeline(
 	}
 
 	// Create processors after options are applied (so they get final config)
-	embeddingProc, err := newEmbeddingProcessor(chatRepository, checkpointRepository, provider.Embedder(), p.logger)
+	embeddingProc, err := newEmbeddingProcessor(chatRepository, checkpointRepository, provider.Embedder(), p.chunking, p.logger)
 	if err != nil {
 		p.Release()
 		return nil, err
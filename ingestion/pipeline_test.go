@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/poiesic/memorit/ai"
+	"github.com/poiesic/memorit/chunk"
 	"github.com/poiesic/memorit/core"
 	"github.com/poiesic/memorit/storage"
 	"github.com/poiesic/memorit/storage/badger"
@@ -434,7 +435,7 @@ func TestEmbeddingProcessor_Process(t *testing.T) {
 		embeddings: [][]float32{{0.1, 0.2, 0.3}, {0.4, 0.5, 0.6}},
 	}
 
-	ep, err := newEmbeddingProcessor(chatRepo, checkpointRepo, embedder, nil)
+	ep, err := newEmbeddingProcessor(chatRepo, checkpointRepo, embedder, nil, nil)
 	require.NoError(t, err)
 
 	// Add records
@@ -461,6 +462,52 @@ func TestEmbeddingProcessor_Process(t *testing.T) {
 	assert.Equal(t, []float32{0.4, 0.5, 0.6}, processed[1].Vector)
 }
 
+func TestEmbeddingProcessor_Process_Chunking(t *testing.T) {
+	chatRepo, _, checkpointRepo, cleanup := setupTestRepositories(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	chunking := &chunk.Config{Mode: chunk.ModeTokens, Size: 2, Overlap: 0}
+	ep, err := newEmbeddingProcessor(chatRepo, checkpointRepo, &testEmbedder{}, chunking, nil)
+	require.NoError(t, err)
+
+	records := []*core.ChatRecord{
+		{Speaker: core.SpeakerTypeHuman, Contents: "one two three four five", Timestamp: time.Now().UTC()},
+		{Speaker: core.SpeakerTypeHuman, Contents: "short", Timestamp: time.Now().UTC()},
+	}
+	added, err := chatRepo.AddChatRecords(ctx, records...)
+	require.NoError(t, err)
+
+	err = ep.process(ctx, added[0].Id, added[1].Id)
+	require.NoError(t, err)
+
+	// Long record is split into chunks, each with its own vector
+	chunks, err := chatRepo.GetChunks(ctx, added[0].Id)
+	require.NoError(t, err)
+	require.Len(t, chunks, 3)
+	assert.Equal(t, "one two", chunks[0].Span().Of(added[0].Contents))
+	assert.Equal(t, "five", chunks[2].Span().Of(added[0].Contents))
+	for _, c := range chunks {
+		assert.NotEmpty(t, c.Vector)
+	}
+
+	// Short record fits in a single chunk and keeps only its record vector
+	chunks, err = chatRepo.GetChunks(ctx, added[1].Id)
+	require.NoError(t, err)
+	assert.Empty(t, chunks)
+
+	// Editing the long record down to a short one drops its chunks
+	edited := added[0]
+	edited.Contents = "one"
+	_, err = chatRepo.UpdateChatRecords(ctx, edited)
+	require.NoError(t, err)
+	err = ep.process(ctx, edited.Id)
+	require.NoError(t, err)
+	chunks, err = chatRepo.GetChunks(ctx, edited.Id)
+	require.NoError(t, err)
+	assert.Empty(t, chunks)
+}
+
 func TestEmbeddingProcessor_Process_EmbedderError(t *testing.T) {
 	chatRepo, _, checkpointRepo, cleanup := setupTestRepositories(t)
 	defer cleanup()
@@ -470,7 +517,7 @@ func TestEmbeddingProcessor_Process_EmbedderError(t *testing.T) {
 		shouldError: true,
 	}
 
-	ep, err := newEmbeddingProcessor(chatRepo, checkpointRepo, embedder, nil)
+	ep, err := newEmbeddingProcessor(chatRepo, checkpointRepo, embedder, nil, nil)
 	require.NoError(t, err)
 
 	// Add record
@@ -702,7 +749,7 @@ func TestEmbeddingProcessor_Checkpoint(t *testing.T) {
 	defer cleanup()
 
 	embedder := &testEmbedder{}
-	ep, err := newEmbeddingProcessor(chatRepo, checkpointRepo, embedder, nil)
+	ep, err := newEmbeddingProcessor(chatRepo, checkpointRepo, embedder, nil, nil)
 	require.NoError(t, err)
 
 	// Checkpoint should not error when no records processed
@@ -716,7 +763,7 @@ func TestEmbeddingProcessor_Checkpoint_SavesAfterProcessing(t *testing.T) {
 	ctx := context.Background()
 
 	embedder := &testEmbedder{}
-	ep, err := newEmbeddingProcessor(chatRepo, checkpointRepo, embedder, nil)
+	ep, err := newEmbeddingProcessor(chatRepo, checkpointRepo, embedder, nil, nil)
 	require.NoError(t, err)
 
 	// Add and process records
@@ -779,7 +826,7 @@ func TestEmbeddingProcessor_Checkpoint_SaveFailure(t *testing.T) {
 	failingRepo := &failingCheckpointRepository{saveError: saveErr}
 
 	embedder := &testEmbedder{}
-	ep, err := newEmbeddingProcessor(chatRepo, failingRepo, embedder, nil)
+	ep, err := newEmbeddingProcessor(chatRepo, failingRepo, embedder, nil, nil)
 	require.NoError(t, err)
 
 	// Add and process a record
@@ -0,0 +1,145 @@
+// Copyright 2025 Poiesic Systems
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+
+package memorit
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/poiesic/memorit/ai"
+	"github.com/poiesic/memorit/storage/badger"
+)
+
+// DatabaseOption configures a Database.
+type DatabaseOption func(*databaseOptions)
+
+type databaseOptions struct {
+	aiConfig    *ai.Config
+	provider    ai.AIProvider
+	embedder    ai.Embedder
+	extractor   ai.ConceptExtractor
+	logger      *slog.Logger
+	inMemory    bool
+	backendOpts []badger.BackendOption
+}
+
+// WithAIConfig sets the configuration used to create the AI provider.
+func WithAIConfig(cfg *ai.Config) DatabaseOption {
+	return func(o *databaseOptions) {
+		o.aiConfig = cfg
+	}
+}
+
+// WithAIProvider uses provider instead of creating an OpenAI-compatible
+// provider from the AI config. The database closes the provider on Close.
+func WithAIProvider(provider ai.AIProvider) DatabaseOption {
+	return func(o *databaseOptions) {
+		o.provider = provider
+	}
+}
+
+// WithEmbedder uses embedder for all embeddings, overriding the provider's embedder.
+// When both an embedder and a concept extractor are supplied no provider is created.
+func WithEmbedder(embedder ai.Embedder) DatabaseOption {
+	return func(o *databaseOptions) {
+		o.embedder = embedder
+	}
+}
+
+// WithConceptExtractor uses extractor for concept extraction, overriding the provider's extractor.
+// When both an embedder and a concept extractor are supplied no provider is created.
+func WithConceptExtractor(extractor ai.ConceptExtractor) DatabaseOption {
+	return func(o *databaseOptions) {
+		o.extractor = extractor
+	}
+}
+
+// WithLogger sets the logger used by the database and its storage backend.
+func WithLogger(logger *slog.Logger) DatabaseOption {
+	return func(o *databaseOptions) {
+		o.logger = logger
+	}
+}
+
+// WithInMemory keeps the database entirely in memory. The file path passed
+// to NewDatabase is ignored and nothing is persisted.
+func WithInMemory() DatabaseOption {
+	return func(o *databaseOptions) {
+		o.inMemory = true
+	}
+}
+
+// WithBlockCacheSize sets the size in bytes of the storage block cache.
+// Default is 512 MB. The block cache is required when compression is enabled.
+func WithBlockCacheSize(size int64) DatabaseOption {
+	return withBackendOption(badger.WithBlockCacheSize(size))
+}
+
+// WithIndexCacheSize sets the size in bytes of the storage index cache.
+// Zero, the default, keeps all indices in memory.
+func WithIndexCacheSize(size int64) DatabaseOption {
+	return withBackendOption(badger.WithIndexCacheSize(size))
+}
+
+// WithCompression sets the storage block compression. Default is no compression.
+func WithCompression(compression badger.Compression) DatabaseOption {
+	return withBackendOption(badger.WithCompression(compression))
+}
+
+// WithSyncWrites makes every write wait for the data to be synced to disk.
+func WithSyncWrites(sync bool) DatabaseOption {
+	return withBackendOption(badger.WithSyncWrites(sync))
+}
+
+// WithGCInterval sets how often value log garbage collection runs. Default is 5 minutes.
+func WithGCInterval(interval time.Duration) DatabaseOption {
+	return withBackendOption(badger.WithGCInterval(interval))
+}
+
+// WithGCDiscardRatio sets the fraction of a value log file that must be
+// discardable before garbage collection rewrites it. Default is 0.5.
+func WithGCDiscardRatio(ratio float64) DatabaseOption {
+	return withBackendOption(badger.WithGCDiscardRatio(ratio))
+}
+
+// WithValueLogFileSize sets the maximum size in bytes of a single value log file.
+func WithValueLogFileSize(size int64) DatabaseOption {
+	return withBackendOption(badger.WithValueLogFileSize(size))
+}
+
+// WithMemTableSize sets the size in bytes of each memtable.
+func WithMemTableSize(size int64) DatabaseOption {
+	return withBackendOption(badger.WithMemTableSize(size))
+}
+
+// WithVectorCache keeps all vectors in an in-memory matrix for fast exact search.
+// workers sets the number of scan goroutines; <= 0 uses GOMAXPROCS.
+func WithVectorCache(workers int) DatabaseOption {
+	return withBackendOption(badger.WithVectorCache(workers))
+}
+
+// WithQuantization enables quantized vector search with full-precision rescoring.
+// oversample <= 0 uses the default.
+func WithQuantization(mode badger.QuantizationMode, oversample int) DatabaseOption {
+	return withBackendOption(badger.WithQuantization(mode, oversample))
+}
+
+// withBackendOption forwards a storage backend option.
+func withBackendOption(opt badger.BackendOption) DatabaseOption {
+	return func(o *databaseOptions) {
+		o.backendOpts = append(o.backendOpts, opt)
+	}
+}
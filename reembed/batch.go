@@ -85,5 +85,62 @@ func (bp *BatchProcessor) Process(ctx context.Context, records []*core.ChatRecor
 		return fmt.Errorf("failed to update records: %w", err)
 	}
 
+	// Chunk vectors must come from the same model as the record vectors
+	if err := bp.processChunks(ctx, records); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// processChunks regenerates the chunk vectors of any records that have chunks.
+func (bp *BatchProcessor) processChunks(ctx context.Context, records []*core.ChatRecord) error {
+	var chunks []core.Chunk
+	var texts []string
+	for _, record := range records {
+		recordChunks, err := bp.repo.GetChunks(ctx, record.Id)
+		if err != nil {
+			return fmt.Errorf("failed to load chunks: %w", err)
+		}
+		for _, chunk := range recordChunks {
+			chunks = append(chunks, chunk)
+			texts = append(texts, chunk.Span().Of(record.Contents))
+		}
+	}
+	if len(chunks) == 0 {
+		return nil
+	}
+
+	var embeddings [][]float32
+	err := RetryWithBackoff(ctx, func() error {
+		var err error
+		embeddings, err = bp.embedder.EmbedTexts(ctx, texts)
+		return err
+	}, bp.maxRetries, bp.retryBaseDelay)
+
+	if err != nil {
+		return fmt.Errorf("failed to generate chunk embeddings after %d attempts: %w", bp.maxRetries, err)
+	}
+
+	if len(embeddings) != len(chunks) {
+		return fmt.Errorf("chunk embedding count mismatch: expected %d, got %d", len(chunks), len(embeddings))
+	}
+
+	for i := range chunks {
+		chunks[i].Vector = NormalizeVector(embeddings[i])
+	}
+
+	// Chunks are grouped by record, store each group
+	for start := 0; start < len(chunks); {
+		end := start
+		for end < len(chunks) && chunks[end].RecordId == chunks[start].RecordId {
+			end++
+		}
+		if err := bp.repo.ReplaceChunks(ctx, chunks[start].RecordId, chunks[start:end]); err != nil {
+			return fmt.Errorf("failed to update chunks: %w", err)
+		}
+		start = end
+	}
+
 	return nil
 }
@@ -72,6 +72,52 @@ func TestBatchProcessor_Process(t *testing.T) {
 	}
 }
 
+func TestBatchProcessor_Process_Chunks(t *testing.T) {
+	repo, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	added, err := repo.AddChatRecords(ctx, &core.ChatRecord{
+		Speaker:   core.SpeakerTypeHuman,
+		Contents:  "first half second half",
+		Timestamp: time.Now(),
+	})
+	require.NoError(t, err)
+
+	err = repo.ReplaceChunks(ctx, added[0].Id, []core.Chunk{
+		{Index: 0, Start: 0, End: 10, Vector: []float32{0, 0, 1}},
+		{Index: 1, Start: 11, End: 22, Vector: []float32{0, 0, 1}},
+	})
+	require.NoError(t, err)
+
+	var embedded []string
+	embedder := &mockEmbedder{
+		embedTextsFunc: func(ctx context.Context, texts []string) ([][]float32, error) {
+			embedded = append(embedded, texts...)
+			result := make([][]float32, len(texts))
+			for i := range texts {
+				result[i] = []float32{1.0, 2.0, 2.0}
+			}
+			return result, nil
+		},
+	}
+	processor := NewBatchProcessor(repo, embedder, 3, 10*time.Millisecond)
+
+	err = processor.Process(ctx, added)
+	require.NoError(t, err)
+
+	// Record text and both chunk texts were embedded
+	assert.Equal(t, []string{"first half second half", "first half", "second half"}, embedded)
+
+	chunks, err := repo.GetChunks(ctx, added[0].Id)
+	require.NoError(t, err)
+	require.Len(t, chunks, 2)
+	for _, c := range chunks {
+		assert.InDelta(t, 1.0/3.0, c.Vector[0], 0.001, "chunk vector should be re-embedded and normalized")
+	}
+}
+
 func TestBatchProcessor_EmptyBatch(t *testing.T) {
 	repo, cleanup := setupTestDB(t)
 	defer cleanup()
@@ -0,0 +1,278 @@
+// Copyright 2025 Poiesic Systems
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+
+package search
+
+import (
+	"container/list"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/poiesic/memorit/core"
+	"github.com/poiesic/memorit/storage"
+)
+
+// lru is a fixed-size least-recently-used cache. It is safe for concurrent use.
+type lru[K comparable, V any] struct {
+	mu    sync.Mutex
+	size  int
+	order *list.List // Most recently used at the front
+	items map[K]*list.Element
+}
+
+type lruEntry[K comparable, V any] struct {
+	key   K
+	value V
+}
+
+func newLRU[K comparable, V any](size int) *lru[K, V] {
+	return &lru[K, V]{size: size, order: list.New(), items: make(map[K]*list.Element, size)}
+}
+
+// get returns the value for key and marks it most recently used.
+func (c *lru[K, V]) get(key K) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	element, ok := c.items[key]
+	if !ok {
+		var zero V
+		return zero, false
+	}
+	c.order.MoveToFront(element)
+	return element.Value.(*lruEntry[K, V]).value, true
+}
+
+// put stores value under key, evicting the least recently used entry if full.
+func (c *lru[K, V]) put(key K, value V) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if element, ok := c.items[key]; ok {
+		element.Value.(*lruEntry[K, V]).value = value
+		c.order.MoveToFront(element)
+		return
+	}
+	if c.order.Len() >= c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*lruEntry[K, V]).key)
+	}
+	c.items[key] = c.order.PushFront(&lruEntry[K, V]{key: key, value: value})
+}
+
+// remove deletes key from the cache.
+func (c *lru[K, V]) remove(key K) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if element, ok := c.items[key]; ok {
+		c.order.Remove(element)
+		delete(c.items, key)
+	}
+}
+
+// len returns the number of cached entries.
+func (c *lru[K, V]) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// cachedResponse is a search response and the repository version it was computed at.
+type cachedResponse struct {
+	response *SearchResponse
+	version  uint64
+	stored   time.Time
+}
+
+// resultCache caches whole search responses until the repositories change
+// or the entries expire.
+type resultCache struct {
+	entries  *lru[string, cachedResponse]
+	ttl      time.Duration
+	trackers []storage.ChangeTracker
+	now      func() time.Time
+}
+
+// version returns the combined version of the tracked repositories. It
+// increases whenever any of them is written.
+func (c *resultCache) version() uint64 {
+	var version uint64
+	for _, tracker := range c.trackers {
+		version += tracker.Version()
+	}
+	return version
+}
+
+// get returns a copy of the cached response for key if the repositories are
+// unchanged and the entry has not expired.
+func (c *resultCache) get(key string) (*SearchResponse, bool) {
+	entry, ok := c.entries.get(key)
+	if !ok {
+		return nil, false
+	}
+	if entry.version != c.version() || (c.ttl > 0 && c.now().Sub(entry.stored) >= c.ttl) {
+		c.entries.remove(key)
+		return nil, false
+	}
+	return copyResponse(entry.response), true
+}
+
+// put caches a copy of response as computed at version.
+func (c *resultCache) put(key string, version uint64, response *SearchResponse) {
+	c.entries.put(key, cachedResponse{response: copyResponse(response), version: version, stored: c.now()})
+}
+
+// resultKey identifies the results of a request by its query text, the time
+// expressions resolved from it and the fields of resolved, the request after
+// resolution, so relative times like "yesterday" key the ranges they resolved
+// to. The monitor and any shared analysis do not affect the results.
+func resultKey(query string, expressions []TimeExpression, resolved *SearchRequest) string {
+	var b strings.Builder
+	writeString := func(s string) {
+		b.WriteString(strconv.Quote(s))
+		b.WriteByte(',')
+	}
+	writeInt := func(i int64) {
+		b.WriteString(strconv.FormatInt(i, 10))
+		b.WriteByte(',')
+	}
+	writeTime := func(t time.Time) {
+		if t.IsZero() {
+			b.WriteString("-,")
+			return
+		}
+		writeInt(t.UnixNano())
+	}
+	writeFloat := func(f float32) {
+		b.WriteString(strconv.FormatFloat(float64(f), 'g', -1, 32))
+		b.WriteByte(',')
+	}
+	section := func(name string) {
+		b.WriteString(name)
+		b.WriteByte(':')
+	}
+
+	writeString(query)
+	section("expressions")
+	for _, expression := range expressions {
+		writeString(expression.Text)
+		writeTime(expression.Start)
+		writeTime(expression.End)
+	}
+	section("request")
+	writeString(resolved.Query)
+	writeInt(int64(resolved.MaxHits))
+
+	filter := &resolved.Filter
+	section("filter")
+	writeTime(filter.Start)
+	writeTime(filter.End)
+	for _, speaker := range filter.Speakers {
+		writeInt(int64(speaker))
+	}
+	section("meta")
+	for _, predicate := range filter.Metadata {
+		writeString(predicate.Key)
+		writeInt(int64(predicate.Op))
+		writeString(predicate.Value)
+	}
+	section("conversations")
+	for _, conversation := range filter.Conversations {
+		writeString(conversation)
+	}
+	section("exclude-ids")
+	for _, id := range filter.ExcludeIDs {
+		writeInt(int64(id))
+	}
+
+	section("phrases")
+	for _, phrase := range resolved.Phrases {
+		writeString(phrase)
+	}
+	section("exclude")
+	for _, excluded := range resolved.Exclude {
+		writeString(excluded)
+	}
+	section("concepts")
+	for _, concept := range resolved.Concepts {
+		writeString(concept.Type)
+		writeString(concept.Name)
+	}
+	section("boosts")
+	for _, boost := range resolved.Boosts {
+		writeString(boost.Term)
+		writeFloat(boost.Factor)
+	}
+	section("time-boosts")
+	for _, boost := range resolved.TimeBoosts {
+		writeTime(boost.Start)
+		writeTime(boost.End)
+		writeFloat(boost.Factor)
+	}
+
+	section("options")
+	writeTime(resolved.ReferenceTime)
+	writeInt(int64(resolved.Fusion))
+	b.WriteString(strconv.FormatBool(resolved.Explain))
+	return b.String()
+}
+
+// copyResponse copies a response, its results and passages, including their
+// spans, breakdowns and snippets, so that callers modifying them do not change
+// the cache. Records are shared and must not be modified.
+func copyResponse(response *SearchResponse) *SearchResponse {
+	copied := &SearchResponse{
+		Results:         make([]*core.SearchResult, len(response.Results)),
+		Skipped:         slices.Clone(response.Skipped),
+		TimeExpressions: slices.Clone(response.TimeExpressions),
+	}
+	for i, result := range response.Results {
+		copied.Results[i] = copyResult(result)
+	}
+	if response.Passages != nil {
+		copied.Passages = make([]*Passage, len(response.Passages))
+	}
+	for i, passage := range response.Passages {
+		p := *passage
+		p.Records = slices.Clone(passage.Records)
+		p.Matches = slices.Clone(passage.Matches)
+		copied.Passages[i] = &p
+	}
+	return copied
+}
+
+// copyResult copies a result and the values it points to, except its record.
+func copyResult(result *core.SearchResult) *core.SearchResult {
+	r := *result
+	if result.Span != nil {
+		span := *result.Span
+		r.Span = &span
+	}
+	if result.Breakdown != nil {
+		breakdown := *result.Breakdown
+		breakdown.Concepts = slices.Clone(result.Breakdown.Concepts)
+		breakdown.KeywordMatches = slices.Clone(result.Breakdown.KeywordMatches)
+		r.Breakdown = &breakdown
+	}
+	if result.Snippet != nil {
+		snippet := *result.Snippet
+		snippet.Highlights = slices.Clone(result.Snippet.Highlights)
+		snippet.Concepts = slices.Clone(result.Snippet.Concepts)
+		r.Snippet = &snippet
+	}
+	return &r
+}
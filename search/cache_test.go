@@ -0,0 +1,301 @@
+package search
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/poiesic/memorit/ai"
+	"github.com/poiesic/memorit/ai/mock"
+	"github.com/poiesic/memorit/core"
+	"github.com/poiesic/memorit/storage"
+	"github.com/poiesic/memorit/storage/badger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// untrackedChatRepository hides the change tracking of a chat repository.
+type untrackedChatRepository struct {
+	storage.ChatRepository
+}
+
+func TestLRU(t *testing.T) {
+	cache := newLRU[string, int](2)
+	cache.put("a", 1)
+	cache.put("b", 2)
+	_, ok := cache.get("a")
+	require.True(t, ok)
+
+	// b is least recently used, so it is evicted
+	cache.put("c", 3)
+	_, ok = cache.get("b")
+	assert.False(t, ok)
+	value, ok := cache.get("a")
+	assert.True(t, ok)
+	assert.Equal(t, 1, value)
+
+	cache.put("a", 10)
+	value, _ = cache.get("a")
+	assert.Equal(t, 10, value)
+	assert.Equal(t, 2, cache.len())
+
+	cache.remove("a")
+	_, ok = cache.get("a")
+	assert.False(t, ok)
+	assert.Equal(t, 1, cache.len())
+}
+
+func TestResultKey(t *testing.T) {
+	reference := time.Now()
+	req := &SearchRequest{
+		Query:         "machine learning",
+		MaxHits:       10,
+		ReferenceTime: reference,
+		Filter: storage.RecordFilter{
+			Start:    reference.Add(-time.Hour),
+			Speakers: []core.SpeakerType{core.SpeakerTypeHuman},
+		},
+	}
+	key := resultKey(req.Query, nil, req)
+
+	// The same instants in another location or without a monotonic reading
+	// are the same request
+	same := *req
+	same.ReferenceTime = reference.Round(0).In(time.FixedZone("east", 3600))
+	same.Filter.Start = req.Filter.Start.Round(0).UTC()
+	same.Monitor = &testMonitor{}
+	assert.Equal(t, key, resultKey(same.Query, nil, &same))
+
+	different := *req
+	different.Filter.Speakers = []core.SpeakerType{core.SpeakerTypeAI}
+	assert.NotEqual(t, key, resultKey(different.Query, nil, &different))
+	different = *req
+	different.Phrases = []string{"machine learning"}
+	assert.NotEqual(t, key, resultKey(different.Query, nil, &different))
+
+	// A relative expression resolved on different days is a different request,
+	// even when both were resolved against the current time
+	searcher := &Searcher{timeMode: TimeModeFilter}
+	day := time.Date(2026, time.March, 10, 12, 0, 0, 0, time.UTC)
+	keyOn := func(now time.Time) string {
+		original := &SearchRequest{Query: "machine learning yesterday", MaxHits: 10, ReferenceTime: now}
+		resolved, expressions := searcher.resolveTimeExpressions(original)
+		require.Len(t, expressions, 1)
+		resolved.ReferenceTime = time.Time{}
+		return resultKey("machine learning yesterday", expressions, resolved)
+	}
+	assert.Equal(t, keyOn(day), keyOn(day.Add(time.Hour)))
+	assert.NotEqual(t, keyOn(day), keyOn(day.AddDate(0, 0, 1)))
+}
+
+func TestCopyResponse(t *testing.T) {
+	record := &core.ChatRecord{Id: 1, Contents: "machine learning"}
+	response := &SearchResponse{
+		Results: []*core.SearchResult{{
+			Record: record,
+			Score:  0.9,
+			Span:   &core.Span{Start: 0, End: 7},
+			Breakdown: &core.ScoreBreakdown{
+				Concepts:       []core.ConceptContribution{{Name: "machine", Type: "thing"}},
+				KeywordMatches: []string{"machine"},
+			},
+			Snippet: &core.Snippet{
+				Span:       core.Span{Start: 0, End: 16},
+				Highlights: []core.Span{{Start: 0, End: 7}},
+				Concepts:   []string{"machine"},
+			},
+		}},
+		Passages: []*Passage{{Records: []*core.ChatRecord{record}, Matches: []int{0}, Score: 0.9}},
+	}
+
+	copied := copyResponse(response)
+	result := copied.Results[0]
+	result.Span.End = 1
+	result.Breakdown.Concepts[0].Name = "changed"
+	result.Breakdown.KeywordMatches[0] = "changed"
+	result.Snippet.Span.End = 1
+	result.Snippet.Highlights[0].End = 1
+	result.Snippet.Concepts[0] = "changed"
+	copied.Passages[0].Matches[0] = 5
+	copied.Passages[0].Records[0] = nil
+	copied.Passages[0].Score = 0
+
+	original := response.Results[0]
+	assert.Equal(t, 7, original.Span.End)
+	assert.Equal(t, "machine", original.Breakdown.Concepts[0].Name)
+	assert.Equal(t, []string{"machine"}, original.Breakdown.KeywordMatches)
+	assert.Equal(t, 16, original.Snippet.Span.End)
+	assert.Equal(t, 7, original.Snippet.Highlights[0].End)
+	assert.Equal(t, []string{"machine"}, original.Snippet.Concepts)
+	assert.Equal(t, []int{0}, response.Passages[0].Matches)
+	assert.Same(t, record, response.Passages[0].Records[0])
+	assert.Equal(t, float32(0.9), response.Passages[0].Score)
+
+	// Records are shared
+	assert.Same(t, record, result.Record)
+	assert.Nil(t, copyResponse(&SearchResponse{}).Passages)
+}
+
+func TestSearch_Caching(t *testing.T) {
+	chatRepo, conceptRepo, backend, err := badger.NewMemoryRepositories()
+	require.NoError(t, err)
+	defer func() {
+		conceptRepo.Close()
+		chatRepo.Close()
+		backend.Close()
+	}()
+
+	ctx := context.Background()
+	now := time.Now().UTC()
+	_, err = chatRepo.AddChatRecords(ctx, &core.ChatRecord{
+		Speaker:   core.SpeakerTypeHuman,
+		Contents:  "Machine learning is fascinating",
+		Timestamp: now,
+		Vector:    []float32{1.0, 0.0, 0.0},
+	})
+	require.NoError(t, err)
+
+	mockEmbedder := mock.NewMockEmbedder()
+	mockEmbedder.EmbedTextFunc = func(ctx context.Context, text string) ([]float32, error) {
+		return []float32{1.0, 0.0, 0.0}, nil
+	}
+	mockExtractor := mock.NewMockConceptExtractor()
+	mockExtractor.ExtractConceptsFunc = func(ctx context.Context, text string) ([]ai.ExtractedConcept, error) {
+		return []ai.ExtractedConcept{{Name: "machine", Type: "thing", Importance: 8}}, nil
+	}
+	provider := mock.NewMockProviderWithServices(mockEmbedder, mockExtractor)
+	reset := func() {
+		mockEmbedder.Reset()
+		mockEmbedder.EmbedTextFunc = func(ctx context.Context, text string) ([]float32, error) {
+			return []float32{1.0, 0.0, 0.0}, nil
+		}
+		mockExtractor.Reset()
+		mockExtractor.ExtractConceptsFunc = func(ctx context.Context, text string) ([]ai.ExtractedConcept, error) {
+			return []ai.ExtractedConcept{{Name: "machine", Type: "thing", Importance: 8}}, nil
+		}
+	}
+
+	t.Run("options", func(t *testing.T) {
+		_, err := NewSearcher(chatRepo, conceptRepo, provider, WithQueryCache(0))
+		assert.ErrorIs(t, err, ErrInvalidCacheSize)
+		_, err = NewSearcher(chatRepo, conceptRepo, provider, WithResultCache(0, 0))
+		assert.ErrorIs(t, err, ErrInvalidCacheSize)
+		_, err = NewSearcher(chatRepo, conceptRepo, provider, WithResultCache(10, -time.Second))
+		assert.ErrorIs(t, err, ErrInvalidTimeout)
+		_, err = NewSearcher(&untrackedChatRepository{chatRepo}, conceptRepo, provider, WithResultCache(10, 0))
+		assert.ErrorIs(t, err, ErrChangeTrackingRequired)
+		_, err = NewSearcher(&untrackedChatRepository{chatRepo}, conceptRepo, provider, WithQueryCache(10))
+		assert.NoError(t, err, "the query cache needs no change tracking")
+	})
+
+	t.Run("query cache skips repeated AI calls", func(t *testing.T) {
+		reset()
+		searcher, err := NewSearcher(chatRepo, conceptRepo, provider, WithQueryCache(10))
+		require.NoError(t, err)
+
+		for range 3 {
+			response, err := searcher.Search(ctx, &SearchRequest{Query: "machine learning", MaxHits: 10})
+			require.NoError(t, err)
+			require.Len(t, response.Results, 1)
+		}
+		assert.Equal(t, 1, mockEmbedder.CallCount())
+		assert.Equal(t, 1, mockExtractor.CallCount())
+
+		_, err = searcher.Search(ctx, &SearchRequest{Query: "something else", MaxHits: 10})
+		require.NoError(t, err)
+		assert.Equal(t, 2, mockEmbedder.CallCount())
+
+		// Failures are not cached
+		mockExtractor.ExtractConceptsFunc = func(ctx context.Context, text string) ([]ai.ExtractedConcept, error) {
+			return nil, assert.AnError
+		}
+		_, err = searcher.Search(ctx, &SearchRequest{Query: "a third query", MaxHits: 10})
+		require.NoError(t, err)
+		_, err = searcher.Search(ctx, &SearchRequest{Query: "a third query", MaxHits: 10})
+		require.NoError(t, err)
+		assert.Equal(t, 4, mockExtractor.CallCount())
+	})
+
+	t.Run("result cache is invalidated by writes", func(t *testing.T) {
+		reset()
+		searcher, err := NewSearcher(chatRepo, conceptRepo, provider, WithResultCache(10, 0))
+		require.NoError(t, err)
+		req := &SearchRequest{Query: "machine learning", MaxHits: 10}
+
+		first, err := searcher.Search(ctx, req)
+		require.NoError(t, err)
+		second, err := searcher.Search(ctx, req)
+		require.NoError(t, err)
+		assert.Equal(t, 1, mockEmbedder.CallCount())
+		assert.Equal(t, first.Results[0].Record.Id, second.Results[0].Record.Id)
+
+		// Cached results are copies
+		second.Results[0].Score = -1
+		third, err := searcher.Search(ctx, req)
+		require.NoError(t, err)
+		assert.Equal(t, first.Results[0].Score, third.Results[0].Score)
+
+		// A different request is a different entry
+		_, err = searcher.Search(ctx, &SearchRequest{Query: "machine learning", MaxHits: 5})
+		require.NoError(t, err)
+		assert.Equal(t, 2, mockEmbedder.CallCount())
+
+		_, err = chatRepo.AddChatRecords(ctx, &core.ChatRecord{
+			Speaker:   core.SpeakerTypeAI,
+			Contents:  "Machine learning needs data",
+			Timestamp: now,
+			Vector:    []float32{0.9, 0.1, 0.0},
+		})
+		require.NoError(t, err)
+
+		response, err := searcher.Search(ctx, req)
+		require.NoError(t, err)
+		assert.Equal(t, 3, mockEmbedder.CallCount())
+		assert.Len(t, response.Results, 2)
+	})
+
+	t.Run("result cache entries expire", func(t *testing.T) {
+		reset()
+		searcher, err := NewSearcher(chatRepo, conceptRepo, provider, WithResultCache(10, time.Minute))
+		require.NoError(t, err)
+		clock := now
+		searcher.results.now = func() time.Time { return clock }
+		req := &SearchRequest{Query: "machine learning", MaxHits: 10}
+
+		_, err = searcher.Search(ctx, req)
+		require.NoError(t, err)
+		clock = clock.Add(30 * time.Second)
+		_, err = searcher.Search(ctx, req)
+		require.NoError(t, err)
+		assert.Equal(t, 1, mockEmbedder.CallCount())
+
+		clock = clock.Add(time.Minute)
+		_, err = searcher.Search(ctx, req)
+		require.NoError(t, err)
+		assert.Equal(t, 2, mockEmbedder.CallCount())
+	})
+
+	t.Run("monitored and degraded searches are not cached", func(t *testing.T) {
+		reset()
+		searcher, err := NewSearcher(chatRepo, conceptRepo, provider, WithResultCache(10, 0))
+		require.NoError(t, err)
+
+		for range 2 {
+			monitor := &testMonitor{}
+			_, err := searcher.Search(ctx, &SearchRequest{Query: "machine learning", MaxHits: 10, Monitor: monitor})
+			require.NoError(t, err)
+			assert.True(t, monitor.finishCalled)
+		}
+		assert.Equal(t, 2, mockEmbedder.CallCount())
+
+		mockExtractor.ExtractConceptsFunc = func(ctx context.Context, text string) ([]ai.ExtractedConcept, error) {
+			return nil, assert.AnError
+		}
+		for range 2 {
+			response, err := searcher.Search(ctx, &SearchRequest{Query: "learning", MaxHits: 10})
+			require.NoError(t, err)
+			assert.Len(t, response.Skipped, 1)
+		}
+		assert.Equal(t, 4, mockEmbedder.CallCount())
+	})
+}
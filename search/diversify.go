@@ -0,0 +1,113 @@
+// Copyright 2025 Poiesic Systems
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+
+package search
+
+import (
+	"math"
+
+	"github.com/poiesic/memorit/core"
+)
+
+// diversityOversample is how many times maxHits semantic candidates are
+// retrieved when diversification is enabled, so that near-duplicates
+// dropped from the top can be replaced by distinct records.
+const diversityOversample = 3
+
+// collapseDuplicates drops every result whose vector has cosine similarity
+// of at least threshold with a higher-ranked result. Results must be sorted
+// by score descending; records without vectors are never collapsed.
+func collapseDuplicates(results []*core.SearchResult, threshold float32) []*core.SearchResult {
+	kept := make([]*core.SearchResult, 0, len(results))
+	for _, result := range results {
+		duplicate := false
+		for _, other := range kept {
+			if cosineSimilarity(result.Record.Vector, other.Record.Vector) >= threshold {
+				duplicate = true
+				break
+			}
+		}
+		if !duplicate {
+			kept = append(kept, result)
+		}
+	}
+	return kept
+}
+
+// maximalMarginalRelevance reorders results with MMR and returns up to limit
+// of them. Each step selects the result maximizing
+//
+//	lambda * relevance - (1 - lambda) * max similarity to already selected results
+//
+// where relevance is the score divided by the best score and similarity is the cosine
+// similarity of the record vectors. Lambda 1 keeps the score order; lower values
+// favor results unlike those already chosen.
+func maximalMarginalRelevance(results []*core.SearchResult, lambda float32, limit int) []*core.SearchResult {
+	if len(results) == 0 || limit <= 0 {
+		return results[:0]
+	}
+
+	var top float32
+	for _, result := range results {
+		top = max(top, result.Score)
+	}
+	relevance := func(score float32) float32 {
+		if top == 0 {
+			return 1
+		}
+		return score / top
+	}
+
+	remaining := append([]*core.SearchResult(nil), results...)
+	// redundancy[i] is the highest similarity of remaining[i] to any selected result
+	redundancy := make([]float32, len(remaining))
+	selected := make([]*core.SearchResult, 0, min(limit, len(results)))
+
+	for len(selected) < limit && len(remaining) > 0 {
+		best := 0
+		bestValue := float32(math.Inf(-1))
+		for i, result := range remaining {
+			value := lambda*relevance(result.Score) - (1-lambda)*redundancy[i]
+			if value > bestValue {
+				best, bestValue = i, value
+			}
+		}
+
+		chosen := remaining[best]
+		selected = append(selected, chosen)
+		remaining = append(remaining[:best], remaining[best+1:]...)
+		redundancy = append(redundancy[:best], redundancy[best+1:]...)
+		for i, result := range remaining {
+			redundancy[i] = max(redundancy[i], cosineSimilarity(result.Record.Vector, chosen.Record.Vector))
+		}
+	}
+	return selected
+}
+
+// cosineSimilarity returns the cosine similarity of two vectors,
+// or 0 if either is empty or zero. Extra dimensions are ignored.
+func cosineSimilarity(a, b []float32) float32 {
+	n := min(len(a), len(b))
+	var dot, normA, normB float64
+	for i := 0; i < n; i++ {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return float32(dot / math.Sqrt(normA*normB))
+}
@@ -0,0 +1,48 @@
+package search
+
+import (
+	"testing"
+
+	"github.com/poiesic/memorit/core"
+	"github.com/stretchr/testify/assert"
+)
+
+func diversityResults() []*core.SearchResult {
+	return []*core.SearchResult{
+		{Record: &core.ChatRecord{Id: 1, Vector: []float32{1.0, 0.0, 0.0}}, Score: 0.95},
+		{Record: &core.ChatRecord{Id: 2, Vector: []float32{0.99, 0.141, 0.0}}, Score: 0.94},
+		{Record: &core.ChatRecord{Id: 3, Vector: []float32{0.0, 1.0, 0.0}}, Score: 0.80},
+		{Record: &core.ChatRecord{Id: 4}, Score: 0.70},
+	}
+}
+
+func resultIDs(results []*core.SearchResult) []core.ID {
+	ids := make([]core.ID, len(results))
+	for i, result := range results {
+		ids[i] = result.Record.Id
+	}
+	return ids
+}
+
+func TestCollapseDuplicates(t *testing.T) {
+	assert.Equal(t, []core.ID{1, 3, 4}, resultIDs(collapseDuplicates(diversityResults(), 0.98)))
+	assert.Equal(t, []core.ID{1, 2, 3, 4}, resultIDs(collapseDuplicates(diversityResults(), 0.999)))
+}
+
+func TestMaximalMarginalRelevance(t *testing.T) {
+	// Lambda 1 keeps the score order
+	assert.Equal(t, []core.ID{1, 2, 3}, resultIDs(maximalMarginalRelevance(diversityResults(), 1, 3)))
+
+	// Balanced lambda prefers the distinct record over the near-duplicate
+	assert.Equal(t, []core.ID{1, 3, 4}, resultIDs(maximalMarginalRelevance(diversityResults(), 0.5, 3)))
+
+	assert.Empty(t, maximalMarginalRelevance(nil, 0.5, 3))
+	assert.Len(t, maximalMarginalRelevance(diversityResults(), 0.5, 10), 4)
+}
+
+func TestCosineSimilarity(t *testing.T) {
+	assert.InDelta(t, 1.0, cosineSimilarity([]float32{2, 0}, []float32{1, 0}), 0.0001)
+	assert.InDelta(t, 0.0, cosineSimilarity([]float32{1, 0}, []float32{0, 1}), 0.0001)
+	assert.Equal(t, float32(0), cosineSimilarity(nil, []float32{1, 0}))
+	assert.Equal(t, float32(0), cosineSimilarity([]float32{0, 0}, []float32{1, 0}))
+}
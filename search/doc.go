@@ -20,6 +20,79 @@
 //   - Conceptual search using extracted concepts
 //   - Verbatim keyword matching with stop-word filtering
 //
+// The semantic and conceptual stages run concurrently, since query concept
+// extraction is usually the slowest step and does not depend on the vector
+// search. WithSemanticTimeout and WithConceptTimeout bound each stage.
+//
+// A failed or timed-out stage degrades the search rather than failing it:
+// if concept extraction fails, results come from semantic and keyword
+// matching; if embedding fails, from conceptual and keyword matching. A
+// failed rerank keeps the retrieval order. SearchResponse.Skipped lists the
+// stages left out, and a monitor that implements StageSkipMonitor is
+// notified of each. WithStrictStages restores failing the search instead.
+//
 // Search results are scored and ranked based on multiple signals to provide
-// the most relevant results for a given query.
+// the most relevant results for a given query. The default weights can be
+// tuned with Options, or replaced entirely by a custom Ranker. Alternatively,
+// a SearchRequest can select reciprocal rank fusion or weighted-sum fusion,
+// which combine the per-stage rankings instead of adding fixed boosts.
+//
+// ParseQuery turns a query string such as
+//
+//	kubernetes speaker:human after:2025-03-01 -"helm chart" concept:software/terraform
+//
+// into a SearchRequest with free text, required phrases, exclusions, required
+// concepts, speaker/time/metadata filters and boosts.
+//
+// FindRelated is a "more like this" search: it finds records related to a
+// stored record using the record's own vector and concepts, without calling
+// the embedder or concept extractor.
+//
+// MultiSearcher federates a search across several Searchers, such as one per
+// database. It embeds the query and extracts its concepts once, searches the
+// sources concurrently, and merges the results by score, labeled with their
+// source. WithNormalization rescales each source's scores first when the
+// sources score differently. Sources that fail are reported alongside the
+// results.
+//
+// Query concepts are matched to stored concepts by exact (type,name) by
+// default. WithConceptExpansion also matches stored concepts with similar
+// embeddings, weighting each match by its similarity.
+//
+// WithContextWindow returns each hit with its neighboring messages, merged
+// into Passages that mark which messages matched.
+//
+// WithReranker adds an optional precision stage that rescores the top
+// candidates, for example with openai.Reranker. It can be given its own
+// timeout, and a failed or timed-out rerank keeps the retrieval ranking.
+//
+// WithSnippets adds a core.Snippet to each result showing why it matched:
+// the passage with the most query terms, highlighted, or for semantic hits
+// the sentence most similar to the query, plus the matched concept names.
+//
+// WithMMR and WithDuplicateCollapse diversify the results using the stored
+// record vectors, so repeated statements don't crowd out distinct memories.
+//
+// WithRecency adds optional time decay so that, among similarly relevant
+// records, recent ones rank first. Each result's ScoreBreakdown reports the
+// relevance score and the recency factor applied to it. Setting
+// SearchRequest.Explain also fills in the signals behind each score and a
+// formula showing how they were combined.
+//
+// A SearchMonitor set on a request observes each stage. LogMonitor logs the
+// stages with timings via slog, TraceMonitor collects a JSON-encodable
+// SearchTrace, TraceSpanMonitor records spans through a Tracer, and
+// MultiMonitor fans callbacks out to several monitors.
+//
+// WithQueryCache keeps the embeddings and extracted concepts of recent queries
+// so repeated queries skip the AI calls. WithResultCache keeps whole responses,
+// keyed by the request, and drops them whenever the repositories report a
+// write through storage.ChangeTracker. Requests with a monitor and responses
+// with skipped stages are never cached.
+//
+// WithTimeExpressions recognizes dates and relative times in queries, such as
+// "what did we decide about pricing last Tuesday", with ParseTimeExpressions.
+// They are removed from the text that is embedded and turned into a
+// timestamp filter, which storage reads through its date index, or into
+// TimeBoosts that favor results from that time.
 package search
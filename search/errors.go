@@ -26,4 +26,71 @@ var (
 
 	// ErrAIProviderRequired is returned when an AI provider is not provided.
 	ErrAIProviderRequired = errors.New("AI provider required")
+
+	// ErrInvalidSimilarity is returned when a similarity threshold is outside [-1, 1].
+	ErrInvalidSimilarity = errors.New("similarity threshold must be between -1 and 1")
+
+	// ErrInvalidWeight is returned when a ranking weight is negative.
+	ErrInvalidWeight = errors.New("ranking weight must not be negative")
+
+	// ErrInvalidRRFConstant is returned when the reciprocal rank fusion constant is not positive.
+	ErrInvalidRRFConstant = errors.New("RRF constant must be positive")
+
+	// ErrInvalidRecency is returned when a recency configuration has an unknown
+	// decay mode, a non-positive half-life or window, or a weight outside [0, 1].
+	ErrInvalidRecency = errors.New("invalid recency configuration")
+
+	// ErrInvalidConceptLimit is returned when concept expansion is enabled with a non-positive limit.
+	ErrInvalidConceptLimit = errors.New("concept expansion limit must be positive")
+
+	// ErrInvalidContextWindow is returned when a context window size is negative.
+	ErrInvalidContextWindow = errors.New("context window size must not be negative")
+
+	// ErrInvalidLambda is returned when the MMR lambda is outside [0, 1].
+	ErrInvalidLambda = errors.New("MMR lambda must be between 0 and 1")
+
+	// ErrInvalidRerankPoolSize is returned when the rerank pool size is not positive.
+	ErrInvalidRerankPoolSize = errors.New("rerank pool size must be positive")
+
+	// ErrInvalidTimeout is returned when a stage timeout is negative.
+	ErrInvalidTimeout = errors.New("timeout must not be negative")
+
+	// ErrInvalidQuery is returned when a query string cannot be parsed.
+	ErrInvalidQuery = errors.New("invalid query")
+
+	// ErrRecordNotEmbedded is reported when FindRelated skips the semantic
+	// stage because the source record has no vector yet.
+	ErrRecordNotEmbedded = errors.New("record has no embedding")
+
+	// ErrInvalidFusion is returned when a search request names an unknown fusion mode.
+	ErrInvalidFusion = errors.New("unknown fusion mode")
+
+	// ErrInvalidSnippetLength is returned when the snippet length is not positive.
+	ErrInvalidSnippetLength = errors.New("snippet length must be positive")
+
+	// ErrInvalidCacheSize is returned when a cache size is not positive.
+	ErrInvalidCacheSize = errors.New("cache size must be positive")
+
+	// ErrChangeTrackingRequired is returned when result caching is enabled but
+	// the repositories do not implement storage.ChangeTracker.
+	ErrChangeTrackingRequired = errors.New("result cache requires repositories that implement storage.ChangeTracker")
+
+	// ErrInvalidTimeMode is returned when an unknown time expression mode is selected.
+	ErrInvalidTimeMode = errors.New("unknown time mode")
+
+	// ErrInvalidTimeBoost is returned when the time boost factor is not positive.
+	ErrInvalidTimeBoost = errors.New("time boost factor must be positive")
+
+	// ErrNoSources is returned when a MultiSearcher is created without sources.
+	ErrNoSources = errors.New("at least one source required")
+
+	// ErrInvalidSource is returned when a source has no searcher, no name, or
+	// the same name as another source.
+	ErrInvalidSource = errors.New("source requires a searcher and a unique name")
+
+	// ErrInvalidNormalization is returned when an unknown score normalization is selected.
+	ErrInvalidNormalization = errors.New("unknown score normalization")
+
+	// ErrAllSourcesFailed is returned when every source of a MultiSearcher fails.
+	ErrAllSourcesFailed = errors.New("all sources failed")
 )
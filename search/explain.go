@@ -0,0 +1,137 @@
+// Copyright 2025 Poiesic Systems
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+
+package search
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/poiesic/memorit/core"
+)
+
+// explainSignals copies the signals of a record into its breakdown and
+// records how its relevance was computed.
+func (s *Searcher) explainSignals(breakdown *core.ScoreBreakdown, sig *Signals, mode FusionMode) {
+	breakdown.Semantic = sig.Semantic
+	breakdown.Similarity = sig.Similarity
+	breakdown.KeywordMatches = sig.KeywordMatches
+	breakdown.Verbatim = sig.Verbatim
+	for _, match := range sig.MatchedConcepts {
+		breakdown.Concepts = append(breakdown.Concepts, core.ConceptContribution{
+			Name:       match.Concept.Name,
+			Type:       match.Concept.Type,
+			Importance: match.Importance,
+			Similarity: match.Similarity,
+		})
+	}
+
+	switch mode {
+	case FusionRRF:
+		breakdown.Formula = s.explainRRF(sig)
+	case FusionWeightedSum:
+		breakdown.Formula = fmt.Sprintf("weighted sum of stage scores scaled by each stage's best "+
+			"(semantic %.4f × %.2f, concept %.4f × %.2f, keyword %.4f × %.2f)",
+			sig.Similarity, s.stageWeights.Semantic,
+			sig.ConceptScore, s.stageWeights.Conceptual,
+			sig.KeywordScore, s.stageWeights.Keyword)
+	default:
+		if ranker, ok := s.ranker.(weightedRanker); ok {
+			breakdown.Formula = ranker.explain(sig)
+		} else {
+			breakdown.Formula = fmt.Sprintf("custom ranker %.4f", breakdown.Relevance)
+		}
+	}
+}
+
+// explainRRF describes the reciprocal rank fusion terms of a record.
+func (s *Searcher) explainRRF(sig *Signals) string {
+	var terms []string
+	stages := []struct {
+		name   string
+		rank   int
+		weight float32
+	}{
+		{"semantic", sig.SemanticRank, s.stageWeights.Semantic},
+		{"concept", sig.ConceptRank, s.stageWeights.Conceptual},
+		{"keyword", sig.KeywordRank, s.stageWeights.Keyword},
+	}
+	for _, stage := range stages {
+		if stage.rank > 0 {
+			terms = append(terms, fmt.Sprintf("%.2f/(%g+%d) [%s]", stage.weight, s.rrfConstant, stage.rank, stage.name))
+		}
+	}
+	if len(terms) == 0 {
+		return "0"
+	}
+	return strings.Join(terms, " + ")
+}
+
+// explain describes the weighted ranker's computation for a record.
+func (r weightedRanker) explain(signals *Signals) string {
+	var formula string
+	switch {
+	case signals.Semantic && signals.Conceptual:
+		boost := 1 + (r.weights.HybridBoost-1)*conceptStrength(signals)
+		formula = fmt.Sprintf("%.4f × %.4f [hybrid]", boost, signals.Similarity)
+	case signals.Conceptual:
+		formula = fmt.Sprintf("%.4f × %.4f [concept only]", r.weights.ConceptOnlyScore, conceptStrength(signals))
+	default:
+		formula = fmt.Sprintf("%.4f [semantic]", signals.Similarity)
+	}
+	if signals.Verbatim {
+		formula += fmt.Sprintf(" + %.4f [verbatim]", r.weights.VerbatimBoost)
+	}
+	return formula
+}
+
+// explainBoost records a query boost applied to the relevance.
+func explainBoost(breakdown *core.ScoreBreakdown, factor float32) {
+	if breakdown.Formula == "" {
+		return
+	}
+	breakdown.Formula = fmt.Sprintf("(%s) × %.2f [boost]", breakdown.Formula, factor)
+}
+
+// explainRerank records that a reranker replaced the retrieval relevance.
+func explainRerank(breakdown *core.ScoreBreakdown, retrieval float32) {
+	if breakdown.Formula == "" {
+		return
+	}
+	breakdown.Formula = fmt.Sprintf("%.4f [reranked; retrieval %s = %.4f]", breakdown.Relevance, breakdown.Formula, retrieval)
+}
+
+// explainRerankTail records that a result outside the reranked pool was scaled
+// below the pool.
+func explainRerankTail(breakdown *core.ScoreBreakdown, factor float32) {
+	if breakdown.Formula == "" {
+		return
+	}
+	breakdown.Formula = fmt.Sprintf("(%s) × %.4f [below reranked]", breakdown.Formula, factor)
+}
+
+// explainScore completes the formula with time decay and the final score.
+func explainScore(result *core.SearchResult) {
+	breakdown := result.Breakdown
+	if breakdown == nil || breakdown.Formula == "" {
+		return
+	}
+	formula := breakdown.Formula
+	if breakdown.RecencyWeight > 0 {
+		formula = fmt.Sprintf("(%s) × (%.2f + %.2f × %.4f [recency])",
+			formula, 1-breakdown.RecencyWeight, breakdown.RecencyWeight, breakdown.Recency)
+	}
+	breakdown.Formula = fmt.Sprintf("%s = %.4f", formula, result.Score)
+}
@@ -0,0 +1,182 @@
+// Copyright 2025 Poiesic Systems
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+
+package search
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+)
+
+// FusionMode selects how the results of the retrieval stages are combined.
+type FusionMode int
+
+const (
+	// FusionRanker scores each record with the searcher's Ranker. This is the default.
+	FusionRanker FusionMode = iota
+	// FusionRRF ranks each stage separately and combines the ranks with
+	// weighted reciprocal rank fusion: sum of weight / (k + rank).
+	FusionRRF
+	// FusionWeightedSum divides each stage's scores by the stage's best score
+	// and combines them as a weighted sum, so every record a stage found
+	// contributes in proportion to how well it matched.
+	FusionWeightedSum
+)
+
+// DefaultRRFConstant is the default k in reciprocal rank fusion.
+const DefaultRRFConstant float32 = 60
+
+// StageWeights weight the contribution of each retrieval stage during rank fusion.
+type StageWeights struct {
+	Semantic   float32
+	Conceptual float32
+	Keyword    float32
+}
+
+// DefaultStageWeights weights every stage equally.
+func DefaultStageWeights() StageWeights {
+	return StageWeights{Semantic: 1, Conceptual: 1, Keyword: 1}
+}
+
+// String returns the name of the fusion mode.
+func (m FusionMode) String() string {
+	switch m {
+	case FusionRanker:
+		return "ranker"
+	case FusionRRF:
+		return "rrf"
+	case FusionWeightedSum:
+		return "weighted-sum"
+	default:
+		return fmt.Sprintf("FusionMode(%d)", m)
+	}
+}
+
+// ParseFusionMode returns the fusion mode with the given name.
+func ParseFusionMode(name string) (FusionMode, error) {
+	switch name {
+	case "ranker", "":
+		return FusionRanker, nil
+	case "rrf":
+		return FusionRRF, nil
+	case "weighted-sum":
+		return FusionWeightedSum, nil
+	default:
+		return FusionRanker, fmt.Errorf("%w %q", ErrInvalidFusion, name)
+	}
+}
+
+// stage extracts one retrieval stage's score from a record's signals.
+// ok is false if the stage did not return the record.
+type stage func(signals *Signals) (score float32, ok bool)
+
+func semanticStage(signals *Signals) (float32, bool) {
+	return signals.Similarity, signals.Semantic
+}
+
+func conceptStage(signals *Signals) (float32, bool) {
+	return signals.ConceptScore, signals.Conceptual
+}
+
+func keywordStage(signals *Signals) (float32, bool) {
+	return signals.KeywordScore, len(signals.KeywordMatches) > 0
+}
+
+// assignStageRanks fills in the per-stage scores and 1-based ranks of every record.
+func assignStageRanks(all []*Signals) {
+	for _, signals := range all {
+		var conceptScore float32
+		for _, match := range signals.MatchedConcepts {
+			conceptScore += float32(match.Importance) * match.Similarity
+		}
+		signals.ConceptScore = conceptScore
+		if signals.QueryWords > 0 {
+			signals.KeywordScore = float32(len(signals.KeywordMatches)) / float32(signals.QueryWords)
+		}
+	}
+
+	rankStage(all, semanticStage, func(s *Signals, rank int) { s.SemanticRank = rank })
+	rankStage(all, conceptStage, func(s *Signals, rank int) { s.ConceptRank = rank })
+	rankStage(all, keywordStage, func(s *Signals, rank int) { s.KeywordRank = rank })
+}
+
+// rankStage orders the records returned by a stage by score and assigns their ranks.
+// Ties are broken by record ID so ranks are deterministic.
+func rankStage(all []*Signals, score stage, assign func(*Signals, int)) {
+	ranked := make([]*Signals, 0, len(all))
+	for _, signals := range all {
+		if _, ok := score(signals); ok {
+			ranked = append(ranked, signals)
+		}
+	}
+	slices.SortFunc(ranked, func(a, b *Signals) int {
+		sa, _ := score(a)
+		sb, _ := score(b)
+		if sa > sb {
+			return -1
+		}
+		if sa < sb {
+			return 1
+		}
+		return cmp.Compare(a.Record.Id, b.Record.Id)
+	})
+	for i, signals := range ranked {
+		assign(signals, i+1)
+	}
+}
+
+// fuseRRF scores records by weighted reciprocal rank fusion.
+func fuseRRF(all []*Signals, weights StageWeights, k float32) []float32 {
+	scores := make([]float32, len(all))
+	for i, signals := range all {
+		if signals.SemanticRank > 0 {
+			scores[i] += weights.Semantic / (k + float32(signals.SemanticRank))
+		}
+		if signals.ConceptRank > 0 {
+			scores[i] += weights.Conceptual / (k + float32(signals.ConceptRank))
+		}
+		if signals.KeywordRank > 0 {
+			scores[i] += weights.Keyword / (k + float32(signals.KeywordRank))
+		}
+	}
+	return scores
+}
+
+// fuseWeightedSum scores records by the weighted sum of their stage scores,
+// each divided by the best score of its stage.
+func fuseWeightedSum(all []*Signals, weights StageWeights) []float32 {
+	scores := make([]float32, len(all))
+	addStage := func(score stage, weight float32) {
+		var hi float32
+		for _, signals := range all {
+			if v, ok := score(signals); ok {
+				hi = max(hi, v)
+			}
+		}
+		if hi <= 0 {
+			return
+		}
+		for i, signals := range all {
+			if v, ok := score(signals); ok {
+				scores[i] += weight * v / hi
+			}
+		}
+	}
+	addStage(semanticStage, weights.Semantic)
+	addStage(conceptStage, weights.Conceptual)
+	addStage(keywordStage, weights.Keyword)
+	return scores
+}
@@ -0,0 +1,114 @@
+package search
+
+import (
+	"testing"
+
+	"github.com/poiesic/memorit/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func fusionSignals() []*Signals {
+	return []*Signals{
+		{
+			Record:          &core.ChatRecord{Id: 1},
+			Semantic:        true,
+			Similarity:      0.9,
+			Conceptual:      true,
+			MatchedConcepts: []ConceptMatch{{Importance: 8, Similarity: 1}},
+			KeywordMatches:  []string{"a", "b"},
+			QueryWords:      2,
+		},
+		{
+			Record:         &core.ChatRecord{Id: 2},
+			Semantic:       true,
+			Similarity:     0.7,
+			KeywordMatches: []string{"a"},
+			QueryWords:     2,
+		},
+		{
+			Record:          &core.ChatRecord{Id: 3},
+			Conceptual:      true,
+			MatchedConcepts: []ConceptMatch{{Importance: 3, Similarity: 1}, {Importance: 4, Similarity: 0.5}},
+			QueryWords:      2,
+		},
+	}
+}
+
+func TestAssignStageRanks(t *testing.T) {
+	signals := fusionSignals()
+	assignStageRanks(signals)
+
+	assert.Equal(t, 1, signals[0].SemanticRank)
+	assert.Equal(t, 2, signals[1].SemanticRank)
+	assert.Equal(t, 0, signals[2].SemanticRank)
+
+	assert.Equal(t, float32(8), signals[0].ConceptScore)
+	assert.Equal(t, float32(5), signals[2].ConceptScore)
+	assert.Equal(t, 1, signals[0].ConceptRank)
+	assert.Equal(t, 0, signals[1].ConceptRank)
+	assert.Equal(t, 2, signals[2].ConceptRank)
+
+	assert.Equal(t, float32(1), signals[0].KeywordScore)
+	assert.Equal(t, float32(0.5), signals[1].KeywordScore)
+	assert.Equal(t, 1, signals[0].KeywordRank)
+	assert.Equal(t, 2, signals[1].KeywordRank)
+	assert.Equal(t, 0, signals[2].KeywordRank)
+}
+
+func TestFuseRRF(t *testing.T) {
+	signals := fusionSignals()
+	assignStageRanks(signals)
+
+	scores := fuseRRF(signals, DefaultStageWeights(), 60)
+	require.Len(t, scores, 3)
+	assert.InDelta(t, 3.0/61, scores[0], 0.00001)
+	assert.InDelta(t, 2.0/62, scores[1], 0.00001)
+	assert.InDelta(t, 1.0/62, scores[2], 0.00001)
+
+	// Weights scale each stage's contribution
+	scores = fuseRRF(signals, StageWeights{Conceptual: 1}, 60)
+	assert.InDelta(t, 1.0/61, scores[0], 0.00001)
+	assert.Equal(t, float32(0), scores[1])
+	assert.InDelta(t, 1.0/62, scores[2], 0.00001)
+}
+
+func TestFuseWeightedSum(t *testing.T) {
+	signals := fusionSignals()
+	assignStageRanks(signals)
+
+	scores := fuseWeightedSum(signals, DefaultStageWeights())
+	require.Len(t, scores, 3)
+	assert.InDelta(t, 3.0, scores[0], 0.00001)
+	assert.InDelta(t, 0.7/0.9+0.5, scores[1], 0.00001)
+	// Found by a single stage at its lowest score, yet still above unfound records
+	assert.InDelta(t, 5.0/8, scores[2], 0.00001)
+
+	// A stage with a single hit scales it to 1
+	single := []*Signals{{Record: &core.ChatRecord{Id: 1}, Semantic: true, Similarity: 0.8}}
+	assignStageRanks(single)
+	assert.Equal(t, []float32{2}, fuseWeightedSum(single, StageWeights{Semantic: 2}))
+
+	// The best hits of two equally weighted stages tie
+	tied := []*Signals{
+		{Record: &core.ChatRecord{Id: 1}, Semantic: true, Similarity: 0.8},
+		{Record: &core.ChatRecord{Id: 2}, Semantic: true, Similarity: 0.6},
+		{Record: &core.ChatRecord{Id: 3}, Conceptual: true, MatchedConcepts: []ConceptMatch{{Importance: 6, Similarity: 1}}},
+	}
+	assignStageRanks(tied)
+	scores = fuseWeightedSum(tied, DefaultStageWeights())
+	assert.InDelta(t, 1.0, scores[0], 0.00001)
+	assert.InDelta(t, 0.75, scores[1], 0.00001)
+	assert.InDelta(t, 1.0, scores[2], 0.00001)
+}
+
+func TestParseFusionMode(t *testing.T) {
+	for _, mode := range []FusionMode{FusionRanker, FusionRRF, FusionWeightedSum} {
+		parsed, err := ParseFusionMode(mode.String())
+		require.NoError(t, err)
+		assert.Equal(t, mode, parsed)
+	}
+	_, err := ParseFusionMode("borda")
+	assert.ErrorIs(t, err, ErrInvalidFusion)
+	assert.Equal(t, "FusionMode(7)", FusionMode(7).String())
+}
@@ -17,12 +17,16 @@ package search
 
 import (
 	"iter"
+	"sync"
 
 	"github.com/poiesic/memorit/core"
 )
 
 // SearchMonitor provides hooks to observe the search process.
 // Implement this interface to track intermediate steps and results during search.
+// The semantic and conceptual stages run concurrently, so their callbacks may
+// arrive in either order, but calls are serialized: implementations need not
+// be safe for concurrent use.
 type SearchMonitor interface {
 	Start(query string)
 	AfterSemanticSearch(ids []uint64)
@@ -36,18 +40,111 @@ type SearchMonitor interface {
 	Finish(results []*core.SearchResult)
 }
 
+// StageSkipMonitor is implemented by monitors that want to know when a
+// failed stage is left out of a search. A SearchMonitor that also implements
+// it is called before Finish for each skipped stage.
+type StageSkipMonitor interface {
+	StageSkipped(stage Stage, err error)
+}
+
+// stageMonitor is the monitor a search reports to, including skipped stages.
+type stageMonitor interface {
+	SearchMonitor
+	StageSkipMonitor
+}
+
 // noopMonitor is a no-op implementation of SearchMonitor
 type noopMonitor struct{}
 
-var _ SearchMonitor = (*noopMonitor)(nil)
+var _ stageMonitor = (*noopMonitor)(nil)
 
 func (n *noopMonitor) Start(_ string)                                    {}
 func (n *noopMonitor) AfterSemanticSearch(_ []uint64)                    {}
-func (n *noopMonitor) AfterQueryConceptExtraction(_ []*core.Concept)    {}
+func (n *noopMonitor) AfterQueryConceptExtraction(_ []*core.Concept)     {}
 func (n *noopMonitor) FoundRelatedConcepts(_ string, _ []uint64)         {}
 func (n *noopMonitor) AfterConceptuallyRelatedSearch(_ iter.Seq[uint64]) {}
-func (n *noopMonitor) AfterRecordRetrieval(_ []*core.ChatRecord)        {}
-func (n *noopMonitor) SemanticAndConceptualHit(_ *core.ChatRecord)      {}
-func (n *noopMonitor) SemanticHit(_ *core.ChatRecord)                   {}
-func (n *noopMonitor) ConceptualHit(_ *core.ChatRecord)                 {}
-func (n *noopMonitor) Finish(_ []*core.SearchResult)                    {}
+func (n *noopMonitor) AfterRecordRetrieval(_ []*core.ChatRecord)         {}
+func (n *noopMonitor) SemanticAndConceptualHit(_ *core.ChatRecord)       {}
+func (n *noopMonitor) SemanticHit(_ *core.ChatRecord)                    {}
+func (n *noopMonitor) ConceptualHit(_ *core.ChatRecord)                  {}
+func (n *noopMonitor) StageSkipped(_ Stage, _ error)                     {}
+func (n *noopMonitor) Finish(_ []*core.SearchResult)                     {}
+
+// lockedMonitor serializes calls to a SearchMonitor shared by concurrent stages.
+type lockedMonitor struct {
+	mu      sync.Mutex
+	monitor SearchMonitor
+}
+
+var _ stageMonitor = (*lockedMonitor)(nil)
+
+func (m *lockedMonitor) Start(query string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.monitor.Start(query)
+}
+
+func (m *lockedMonitor) AfterSemanticSearch(ids []uint64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.monitor.AfterSemanticSearch(ids)
+}
+
+func (m *lockedMonitor) AfterQueryConceptExtraction(concepts []*core.Concept) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.monitor.AfterQueryConceptExtraction(concepts)
+}
+
+func (m *lockedMonitor) FoundRelatedConcepts(tuple string, conceptIds []uint64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.monitor.FoundRelatedConcepts(tuple, conceptIds)
+}
+
+func (m *lockedMonitor) AfterConceptuallyRelatedSearch(ids iter.Seq[uint64]) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.monitor.AfterConceptuallyRelatedSearch(ids)
+}
+
+func (m *lockedMonitor) AfterRecordRetrieval(records []*core.ChatRecord) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.monitor.AfterRecordRetrieval(records)
+}
+
+func (m *lockedMonitor) SemanticAndConceptualHit(record *core.ChatRecord) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.monitor.SemanticAndConceptualHit(record)
+}
+
+func (m *lockedMonitor) SemanticHit(record *core.ChatRecord) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.monitor.SemanticHit(record)
+}
+
+func (m *lockedMonitor) ConceptualHit(record *core.ChatRecord) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.monitor.ConceptualHit(record)
+}
+
+// StageSkipped forwards to the request's monitor if it implements StageSkipMonitor.
+func (m *lockedMonitor) StageSkipped(stage Stage, err error) {
+	skipMonitor, ok := m.monitor.(StageSkipMonitor)
+	if !ok {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	skipMonitor.StageSkipped(stage, err)
+}
+
+func (m *lockedMonitor) Finish(results []*core.SearchResult) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.monitor.Finish(results)
+}
@@ -0,0 +1,206 @@
+// Copyright 2025 Poiesic Systems
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+
+package search
+
+import (
+	"context"
+	"iter"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/poiesic/memorit/core"
+)
+
+// LogMonitor logs each stage of a search with the time elapsed since the
+// search started. Skipped stages are logged as warnings.
+// It tracks a single search: use a new LogMonitor for each request.
+type LogMonitor struct {
+	logger *slog.Logger
+	level  slog.Level
+	now    func() time.Time
+	start  time.Time
+	query  string
+	hits   [3]int // Semantic-only, conceptual-only and both
+}
+
+var (
+	_ SearchMonitor    = (*LogMonitor)(nil)
+	_ StageSkipMonitor = (*LogMonitor)(nil)
+)
+
+// NewLogMonitor creates a monitor that logs to logger at level.
+// A nil logger uses slog.Default().
+func NewLogMonitor(logger *slog.Logger, level slog.Level) *LogMonitor {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &LogMonitor{logger: logger, level: level, now: time.Now}
+}
+
+func (m *LogMonitor) log(msg string, args ...any) {
+	args = append(args, "query", m.query, "elapsed", m.now().Sub(m.start))
+	m.logger.Log(context.Background(), m.level, msg, args...)
+}
+
+func (m *LogMonitor) Start(query string) {
+	m.start = m.now()
+	m.query = query
+	m.hits = [3]int{}
+	m.logger.Log(context.Background(), m.level, "search started", "query", query)
+}
+
+func (m *LogMonitor) AfterSemanticSearch(ids []uint64) {
+	m.log("semantic search complete", "hits", len(ids))
+}
+
+func (m *LogMonitor) AfterQueryConceptExtraction(concepts []*core.Concept) {
+	tuples := make([]string, len(concepts))
+	for i, concept := range concepts {
+		tuples[i] = concept.Tuple()
+	}
+	m.log("query concepts resolved", "concepts", tuples)
+}
+
+func (m *LogMonitor) FoundRelatedConcepts(tuple string, conceptIds []uint64) {
+	m.log("related concepts found", "concept", tuple, "related", len(conceptIds))
+}
+
+func (m *LogMonitor) AfterConceptuallyRelatedSearch(ids iter.Seq[uint64]) {
+	hits := 0
+	for range ids {
+		hits++
+	}
+	m.log("conceptual search complete", "hits", hits)
+}
+
+func (m *LogMonitor) AfterRecordRetrieval(records []*core.ChatRecord) {
+	m.log("records retrieved", "records", len(records))
+}
+
+func (m *LogMonitor) SemanticAndConceptualHit(_ *core.ChatRecord) {
+	m.hits[2]++
+}
+
+func (m *LogMonitor) SemanticHit(_ *core.ChatRecord) {
+	m.hits[0]++
+}
+
+func (m *LogMonitor) ConceptualHit(_ *core.ChatRecord) {
+	m.hits[1]++
+}
+
+func (m *LogMonitor) StageSkipped(stage Stage, err error) {
+	m.logger.Warn("search stage skipped", "stage", stage, "err", err,
+		"query", m.query, "elapsed", m.now().Sub(m.start))
+}
+
+func (m *LogMonitor) Finish(results []*core.SearchResult) {
+	m.log("search finished", "results", len(results),
+		"semanticHits", m.hits[0], "conceptualHits", m.hits[1], "bothHits", m.hits[2])
+}
+
+// MultiMonitor fans each callback out to several monitors, in order.
+type MultiMonitor []SearchMonitor
+
+var (
+	_ SearchMonitor    = MultiMonitor(nil)
+	_ StageSkipMonitor = MultiMonitor(nil)
+)
+
+// NewMultiMonitor creates a monitor that forwards callbacks to each of
+// monitors. Nil monitors are ignored.
+func NewMultiMonitor(monitors ...SearchMonitor) MultiMonitor {
+	multi := make(MultiMonitor, 0, len(monitors))
+	for _, monitor := range monitors {
+		if monitor != nil {
+			multi = append(multi, monitor)
+		}
+	}
+	return multi
+}
+
+func (m MultiMonitor) Start(query string) {
+	for _, monitor := range m {
+		monitor.Start(query)
+	}
+}
+
+func (m MultiMonitor) AfterSemanticSearch(ids []uint64) {
+	for _, monitor := range m {
+		monitor.AfterSemanticSearch(ids)
+	}
+}
+
+func (m MultiMonitor) AfterQueryConceptExtraction(concepts []*core.Concept) {
+	for _, monitor := range m {
+		monitor.AfterQueryConceptExtraction(concepts)
+	}
+}
+
+func (m MultiMonitor) FoundRelatedConcepts(tuple string, conceptIds []uint64) {
+	for _, monitor := range m {
+		monitor.FoundRelatedConcepts(tuple, conceptIds)
+	}
+}
+
+// AfterConceptuallyRelatedSearch collects ids once so that every monitor
+// sees them, even if the sequence cannot be iterated twice.
+func (m MultiMonitor) AfterConceptuallyRelatedSearch(ids iter.Seq[uint64]) {
+	collected := slices.Collect(ids)
+	for _, monitor := range m {
+		monitor.AfterConceptuallyRelatedSearch(slices.Values(collected))
+	}
+}
+
+func (m MultiMonitor) AfterRecordRetrieval(records []*core.ChatRecord) {
+	for _, monitor := range m {
+		monitor.AfterRecordRetrieval(records)
+	}
+}
+
+func (m MultiMonitor) SemanticAndConceptualHit(record *core.ChatRecord) {
+	for _, monitor := range m {
+		monitor.SemanticAndConceptualHit(record)
+	}
+}
+
+func (m MultiMonitor) SemanticHit(record *core.ChatRecord) {
+	for _, monitor := range m {
+		monitor.SemanticHit(record)
+	}
+}
+
+func (m MultiMonitor) ConceptualHit(record *core.ChatRecord) {
+	for _, monitor := range m {
+		monitor.ConceptualHit(record)
+	}
+}
+
+// StageSkipped forwards to the monitors that implement StageSkipMonitor.
+func (m MultiMonitor) StageSkipped(stage Stage, err error) {
+	for _, monitor := range m {
+		if skipMonitor, ok := monitor.(StageSkipMonitor); ok {
+			skipMonitor.StageSkipped(stage, err)
+		}
+	}
+}
+
+func (m MultiMonitor) Finish(results []*core.SearchResult) {
+	for _, monitor := range m {
+		monitor.Finish(results)
+	}
+}
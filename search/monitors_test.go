@@ -0,0 +1,228 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"iter"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/poiesic/memorit/ai"
+	"github.com/poiesic/memorit/ai/mock"
+	"github.com/poiesic/memorit/core"
+	"github.com/poiesic/memorit/storage/badger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testSpan is a span recorded by testTracer.
+type testSpan struct {
+	name   string
+	parent *testSpan
+	attrs  map[string]any
+	errs   []error
+	ended  bool
+}
+
+func (s *testSpan) SetAttribute(key string, value any) { s.attrs[key] = value }
+func (s *testSpan) RecordError(err error)              { s.errs = append(s.errs, err) }
+func (s *testSpan) End()                               { s.ended = true }
+
+// testTracer records the spans it starts.
+type testTracer struct {
+	spans []*testSpan
+}
+
+func (t *testTracer) StartSpan(parent TraceSpan, name string) TraceSpan {
+	span := &testSpan{name: name, attrs: make(map[string]any)}
+	if parent != nil {
+		span.parent = parent.(*testSpan)
+	}
+	t.spans = append(t.spans, span)
+	return span
+}
+
+func (t *testTracer) span(name string) *testSpan {
+	for _, span := range t.spans {
+		if span.name == name {
+			return span
+		}
+	}
+	return nil
+}
+
+func TestTraceMonitor(t *testing.T) {
+	monitor := NewTraceMonitor()
+	clock := time.Unix(0, 0)
+	monitor.now = func() time.Time { return clock }
+	at := func(ms int) { clock = time.Unix(0, 0).Add(time.Duration(ms) * time.Millisecond) }
+
+	records := []*core.ChatRecord{{Id: 1}, {Id: 2}}
+	monitor.Start("machine learning")
+	at(2)
+	monitor.AfterQueryConceptExtraction([]*core.Concept{{Id: 10, Name: "machine", Type: "thing"}})
+	monitor.FoundRelatedConcepts("(thing,machine)", []uint64{10})
+	at(3)
+	monitor.AfterSemanticSearch([]uint64{1})
+	at(4)
+	monitor.AfterConceptuallyRelatedSearch(func(yield func(uint64) bool) { yield(2) })
+	at(5)
+	monitor.AfterRecordRetrieval(records)
+	monitor.SemanticHit(records[0])
+	monitor.ConceptualHit(records[1])
+	at(6)
+	monitor.StageSkipped(StageRerank, fmt.Errorf("reranker timed out"))
+	at(8)
+	monitor.Finish([]*core.SearchResult{{Record: records[0], Score: 0.9}, {Record: records[1], Score: 0.5}})
+
+	trace := monitor.Trace()
+	assert.Equal(t, "machine learning", trace.Query)
+	assert.Equal(t, []StageTrace{
+		{Name: PhaseSemantic, Start: 0, End: 3 * time.Millisecond, IDs: []uint64{1}},
+		{Name: PhaseConcepts, Start: 0, End: 2 * time.Millisecond, IDs: []uint64{10}},
+		{Name: PhaseConceptual, Start: 2 * time.Millisecond, End: 4 * time.Millisecond, IDs: []uint64{2}},
+		{Name: PhaseRetrieve, Start: 4 * time.Millisecond, End: 5 * time.Millisecond, IDs: []uint64{1, 2}},
+		{Name: PhaseRank, Start: 5 * time.Millisecond, End: 8 * time.Millisecond, IDs: []uint64{1, 2}, Error: "reranker timed out"},
+	}, trace.Stages)
+	assert.Equal(t, []ConceptTrace{{Tuple: "(thing,machine)", Related: []uint64{10}}}, trace.Concepts)
+	assert.Equal(t, []HitTrace{{ID: 1, Semantic: true}, {ID: 2, Conceptual: true}}, trace.Hits)
+	assert.Equal(t, []ResultTrace{{ID: 1, Score: 0.9}, {ID: 2, Score: 0.5}}, trace.Results)
+	assert.Equal(t, 8*time.Millisecond, trace.Duration)
+
+	encoded, err := json.Marshal(trace)
+	require.NoError(t, err)
+	assert.Contains(t, string(encoded), `{"name":"rank","start":5000000,"end":8000000,"ids":[1,2],"error":"reranker timed out"}`)
+}
+
+func TestMultiMonitor(t *testing.T) {
+	first, second := NewTraceMonitor(), NewTraceMonitor()
+	multi := NewMultiMonitor(first, nil, second)
+	require.Len(t, multi, 2)
+
+	// A single-use sequence still reaches every monitor
+	used := false
+	var once iter.Seq[uint64] = func(yield func(uint64) bool) {
+		if used {
+			return
+		}
+		used = true
+		for _, id := range []uint64{3, 1} {
+			if !yield(id) {
+				return
+			}
+		}
+	}
+
+	multi.Start("query")
+	multi.AfterQueryConceptExtraction(nil)
+	multi.AfterConceptuallyRelatedSearch(once)
+	multi.Finish(nil)
+
+	for _, monitor := range []*TraceMonitor{first, second} {
+		trace := monitor.Trace()
+		assert.Equal(t, "query", trace.Query)
+		assert.Equal(t, []uint64{1, 3}, trace.Stages[2].IDs)
+	}
+}
+
+func TestMonitors_Search(t *testing.T) {
+	chatRepo, conceptRepo, backend, err := badger.NewMemoryRepositories()
+	require.NoError(t, err)
+	defer func() {
+		conceptRepo.Close()
+		chatRepo.Close()
+		backend.Close()
+	}()
+
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	added, err := chatRepo.AddChatRecords(ctx, &core.ChatRecord{
+		Speaker:   core.SpeakerTypeHuman,
+		Contents:  "Machine learning is fascinating",
+		Timestamp: now,
+		Vector:    []float32{1.0, 0.0, 0.0},
+	})
+	require.NoError(t, err)
+
+	extractErr := fmt.Errorf("classifier returned invalid JSON")
+	mockEmbedder := mock.NewMockEmbedder()
+	mockEmbedder.EmbedTextFunc = func(ctx context.Context, text string) ([]float32, error) {
+		return []float32{1.0, 0.0, 0.0}, nil
+	}
+	mockExtractor := mock.NewMockConceptExtractor()
+	mockExtractor.ExtractConceptsFunc = func(ctx context.Context, text string) ([]ai.ExtractedConcept, error) {
+		return nil, extractErr
+	}
+	searcher, err := NewSearcher(chatRepo, conceptRepo, mock.NewMockProviderWithServices(mockEmbedder, mockExtractor))
+	require.NoError(t, err)
+
+	var logs bytes.Buffer
+	logMonitor := NewLogMonitor(slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug})), slog.LevelDebug)
+	traceMonitor := NewTraceMonitor()
+	tracer := &testTracer{}
+
+	response, err := searcher.Search(ctx, &SearchRequest{
+		Query:   "machine learning",
+		MaxHits: 10,
+		Monitor: NewMultiMonitor(logMonitor, traceMonitor, NewTraceSpanMonitor(tracer)),
+	})
+	require.NoError(t, err)
+	require.Len(t, response.Results, 1)
+
+	t.Run("log monitor", func(t *testing.T) {
+		output := logs.String()
+		for _, msg := range []string{"search started", "semantic search complete", "conceptual search complete", "records retrieved", "search finished"} {
+			assert.Contains(t, output, msg)
+		}
+		assert.Contains(t, output, "level=WARN msg=\"search stage skipped\" stage=conceptual")
+		assert.Contains(t, output, "semanticHits=1")
+		assert.Contains(t, output, "elapsed=")
+	})
+
+	t.Run("trace monitor", func(t *testing.T) {
+		trace := traceMonitor.Trace()
+		names := make([]string, len(trace.Stages))
+		for i, stage := range trace.Stages {
+			names[i] = stage.Name
+		}
+		assert.Equal(t, []string{PhaseSemantic, PhaseConcepts, PhaseRetrieve, PhaseRank}, names)
+		assert.Equal(t, []uint64{uint64(added[0].Id)}, trace.Stages[0].IDs)
+		assert.Equal(t, extractErr.Error(), trace.Stages[1].Error)
+		assert.Equal(t, []HitTrace{{ID: uint64(added[0].Id), Semantic: true}}, trace.Hits)
+		require.Len(t, trace.Results, 1)
+		assert.Positive(t, trace.Duration)
+	})
+
+	t.Run("span monitor", func(t *testing.T) {
+		root := tracer.span("search")
+		require.NotNil(t, root)
+		assert.Nil(t, root.parent)
+		assert.Equal(t, "machine learning", root.attrs["query"])
+		for _, span := range tracer.spans {
+			assert.True(t, span.ended, span.name)
+			if span != root {
+				assert.Same(t, root, span.parent, span.name)
+			}
+		}
+		assert.Equal(t, int64(1), tracer.span("search.semantic").attrs["hits"])
+		assert.Equal(t, []error{extractErr}, tracer.span("search.concepts").errs)
+		assert.Nil(t, tracer.span("search.conceptual"))
+		assert.Equal(t, int64(1), tracer.span("search.rank").attrs["results"])
+		assert.Equal(t, int64(1), root.attrs["results"])
+
+		// Attributes only use the value types TraceSpan documents
+		for _, span := range tracer.spans {
+			for key, value := range span.attrs {
+				switch value.(type) {
+				case string, int64, []string:
+				default:
+					t.Errorf("span %s attribute %s has type %T", span.name, key, value)
+				}
+			}
+		}
+	})
+}
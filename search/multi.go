@@ -0,0 +1,285 @@
+// Copyright 2025 Poiesic Systems
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+
+package search
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/poiesic/memorit/ai"
+	"github.com/poiesic/memorit/core"
+)
+
+// Source is a named searcher queried by a MultiSearcher.
+type Source struct {
+	Name     string
+	Searcher *Searcher
+}
+
+// Normalization selects how a MultiSearcher makes scores from different
+// sources comparable before merging them.
+type Normalization int
+
+const (
+	// NormalizeNone merges the raw scores. The sources share the query's
+	// embedding and extracted concepts, so with identically configured
+	// searchers their scores are already on one scale, and a strong source's
+	// results outrank a weak source's. This is the default.
+	NormalizeNone Normalization = iota
+	// NormalizeMax divides each source's scores by its best score,
+	// preserving the ratios between them. Use it when the source searchers
+	// score on different scales, such as with different rankers or boosts.
+	NormalizeMax
+	// NormalizeMinMax scales each source's scores to [0, 1], so its best
+	// result scores 1 and its worst 0. A source with a single result, or
+	// equal scores, scores 1. It discards how good each source's matches
+	// are: one weak match from a small source ties the best hit of a strong
+	// source, and each source's last result falls below any minimum score.
+	// Use it only for sources whose scores share no scale at all.
+	NormalizeMinMax
+)
+
+// SourceResult is a merged result labeled with the source it came from.
+type SourceResult struct {
+	Source string
+	// Score is the normalized score results are merged by.
+	Score float32
+	// Result is the source's result, with its original score.
+	Result *core.SearchResult
+}
+
+// SourceError records a source that failed.
+type SourceError struct {
+	Source string
+	Err    error
+}
+
+// MultiResponse is the result of a federated search.
+type MultiResponse struct {
+	// Results from every source, merged by normalized score, highest first.
+	Results []*SourceResult
+	// Failed lists the sources that failed, in source order.
+	Failed []SourceError
+	// Skipped lists the stages each successful source left out.
+	Skipped map[string][]SkippedStage
+}
+
+// queryAnalysis is a query's embedding and extracted concepts, shared by
+// the searches of a MultiSearcher so the AI provider is called once.
+type queryAnalysis struct {
+	query      string // The query text analyzed, without time expressions
+	embedding  []float32
+	embedErr   error
+	concepts   []ai.ExtractedConcept
+	extractErr error
+}
+
+// MultiSearcher searches several searchers, typically over separate
+// databases, concurrently and merges their results.
+type MultiSearcher struct {
+	sources       []Source
+	normalization Normalization
+	sourceTimeout time.Duration
+	logger        *slog.Logger
+}
+
+// MultiOption configures a MultiSearcher.
+type MultiOption func(*MultiSearcher) error
+
+// WithNormalization selects how scores are normalized across sources.
+// Default is NormalizeNone.
+func WithNormalization(normalization Normalization) MultiOption {
+	return func(m *MultiSearcher) error {
+		if normalization < NormalizeNone || normalization > NormalizeMinMax {
+			return ErrInvalidNormalization
+		}
+		m.normalization = normalization
+		return nil
+	}
+}
+
+// WithSourceTimeout bounds each source's search. A source that times out is
+// reported as failed. Zero means no timeout.
+// Default is 0.
+func WithSourceTimeout(timeout time.Duration) MultiOption {
+	return func(m *MultiSearcher) error {
+		if timeout < 0 {
+			return ErrInvalidTimeout
+		}
+		m.sourceTimeout = timeout
+		return nil
+	}
+}
+
+// WithMultiLogger sets a custom logger.
+// Default is slog.Default().
+func WithMultiLogger(logger *slog.Logger) MultiOption {
+	return func(m *MultiSearcher) error {
+		if logger == nil {
+			logger = slog.Default()
+		}
+		m.logger = logger
+		return nil
+	}
+}
+
+// NewMultiSearcher creates a searcher over sources. The query is embedded and
+// its concepts extracted once, with the first source's AI provider, so every
+// source must use the same embedding model.
+func NewMultiSearcher(sources []Source, opts ...MultiOption) (*MultiSearcher, error) {
+	if len(sources) == 0 {
+		return nil, ErrNoSources
+	}
+	names := make(map[string]bool, len(sources))
+	for _, source := range sources {
+		if source.Searcher == nil || source.Name == "" || names[source.Name] {
+			return nil, ErrInvalidSource
+		}
+		names[source.Name] = true
+	}
+
+	m := &MultiSearcher{
+		sources: slices.Clone(sources),
+		logger:  slog.Default(),
+	}
+	for _, opt := range opts {
+		if err := opt(m); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// Search runs req against every source concurrently and returns up to
+// req.MaxHits results merged by normalized score. Sources that fail are
+// reported in the response; Search fails only if every source fails or ctx
+// is done. req.Monitor is not used, since the sources search concurrently.
+func (m *MultiSearcher) Search(ctx context.Context, req *SearchRequest) (*MultiResponse, error) {
+	analysis := m.analyze(ctx, req)
+
+	responses := make([]*SearchResponse, len(m.sources))
+	errs := make([]error, len(m.sources))
+	var wg sync.WaitGroup
+	for i, source := range m.sources {
+		wg.Go(func() {
+			ctx, cancel := withStageTimeout(ctx, m.sourceTimeout)
+			defer cancel()
+			sourceReq := *req
+			sourceReq.Monitor = nil
+			sourceReq.analysis = analysis
+			responses[i], errs[i] = source.Searcher.Search(ctx, &sourceReq)
+		})
+	}
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	response := &MultiResponse{Skipped: make(map[string][]SkippedStage)}
+	for i, source := range m.sources {
+		if errs[i] != nil {
+			m.logger.Warn("source search failed, continuing with the other sources", "source", source.Name, "err", errs[i])
+			response.Failed = append(response.Failed, SourceError{Source: source.Name, Err: errs[i]})
+			continue
+		}
+		if len(responses[i].Skipped) > 0 {
+			response.Skipped[source.Name] = responses[i].Skipped
+		}
+		scores := normalizeScores(responses[i].Results, m.normalization)
+		for j, result := range responses[i].Results {
+			response.Results = append(response.Results, &SourceResult{Source: source.Name, Score: scores[j], Result: result})
+		}
+	}
+	if len(response.Failed) == len(m.sources) {
+		failures := make([]error, len(response.Failed))
+		for i, failed := range response.Failed {
+			failures[i] = failed.Err
+		}
+		return nil, errors.Join(append([]error{ErrAllSourcesFailed}, failures...)...)
+	}
+
+	// Sort by normalized score, keeping source order and each source's ranking for ties
+	slices.SortStableFunc(response.Results, func(a, b *SourceResult) int {
+		return cmp.Compare(b.Score, a.Score)
+	})
+	if len(response.Results) > req.MaxHits {
+		response.Results = response.Results[:req.MaxHits]
+	}
+	return response, nil
+}
+
+// analyze embeds the query and extracts its concepts concurrently, using the
+// first source's AI services, query cache, stage timeouts and time mode.
+// Failures are recorded, so each source degrades or fails as it would had its
+// own call failed. Sources that remove different time expressions from the
+// query analyze it themselves.
+func (m *MultiSearcher) analyze(ctx context.Context, req *SearchRequest) *queryAnalysis {
+	first := m.sources[0].Searcher
+	resolved, _ := first.resolveTimeExpressions(req)
+	query := &SearchRequest{Query: resolved.Query}
+	analysis := &queryAnalysis{query: resolved.Query}
+	var wg sync.WaitGroup
+	wg.Go(func() {
+		ctx, cancel := withStageTimeout(ctx, first.semanticTimeout)
+		defer cancel()
+		analysis.embedding, analysis.embedErr = first.embedQuery(ctx, query)
+	})
+	wg.Go(func() {
+		ctx, cancel := withStageTimeout(ctx, first.conceptTimeout)
+		defer cancel()
+		analysis.concepts, analysis.extractErr = first.extractQuery(ctx, query)
+	})
+	wg.Wait()
+	return analysis
+}
+
+// normalizeScores returns the scores of one source's results, normalized
+// so that they are comparable with other sources' scores.
+func normalizeScores(results []*core.SearchResult, normalization Normalization) []float32 {
+	scores := make([]float32, len(results))
+	if len(results) == 0 {
+		return scores
+	}
+	lo, hi := results[0].Score, results[0].Score
+	for i, result := range results {
+		scores[i] = result.Score
+		lo = min(lo, result.Score)
+		hi = max(hi, result.Score)
+	}
+	switch normalization {
+	case NormalizeMinMax:
+		for i := range scores {
+			if hi > lo {
+				scores[i] = (scores[i] - lo) / (hi - lo)
+			} else {
+				scores[i] = 1
+			}
+		}
+	case NormalizeMax:
+		if hi > 0 {
+			for i := range scores {
+				scores[i] /= hi
+			}
+		}
+	}
+	return scores
+}
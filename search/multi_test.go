@@ -0,0 +1,215 @@
+package search
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/poiesic/memorit/ai"
+	"github.com/poiesic/memorit/ai/mock"
+	"github.com/poiesic/memorit/core"
+	"github.com/poiesic/memorit/storage"
+	"github.com/poiesic/memorit/storage/badger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// failingChatRepository fails every record lookup.
+type failingChatRepository struct {
+	storage.ChatRepository
+}
+
+func (r *failingChatRepository) GetChatRecords(ctx context.Context, ids ...core.ID) ([]*core.ChatRecord, error) {
+	return nil, assert.AnError
+}
+
+func TestNormalizeScores(t *testing.T) {
+	results := []*core.SearchResult{{Score: 2}, {Score: 1.5}, {Score: 1}}
+
+	assert.Equal(t, []float32{1, 0.5, 0}, normalizeScores(results, NormalizeMinMax))
+	assert.Equal(t, []float32{1, 0.75, 0.5}, normalizeScores(results, NormalizeMax))
+	assert.Equal(t, []float32{2, 1.5, 1}, normalizeScores(results, NormalizeNone))
+	assert.Equal(t, []float32{1}, normalizeScores(results[:1], NormalizeMinMax))
+	assert.Empty(t, normalizeScores(nil, NormalizeMinMax))
+}
+
+func TestNewMultiSearcher(t *testing.T) {
+	chatRepo, conceptRepo, backend, err := badger.NewMemoryRepositories()
+	require.NoError(t, err)
+	defer func() {
+		conceptRepo.Close()
+		chatRepo.Close()
+		backend.Close()
+	}()
+	searcher, err := NewSearcher(chatRepo, conceptRepo, mock.NewMockProvider())
+	require.NoError(t, err)
+
+	_, err = NewMultiSearcher(nil)
+	assert.ErrorIs(t, err, ErrNoSources)
+	_, err = NewMultiSearcher([]Source{{Name: "a"}})
+	assert.ErrorIs(t, err, ErrInvalidSource)
+	_, err = NewMultiSearcher([]Source{{Searcher: searcher}})
+	assert.ErrorIs(t, err, ErrInvalidSource)
+	_, err = NewMultiSearcher([]Source{{Name: "a", Searcher: searcher}, {Name: "a", Searcher: searcher}})
+	assert.ErrorIs(t, err, ErrInvalidSource)
+	_, err = NewMultiSearcher([]Source{{Name: "a", Searcher: searcher}}, WithNormalization(Normalization(9)))
+	assert.ErrorIs(t, err, ErrInvalidNormalization)
+	_, err = NewMultiSearcher([]Source{{Name: "a", Searcher: searcher}}, WithSourceTimeout(-time.Second))
+	assert.ErrorIs(t, err, ErrInvalidTimeout)
+
+	multi, err := NewMultiSearcher([]Source{{Name: "a", Searcher: searcher}})
+	require.NoError(t, err)
+	assert.Equal(t, NormalizeNone, multi.normalization)
+	multi, err = NewMultiSearcher([]Source{{Name: "a", Searcher: searcher}}, WithNormalization(NormalizeMax))
+	require.NoError(t, err)
+	assert.Equal(t, NormalizeMax, multi.normalization)
+}
+
+func TestMultiSearcher_Search(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	embedder := mock.NewMockEmbedder()
+	embedder.EmbedTextFunc = func(ctx context.Context, text string) ([]float32, error) {
+		return []float32{1.0, 0.0, 0.0}, nil
+	}
+	extractor := mock.NewMockConceptExtractor()
+	extractor.ExtractConceptsFunc = func(ctx context.Context, text string) ([]ai.ExtractedConcept, error) {
+		return []ai.ExtractedConcept{{Name: "machine", Type: "thing", Importance: 8}}, nil
+	}
+	provider := mock.NewMockProviderWithServices(embedder, extractor)
+
+	// newSource creates a searcher over a memory database holding records
+	// with the given contents and vectors, all mentioning the machine concept.
+	newSource := func(t *testing.T, contents []string, vectors [][]float32) (*Searcher, []*core.ChatRecord, func()) {
+		chatRepo, conceptRepo, backend, err := badger.NewMemoryRepositories()
+		require.NoError(t, err)
+		concept := &core.Concept{Name: "machine", Type: "thing", InsertedAt: now, UpdatedAt: now}
+		concept.Id = core.IDFromContent(concept.Tuple())
+		_, err = conceptRepo.AddConcepts(ctx, concept)
+		require.NoError(t, err)
+
+		records := make([]*core.ChatRecord, len(contents))
+		for i := range contents {
+			records[i] = &core.ChatRecord{
+				Speaker:   core.SpeakerTypeHuman,
+				Contents:  contents[i],
+				Timestamp: now,
+				Vector:    vectors[i],
+				Concepts:  []core.ConceptRef{{ConceptId: concept.Id, Importance: 5}},
+			}
+		}
+		added, err := chatRepo.AddChatRecords(ctx, records...)
+		require.NoError(t, err)
+
+		searcher, err := NewSearcher(chatRepo, conceptRepo, provider)
+		require.NoError(t, err)
+		return searcher, added, func() {
+			conceptRepo.Close()
+			chatRepo.Close()
+			backend.Close()
+		}
+	}
+
+	work, workRecords, closeWork := newSource(t,
+		[]string{"The machine at work broke", "We bought a new machine"},
+		[][]float32{{1.0, 0.0, 0.0}, {0.6, 0.8, 0.0}})
+	defer closeWork()
+	home, homeRecords, closeHome := newSource(t,
+		[]string{"My washing machine is loud"},
+		[][]float32{{0.0, 1.0, 0.0}})
+	defer closeHome()
+
+	t.Run("merges labeled results and analyzes the query once", func(t *testing.T) {
+		embedder.Reset()
+		embedder.EmbedTextFunc = func(ctx context.Context, text string) ([]float32, error) {
+			return []float32{1.0, 0.0, 0.0}, nil
+		}
+		extractor.Reset()
+		extractor.ExtractConceptsFunc = func(ctx context.Context, text string) ([]ai.ExtractedConcept, error) {
+			return []ai.ExtractedConcept{{Name: "machine", Type: "thing", Importance: 8}}, nil
+		}
+
+		multi, err := NewMultiSearcher([]Source{{Name: "work", Searcher: work}, {Name: "home", Searcher: home}})
+		require.NoError(t, err)
+
+		response, err := multi.Search(ctx, &SearchRequest{Query: "machine", MaxHits: 10})
+		require.NoError(t, err)
+		assert.Equal(t, 1, embedder.CallCount())
+		assert.Equal(t, 1, extractor.CallCount())
+		assert.Empty(t, response.Failed)
+
+		require.Len(t, response.Results, 3)
+		// Raw scores are merged, so the home source's concept-only match ranks
+		// between the work source's semantic matches
+		assert.Equal(t, "work", response.Results[0].Source)
+		assert.Equal(t, workRecords[0].Id, response.Results[0].Result.Record.Id)
+		assert.Equal(t, "home", response.Results[1].Source)
+		assert.Equal(t, homeRecords[0].Id, response.Results[1].Result.Record.Id)
+		assert.Equal(t, "work", response.Results[2].Source)
+		assert.Equal(t, workRecords[1].Id, response.Results[2].Result.Record.Id)
+		for _, result := range response.Results {
+			assert.Equal(t, result.Result.Score, result.Score)
+		}
+
+		response, err = multi.Search(ctx, &SearchRequest{Query: "machine", MaxHits: 2})
+		require.NoError(t, err)
+		assert.Len(t, response.Results, 2)
+	})
+
+	t.Run("min-max normalization", func(t *testing.T) {
+		multi, err := NewMultiSearcher([]Source{{Name: "work", Searcher: work}, {Name: "home", Searcher: home}},
+			WithNormalization(NormalizeMinMax))
+		require.NoError(t, err)
+
+		response, err := multi.Search(ctx, &SearchRequest{Query: "machine", MaxHits: 10})
+		require.NoError(t, err)
+		require.Len(t, response.Results, 3)
+		// Each source's best result normalizes to 1; ties keep source order
+		assert.Equal(t, "work", response.Results[0].Source)
+		assert.Equal(t, float32(1), response.Results[0].Score)
+		assert.Equal(t, "home", response.Results[1].Source)
+		assert.Equal(t, float32(1), response.Results[1].Score)
+		assert.Equal(t, "work", response.Results[2].Source)
+		assert.Equal(t, float32(0), response.Results[2].Score)
+		assert.Greater(t, response.Results[2].Result.Score, float32(0), "original score is kept")
+	})
+
+	t.Run("tolerates failed sources", func(t *testing.T) {
+		broken, err := NewSearcher(&failingChatRepository{work.chatRepository}, work.conceptRepository, provider)
+		require.NoError(t, err)
+
+		multi, err := NewMultiSearcher([]Source{{Name: "work", Searcher: work}, {Name: "broken", Searcher: broken}})
+		require.NoError(t, err)
+
+		response, err := multi.Search(ctx, &SearchRequest{Query: "machine", MaxHits: 10})
+		require.NoError(t, err)
+		require.Len(t, response.Failed, 1)
+		assert.Equal(t, "broken", response.Failed[0].Source)
+		assert.ErrorIs(t, response.Failed[0].Err, assert.AnError)
+		require.Len(t, response.Results, 2)
+		for _, result := range response.Results {
+			assert.Equal(t, "work", result.Source)
+		}
+
+		multi, err = NewMultiSearcher([]Source{{Name: "broken", Searcher: broken}})
+		require.NoError(t, err)
+		_, err = multi.Search(ctx, &SearchRequest{Query: "machine", MaxHits: 10})
+		assert.ErrorIs(t, err, ErrAllSourcesFailed)
+	})
+
+	t.Run("shared analysis failure degrades every source", func(t *testing.T) {
+		extractor.ExtractConceptsFunc = func(ctx context.Context, text string) ([]ai.ExtractedConcept, error) {
+			return nil, assert.AnError
+		}
+		defer func() { extractor.ExtractConceptsFunc = nil }()
+
+		multi, err := NewMultiSearcher([]Source{{Name: "work", Searcher: work}, {Name: "home", Searcher: home}})
+		require.NoError(t, err)
+
+		response, err := multi.Search(ctx, &SearchRequest{Query: "machine", MaxHits: 10})
+		require.NoError(t, err)
+		assert.Equal(t, []SkippedStage{{Stage: StageConceptual, Err: assert.AnError}}, response.Skipped["work"])
+		assert.Equal(t, []SkippedStage{{Stage: StageConceptual, Err: assert.AnError}}, response.Skipped["home"])
+	})
+}
@@ -0,0 +1,137 @@
+// Copyright 2025 Poiesic Systems
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+
+package search
+
+import (
+	"cmp"
+	"context"
+	"slices"
+
+	"github.com/poiesic/memorit/core"
+	"github.com/poiesic/memorit/storage"
+)
+
+// Passage is a run of consecutive messages surrounding one or more search hits.
+type Passage struct {
+	// Records are the messages in the passage, ordered by timestamp.
+	Records []*core.ChatRecord
+	// Matches are the indexes into Records of the messages that matched the query.
+	Matches []int
+	// Score is the best score among the matched messages.
+	Score float32
+	// Conversation is the core.MetadataConversation value the passage was drawn
+	// from, or empty if neighbors were taken by timestamp across all messages.
+	Conversation string
+}
+
+// Matched reports whether Records[i] matched the query.
+func (p *Passage) Matched(i int) bool {
+	return slices.Contains(p.Matches, i)
+}
+
+// window is the neighborhood of a single hit before merging.
+type window struct {
+	conversation string
+	records      []*core.ChatRecord
+	hits         map[core.ID]bool
+	score        float32
+}
+
+// buildPassages expands each result with its neighboring messages and merges
+// overlapping windows into passages, ordered by score descending.
+func (s *Searcher) buildPassages(ctx context.Context, results []*core.SearchResult) ([]*Passage, error) {
+	var windows []*window
+	for _, result := range results {
+		conversation := result.Record.Metadata[core.MetadataConversation]
+		var filter *storage.RecordFilter
+		if conversation != "" {
+			filter = &storage.RecordFilter{Conversations: []string{conversation}}
+		}
+
+		records, err := s.chatRepository.GetAdjacentChatRecords(ctx, result.Record.Id, s.contextBefore, s.contextAfter, filter)
+		if err != nil {
+			s.logger.Error("error retrieving context window", "recordID", result.Record.Id, "err", err)
+			return nil, err
+		}
+		w := &window{
+			conversation: conversation,
+			records:      records,
+			hits:         map[core.ID]bool{result.Record.Id: true},
+			score:        result.Score,
+		}
+
+		// Fold in every earlier window this one overlaps, which may chain several together
+		kept := windows[:0]
+		for _, other := range windows {
+			if other.conversation == w.conversation && overlaps(other, w) {
+				w.absorb(other)
+			} else {
+				kept = append(kept, other)
+			}
+		}
+		windows = append(kept, w)
+	}
+
+	passages := make([]*Passage, len(windows))
+	for i, w := range windows {
+		p := &Passage{Records: w.records, Score: w.score, Conversation: w.conversation}
+		for j, record := range w.records {
+			if w.hits[record.Id] {
+				p.Matches = append(p.Matches, j)
+			}
+		}
+		passages[i] = p
+	}
+	slices.SortStableFunc(passages, func(a, b *Passage) int {
+		return cmp.Compare(b.Score, a.Score)
+	})
+	return passages, nil
+}
+
+// overlaps reports whether two windows share a message.
+func overlaps(a, b *window) bool {
+	for _, ra := range a.records {
+		for _, rb := range b.records {
+			if ra.Id == rb.Id {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// absorb merges other into w, keeping records in timestamp order without duplicates.
+func (w *window) absorb(other *window) {
+	seen := make(map[core.ID]bool, len(w.records))
+	for _, record := range w.records {
+		seen[record.Id] = true
+	}
+	for _, record := range other.records {
+		if !seen[record.Id] {
+			w.records = append(w.records, record)
+		}
+	}
+	slices.SortFunc(w.records, func(a, b *core.ChatRecord) int {
+		if c := a.Timestamp.Compare(b.Timestamp); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Id, b.Id)
+	})
+	for id := range other.hits {
+		w.hits[id] = true
+	}
+	w.score = max(w.score, other.score)
+}
@@ -0,0 +1,341 @@
+// Copyright 2025 Poiesic Systems
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+
+package search
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/poiesic/memorit/core"
+	"github.com/poiesic/memorit/storage"
+)
+
+// ConceptConstraint names a concept that every result must be linked to.
+type ConceptConstraint struct {
+	Type string
+	Name string
+}
+
+// Tuple returns the concept's "(type,name)" identity tuple.
+func (c ConceptConstraint) Tuple() string {
+	return "(" + c.Type + "," + c.Name + ")"
+}
+
+// Boost multiplies the relevance of results whose contents contain Term.
+type Boost struct {
+	Term   string
+	Factor float32
+}
+
+// queryToken is a whitespace-separated element of a query string.
+type queryToken struct {
+	text    string
+	negated bool   // Prefixed with '-'
+	quoted  bool   // The whole token was a quoted phrase
+	boost   string // Number after '^' following a quoted phrase
+}
+
+// ParseQuery parses a search query string into a SearchRequest.
+// The grammar is a sequence of whitespace-separated elements:
+//
+//	word                 free text, used by every retrieval stage
+//	"exact phrase"       free text that every result must contain
+//	-word, -"phrase"     words or phrases that no result may contain
+//	word^2, "phrase"^2   free text that multiplies the relevance of results containing it
+//	                     ('^' not followed by a number is ordinary text)
+//	concept:type/name    a concept every result must be linked to
+//	speaker:human|ai     restrict results to a speaker; may be repeated
+//	after:DATE           results at or after DATE (YYYY-MM-DD or RFC 3339)
+//	before:DATE          results before DATE
+//	conversation:ID      restrict results to a conversation; may be repeated
+//	meta:key=value       metadata equality; -meta:key=value for inequality
+//	meta:key             metadata key exists; -meta:key for absence
+//
+// Values containing spaces can be quoted, as in meta:topic="home lab".
+// Elements with an unrecognized field name are treated as free text.
+// The returned request has no MaxHits; callers set it and any other options.
+func ParseQuery(input string) (*SearchRequest, error) {
+	tokens, err := lexQuery(input)
+	if err != nil {
+		return nil, err
+	}
+
+	req := &SearchRequest{}
+	var text []string
+	for _, token := range tokens {
+		if token.quoted {
+			switch {
+			case token.boost != "":
+				if err := addBoost(req, token.text, token.boost, token.negated); err != nil {
+					return nil, err
+				}
+				text = append(text, token.text)
+			case token.negated:
+				req.Exclude = append(req.Exclude, token.text)
+			default:
+				req.Phrases = append(req.Phrases, token.text)
+				text = append(text, token.text)
+			}
+			continue
+		}
+
+		if key, value, ok := strings.Cut(token.text, ":"); ok && value != "" {
+			handled, err := applyQueryField(req, strings.ToLower(key), value, token.negated)
+			if err != nil {
+				return nil, err
+			}
+			if handled {
+				continue
+			}
+		}
+		if i := strings.LastIndexByte(token.text, '^'); i > 0 && isBoostNumber(token.text[i+1:]) {
+			term := token.text[:i]
+			if err := addBoost(req, term, token.text[i+1:], token.negated); err != nil {
+				return nil, err
+			}
+			text = append(text, term)
+			continue
+		}
+		if token.negated {
+			req.Exclude = append(req.Exclude, token.text)
+		} else {
+			text = append(text, token.text)
+		}
+	}
+	req.Query = strings.Join(text, " ")
+	return req, nil
+}
+
+// lexQuery splits a query string into tokens, honoring double-quoted phrases.
+func lexQuery(input string) ([]queryToken, error) {
+	runes := []rune(input)
+	var tokens []queryToken
+	for i := 0; i < len(runes); {
+		if unicode.IsSpace(runes[i]) {
+			i++
+			continue
+		}
+
+		var token queryToken
+		if runes[i] == '-' && i+1 < len(runes) && !unicode.IsSpace(runes[i+1]) {
+			token.negated = true
+			i++
+		}
+		if runes[i] == '"' {
+			end := closingQuote(runes, i)
+			if end < 0 {
+				return nil, fmt.Errorf("%w: unterminated phrase", ErrInvalidQuery)
+			}
+			token.text = string(runes[i+1 : end])
+			token.quoted = true
+			i = end + 1
+			// A '^' not followed by a number starts the next token
+			if i < len(runes) && runes[i] == '^' {
+				end := i + 1
+				for end < len(runes) && !unicode.IsSpace(runes[end]) {
+					end++
+				}
+				if boost := string(runes[i+1 : end]); isBoostNumber(boost) {
+					token.boost = boost
+					i = end
+				}
+			}
+			tokens = append(tokens, token)
+			continue
+		}
+
+		var b strings.Builder
+		for i < len(runes) && !unicode.IsSpace(runes[i]) {
+			if runes[i] == '"' {
+				end := closingQuote(runes, i)
+				if end < 0 {
+					return nil, fmt.Errorf("%w: unterminated phrase", ErrInvalidQuery)
+				}
+				b.WriteString(string(runes[i+1 : end]))
+				i = end + 1
+				continue
+			}
+			b.WriteRune(runes[i])
+			i++
+		}
+		token.text = b.String()
+		tokens = append(tokens, token)
+	}
+	return tokens, nil
+}
+
+// closingQuote returns the index of the quote closing the one at open, or -1.
+func closingQuote(runes []rune, open int) int {
+	for i := open + 1; i < len(runes); i++ {
+		if runes[i] == '"' {
+			return i
+		}
+	}
+	return -1
+}
+
+// applyQueryField applies a field:value element to req.
+// handled is false if key is not a recognized field name.
+func applyQueryField(req *SearchRequest, key, value string, negated bool) (handled bool, err error) {
+	switch key {
+	case "meta":
+	case "speaker", "after", "before", "conversation", "concept":
+		if negated {
+			return false, fmt.Errorf("%w: %s cannot be negated", ErrInvalidQuery, key)
+		}
+	default:
+		return false, nil
+	}
+
+	switch key {
+	case "speaker":
+		speaker, err := parseSpeaker(value)
+		if err != nil {
+			return false, err
+		}
+		req.Filter.Speakers = append(req.Filter.Speakers, speaker)
+	case "after":
+		start, err := parseQueryTime(value)
+		if err != nil {
+			return false, err
+		}
+		req.Filter.Start = start
+	case "before":
+		end, err := parseQueryTime(value)
+		if err != nil {
+			return false, err
+		}
+		req.Filter.End = end
+	case "conversation":
+		req.Filter.Conversations = append(req.Filter.Conversations, value)
+	case "meta":
+		predicate := storage.MetadataPredicate{Op: storage.MetadataExists}
+		name, metaValue, hasValue := strings.Cut(value, "=")
+		predicate.Key = name
+		switch {
+		case hasValue && negated:
+			predicate.Op, predicate.Value = storage.MetadataNotEquals, metaValue
+		case hasValue:
+			predicate.Op, predicate.Value = storage.MetadataEquals, metaValue
+		case negated:
+			predicate.Op = storage.MetadataNotExists
+		}
+		if predicate.Key == "" {
+			return false, fmt.Errorf("%w: metadata key required in %q", ErrInvalidQuery, value)
+		}
+		req.Filter.Metadata = append(req.Filter.Metadata, predicate)
+	case "concept":
+		conceptType, name, ok := strings.Cut(value, "/")
+		if !ok || conceptType == "" || name == "" {
+			return false, fmt.Errorf("%w: concept must be type/name, got %q", ErrInvalidQuery, value)
+		}
+		req.Concepts = append(req.Concepts, ConceptConstraint{Type: conceptType, Name: name})
+	}
+	return true, nil
+}
+
+// addBoost adds a boost for term with the factor parsed from value.
+func addBoost(req *SearchRequest, term, value string, negated bool) error {
+	if negated {
+		return fmt.Errorf("%w: boosted term %q cannot be negated", ErrInvalidQuery, term)
+	}
+	factor, err := strconv.ParseFloat(value, 32)
+	if err != nil || factor <= 0 {
+		return fmt.Errorf("%w: boost must be a positive number, got %q", ErrInvalidQuery, value)
+	}
+	req.Boosts = append(req.Boosts, Boost{Term: term, Factor: float32(factor)})
+	return nil
+}
+
+// isBoostNumber reports whether value, the text after a '^', is a finite
+// number and so makes the preceding term a boost.
+func isBoostNumber(value string) bool {
+	factor, err := strconv.ParseFloat(value, 32)
+	return err == nil && !math.IsInf(factor, 0) && !math.IsNaN(factor)
+}
+
+// parseSpeaker parses a speaker name.
+func parseSpeaker(value string) (core.SpeakerType, error) {
+	switch strings.ToLower(value) {
+	case "human", "user":
+		return core.SpeakerTypeHuman, nil
+	case "ai", "assistant":
+		return core.SpeakerTypeAI, nil
+	default:
+		return 0, fmt.Errorf("%w: unknown speaker %q", ErrInvalidQuery, value)
+	}
+}
+
+// parseQueryTime parses a date (in UTC) or an RFC 3339 timestamp.
+func parseQueryTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.DateOnly, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("%w: invalid date %q", ErrInvalidQuery, value)
+}
+
+// constrained reports whether the request restricts results after retrieval.
+func (r *SearchRequest) constrained() bool {
+	return len(r.Phrases) > 0 || len(r.Exclude) > 0 || len(r.Concepts) > 0
+}
+
+// admits reports whether a record satisfies the request's phrase, exclusion
+// and concept constraints.
+func (r *SearchRequest) admits(record *core.ChatRecord) bool {
+	for _, phrase := range r.Phrases {
+		if !containsTerm(record.Contents, phrase) {
+			return false
+		}
+	}
+	for _, excluded := range r.Exclude {
+		if containsTerm(record.Contents, excluded) {
+			return false
+		}
+	}
+	for _, concept := range r.Concepts {
+		id := core.IDFromContent(concept.Tuple())
+		if !slices.ContainsFunc(record.Concepts, func(ref core.ConceptRef) bool { return ref.ConceptId == id }) {
+			return false
+		}
+	}
+	return true
+}
+
+// boostFactor returns the product of the factors of the boosts whose term
+// appears in the record's contents and the time boosts whose range contains
+// its timestamp.
+func (r *SearchRequest) boostFactor(record *core.ChatRecord) float32 {
+	factor := float32(1)
+	for _, boost := range r.Boosts {
+		if containsTerm(record.Contents, boost.Term) {
+			factor *= boost.Factor
+		}
+	}
+	for _, boost := range r.TimeBoosts {
+		if boost.contains(record.Timestamp) {
+			factor *= boost.Factor
+		}
+	}
+	return factor
+}
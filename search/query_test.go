@@ -0,0 +1,98 @@
+package search
+
+import (
+	"testing"
+	"time"
+
+	"github.com/poiesic/memorit/core"
+	"github.com/poiesic/memorit/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseQuery(t *testing.T) {
+	t.Run("full example", func(t *testing.T) {
+		req, err := ParseQuery(`kubernetes speaker:human after:2025-03-01 -"helm chart" concept:software/terraform`)
+		require.NoError(t, err)
+		assert.Equal(t, "kubernetes", req.Query)
+		assert.Equal(t, []string{"helm chart"}, req.Exclude)
+		assert.Equal(t, []ConceptConstraint{{Type: "software", Name: "terraform"}}, req.Concepts)
+		assert.Equal(t, []core.SpeakerType{core.SpeakerTypeHuman}, req.Filter.Speakers)
+		assert.Equal(t, time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC), req.Filter.Start)
+		assert.True(t, req.Filter.End.IsZero())
+	})
+
+	tests := []struct {
+		name     string
+		input    string
+		expected SearchRequest
+	}{
+		{"free text", "  what did I   say ", SearchRequest{Query: "what did I say"}},
+		{"phrase", `deploy "blue green" today`, SearchRequest{Query: "deploy blue green today", Phrases: []string{"blue green"}}},
+		{"excluded word", "pets -cats", SearchRequest{Query: "pets", Exclude: []string{"cats"}}},
+		{"lone dash is text", "a - b", SearchRequest{Query: "a - b"}},
+		{"boosted word", "dogs^2 cats", SearchRequest{Query: "dogs cats", Boosts: []Boost{{Term: "dogs", Factor: 2}}}},
+		{"boosted phrase", `"golden retriever"^1.5`, SearchRequest{Query: "golden retriever", Boosts: []Boost{{Term: "golden retriever", Factor: 1.5}}}},
+		{"caret without number is text", "x^y -dogs^x c++^", SearchRequest{Query: "x^y c++^", Exclude: []string{"dogs^x"}}},
+		{"phrase caret without number is text", `"golden retriever"^y`, SearchRequest{Query: "golden retriever ^y", Phrases: []string{"golden retriever"}}},
+		{"infinite boost is text", "dogs^inf", SearchRequest{Query: "dogs^inf"}},
+		{"speakers", "speaker:AI speaker:user", SearchRequest{Filter: storage.RecordFilter{Speakers: []core.SpeakerType{core.SpeakerTypeAI, core.SpeakerTypeHuman}}}},
+		{"before timestamp", "before:2025-03-01T12:00:00Z", SearchRequest{Filter: storage.RecordFilter{End: time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)}}},
+		{"conversation", "conversation:abc", SearchRequest{Filter: storage.RecordFilter{Conversations: []string{"abc"}}}},
+		{"metadata", `meta:topic="home lab" -meta:draft=true meta:pinned -meta:archived`, SearchRequest{Filter: storage.RecordFilter{Metadata: []storage.MetadataPredicate{
+			{Key: "topic", Op: storage.MetadataEquals, Value: "home lab"},
+			{Key: "draft", Op: storage.MetadataNotEquals, Value: "true"},
+			{Key: "pinned", Op: storage.MetadataExists},
+			{Key: "archived", Op: storage.MetadataNotExists},
+		}}}},
+		{"unknown field is text", "http://example.com note:", SearchRequest{Query: "http://example.com note:"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := ParseQuery(tt.input)
+			require.NoError(t, err)
+			assert.Equal(t, &tt.expected, req)
+		})
+	}
+
+	invalid := []struct {
+		name  string
+		input string
+	}{
+		{"unterminated phrase", `say "hello`},
+		{"unterminated value", `meta:topic="home`},
+		{"unknown speaker", "speaker:robot"},
+		{"invalid date", "after:March"},
+		{"concept without name", "concept:software"},
+		{"metadata without key", "meta:=x"},
+		{"negated field", "-speaker:human"},
+		{"negated boost", "-dogs^2"},
+		{"zero boost", "dogs^0"},
+		{"negative boost", `"golden retriever"^-1`},
+	}
+	for _, tt := range invalid {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseQuery(tt.input)
+			assert.ErrorIs(t, err, ErrInvalidQuery)
+		})
+	}
+}
+
+func TestSearchRequest_Admits(t *testing.T) {
+	terraform := ConceptConstraint{Type: "software", Name: "terraform"}
+	record := &core.ChatRecord{
+		Contents: "We replaced the Helm chart with Terraform, mostly.",
+		Concepts: []core.ConceptRef{{ConceptId: core.IDFromContent(terraform.Tuple()), Importance: 5}},
+	}
+
+	assert.True(t, (&SearchRequest{Phrases: []string{"helm chart"}}).admits(record))
+	assert.True(t, (&SearchRequest{Phrases: []string{"terraform"}}).admits(record))
+	assert.False(t, (&SearchRequest{Phrases: []string{"chart helm"}}).admits(record))
+	assert.False(t, (&SearchRequest{Exclude: []string{"helm chart"}}).admits(record))
+	assert.True(t, (&SearchRequest{Exclude: []string{"helmet"}}).admits(record))
+	assert.True(t, (&SearchRequest{Concepts: []ConceptConstraint{terraform}}).admits(record))
+	assert.False(t, (&SearchRequest{Concepts: []ConceptConstraint{{Type: "software", Name: "helm"}}}).admits(record))
+
+	req := &SearchRequest{Boosts: []Boost{{Term: "terraform", Factor: 2}, {Term: "helm", Factor: 1.5}, {Term: "pulumi", Factor: 3}}}
+	assert.Equal(t, float32(3), req.boostFactor(record))
+}
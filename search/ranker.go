@@ -0,0 +1,155 @@
+// Copyright 2025 Poiesic Systems
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+
+package search
+
+import (
+	"time"
+
+	"github.com/poiesic/memorit/core"
+)
+
+// Default ranking constants.
+const (
+	DefaultMinSimilarity    float32 = 0.60
+	DefaultHybridBoost      float32 = 1.5
+	DefaultConceptOnlyScore float32 = 1.2
+	DefaultVerbatimBoost    float32 = 0.3
+)
+
+// ConceptMatch is a query concept found on a record.
+type ConceptMatch struct {
+	Concept *core.Concept
+	// Importance is the record's importance score for the concept (1-10).
+	Importance int
+	// Similarity is how closely the stored concept matches a query concept:
+	// 1 for an exact match, the cosine similarity for an expanded match.
+	Similarity float32
+}
+
+// Signals are the per-record relevance signals gathered during a search.
+// They are passed to a Ranker to compute the record's score.
+type Signals struct {
+	Record *core.ChatRecord
+
+	// Semantic is true if the record was returned by vector search.
+	Semantic bool
+	// Similarity is the cosine similarity to the query; zero if not Semantic.
+	Similarity float32
+
+	// Conceptual is true if the record shares at least one concept with the query.
+	Conceptual bool
+	// MatchedConcepts are the query concepts found on the record.
+	MatchedConcepts []ConceptMatch
+
+	// KeywordMatches are the query words (stop words removed) found in the record.
+	KeywordMatches []string
+	// QueryWords is the number of query words after stop-word filtering.
+	QueryWords int
+	// Verbatim is true if every query word appears in the record.
+	Verbatim bool
+
+	// Age is the time between the record's timestamp and the search.
+	Age time.Duration
+
+	// ConceptScore is the sum of importance times similarity over the matched concepts.
+	ConceptScore float32
+	// KeywordScore is the fraction of query words found in the record.
+	KeywordScore float32
+
+	// SemanticRank, ConceptRank and KeywordRank are the record's 1-based
+	// positions in each stage's ranking by score, or zero if the stage
+	// did not return the record.
+	SemanticRank int
+	ConceptRank  int
+	KeywordRank  int
+}
+
+// Ranker scores a record from its relevance signals. Higher scores rank first.
+// Implementations must be safe for concurrent use.
+type Ranker interface {
+	Score(signals *Signals) float32
+}
+
+// RankerFunc adapts an ordinary function to the Ranker interface.
+type RankerFunc func(signals *Signals) float32
+
+// Score calls f(signals).
+func (f RankerFunc) Score(signals *Signals) float32 {
+	return f(signals)
+}
+
+// Weights are the constants used by the weighted ranker.
+type Weights struct {
+	// HybridBoost multiplies the similarity of records found by both
+	// semantic and conceptual search.
+	HybridBoost float32
+	// ConceptOnlyScore is the flat score of records found only by conceptual search.
+	ConceptOnlyScore float32
+	// VerbatimBoost is added when every query word appears in the record.
+	VerbatimBoost float32
+}
+
+// DefaultWeights returns the default ranking weights.
+func DefaultWeights() Weights {
+	return Weights{
+		HybridBoost:      DefaultHybridBoost,
+		ConceptOnlyScore: DefaultConceptOnlyScore,
+		VerbatimBoost:    DefaultVerbatimBoost,
+	}
+}
+
+// NewWeightedRanker returns the default Ranker.
+// Hybrid hits score HybridBoost * similarity, concept-only hits score
+// ConceptOnlyScore, semantic-only hits score their similarity, and verbatim
+// matches get VerbatimBoost added. When concepts were matched by expansion
+// rather than exactly, the concept boosts are scaled by the best concept similarity.
+func NewWeightedRanker(weights Weights) Ranker {
+	return weightedRanker{weights: weights}
+}
+
+type weightedRanker struct {
+	weights Weights
+}
+
+func (r weightedRanker) Score(signals *Signals) float32 {
+	var score float32
+	switch {
+	case signals.Semantic && signals.Conceptual:
+		boost := 1 + (r.weights.HybridBoost-1)*conceptStrength(signals)
+		score = boost * signals.Similarity
+	case signals.Conceptual:
+		score = r.weights.ConceptOnlyScore * conceptStrength(signals)
+	default:
+		score = signals.Similarity
+	}
+	if signals.Verbatim {
+		score += r.weights.VerbatimBoost
+	}
+	return score
+}
+
+// conceptStrength returns the best similarity among the matched concepts.
+// Signals without match details are treated as exact matches.
+func conceptStrength(signals *Signals) float32 {
+	if len(signals.MatchedConcepts) == 0 {
+		return 1
+	}
+	var best float32
+	for _, match := range signals.MatchedConcepts {
+		best = max(best, match.Similarity)
+	}
+	return best
+}
@@ -0,0 +1,42 @@
+package search
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWeightedRanker(t *testing.T) {
+	ranker := NewWeightedRanker(DefaultWeights())
+
+	tests := []struct {
+		name     string
+		signals  Signals
+		expected float32
+	}{
+		{"semantic only", Signals{Semantic: true, Similarity: 0.8}, 0.8},
+		{"conceptual only", Signals{Conceptual: true}, 1.2},
+		{"hybrid", Signals{Semantic: true, Conceptual: true, Similarity: 0.8}, 1.2},
+		{"semantic verbatim", Signals{Semantic: true, Similarity: 0.8, Verbatim: true}, 1.1},
+		{"conceptual verbatim", Signals{Conceptual: true, Verbatim: true}, 1.5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.InDelta(t, tt.expected, ranker.Score(&tt.signals), 0.0001)
+		})
+	}
+
+	t.Run("custom weights", func(t *testing.T) {
+		ranker := NewWeightedRanker(Weights{HybridBoost: 2, ConceptOnlyScore: 0.5, VerbatimBoost: 1})
+		assert.InDelta(t, 1.6, ranker.Score(&Signals{Semantic: true, Conceptual: true, Similarity: 0.8}), 0.0001)
+		assert.InDelta(t, 1.5, ranker.Score(&Signals{Conceptual: true, Verbatim: true}), 0.0001)
+	})
+}
+
+func TestRankerFunc(t *testing.T) {
+	ranker := RankerFunc(func(signals *Signals) float32 {
+		return float32(len(signals.KeywordMatches))
+	})
+	assert.Equal(t, float32(2), ranker.Score(&Signals{KeywordMatches: []string{"a", "b"}}))
+}
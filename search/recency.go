@@ -0,0 +1,107 @@
+// Copyright 2025 Poiesic Systems
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+
+package search
+
+import (
+	"math"
+	"time"
+)
+
+// DecayMode selects how the recency factor falls off with a record's age.
+type DecayMode int
+
+const (
+	// DecayExponential halves the recency factor every HalfLife.
+	DecayExponential DecayMode = iota
+	// DecayLinear lowers the recency factor linearly from 1 to 0 over Window.
+	DecayLinear
+)
+
+// String returns the name of the decay mode.
+func (m DecayMode) String() string {
+	switch m {
+	case DecayExponential:
+		return "exponential"
+	case DecayLinear:
+		return "linear"
+	default:
+		return "unknown"
+	}
+}
+
+// Recency configures time decay in scoring. Each result's score is scaled by
+//
+//	(1 - Weight) + Weight * factor
+//
+// where factor is 1 for a record at the reference time and decays toward 0
+// with age. A Weight of 0 ignores age; a Weight of 1 scores by relevance times factor.
+type Recency struct {
+	Mode     DecayMode
+	HalfLife time.Duration // Age at which the factor is 0.5 (DecayExponential)
+	Window   time.Duration // Age at which the factor reaches 0 (DecayLinear)
+	Weight   float32       // Share of the score subject to decay, in [0, 1]
+}
+
+// ExponentialDecay returns a Recency that halves the decayed share of the score every halfLife.
+func ExponentialDecay(halfLife time.Duration, weight float32) Recency {
+	return Recency{Mode: DecayExponential, HalfLife: halfLife, Weight: weight}
+}
+
+// LinearDecay returns a Recency that removes the decayed share of the score linearly over window.
+func LinearDecay(window time.Duration, weight float32) Recency {
+	return Recency{Mode: DecayLinear, Window: window, Weight: weight}
+}
+
+// validate checks the configuration for the selected mode.
+func (r Recency) validate() error {
+	if r.Weight < 0 || r.Weight > 1 {
+		return ErrInvalidRecency
+	}
+	switch r.Mode {
+	case DecayExponential:
+		if r.HalfLife <= 0 {
+			return ErrInvalidRecency
+		}
+	case DecayLinear:
+		if r.Window <= 0 {
+			return ErrInvalidRecency
+		}
+	default:
+		return ErrInvalidRecency
+	}
+	return nil
+}
+
+// factor returns the recency factor in [0, 1] for a record of the given age.
+// Records newer than the reference time are treated as age 0.
+func (r Recency) factor(age time.Duration) float32 {
+	if age <= 0 {
+		return 1
+	}
+	switch r.Mode {
+	case DecayExponential:
+		return float32(math.Exp2(-float64(age) / float64(r.HalfLife)))
+	case DecayLinear:
+		return float32(max(0, 1-float64(age)/float64(r.Window)))
+	default:
+		return 1
+	}
+}
+
+// apply scales a relevance score by the recency factor.
+func (r Recency) apply(score, factor float32) float32 {
+	return score * ((1 - r.Weight) + r.Weight*factor)
+}
@@ -0,0 +1,40 @@
+package search
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecency_Factor(t *testing.T) {
+	day := 24 * time.Hour
+
+	exponential := ExponentialDecay(7*day, 1)
+	assert.Equal(t, float32(1), exponential.factor(0))
+	assert.Equal(t, float32(1), exponential.factor(-day))
+	assert.InDelta(t, 0.5, exponential.factor(7*day), 0.0001)
+	assert.InDelta(t, 0.25, exponential.factor(14*day), 0.0001)
+
+	linear := LinearDecay(10*day, 1)
+	assert.InDelta(t, 0.7, linear.factor(3*day), 0.0001)
+	assert.Equal(t, float32(0), linear.factor(10*day))
+	assert.Equal(t, float32(0), linear.factor(100*day))
+}
+
+func TestRecency_Apply(t *testing.T) {
+	recency := ExponentialDecay(time.Hour, 0.25)
+	assert.InDelta(t, 2.0, recency.apply(2, 1), 0.0001)
+	assert.InDelta(t, 1.5, recency.apply(2, 0), 0.0001)
+	assert.InDelta(t, 1.75, recency.apply(2, 0.5), 0.0001)
+}
+
+func TestRecency_Validate(t *testing.T) {
+	assert.NoError(t, ExponentialDecay(time.Hour, 0.5).validate())
+	assert.NoError(t, LinearDecay(time.Hour, 1).validate())
+	assert.Equal(t, ErrInvalidRecency, ExponentialDecay(0, 0.5).validate())
+	assert.Equal(t, ErrInvalidRecency, LinearDecay(-time.Hour, 0.5).validate())
+	assert.Equal(t, ErrInvalidRecency, ExponentialDecay(time.Hour, 1.5).validate())
+	assert.Equal(t, ErrInvalidRecency, ExponentialDecay(time.Hour, -0.1).validate())
+	assert.Equal(t, ErrInvalidRecency, Recency{Mode: DecayMode(9), HalfLife: time.Hour}.validate())
+}
@@ -0,0 +1,139 @@
+// Copyright 2025 Poiesic Systems
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+
+package search
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/poiesic/memorit/core"
+	"github.com/poiesic/memorit/storage"
+)
+
+// DefaultRelatedHits is the number of results FindRelated returns when RelatedOptions.MaxHits is zero.
+const DefaultRelatedHits = 10
+
+// RelatedOptions configures FindRelated. The zero value returns up to
+// DefaultRelatedHits results from any conversation.
+type RelatedOptions struct {
+	// MaxHits is the maximum number of results to return.
+	MaxHits int
+	// ExcludeConversation leaves out records from the source record's conversation.
+	ExcludeConversation bool
+	// Filter restricts which records can be returned, as in SearchRequest.
+	Filter storage.RecordFilter
+	// ReferenceTime is the time record ages are measured from for time decay.
+	// The zero value uses the current time.
+	ReferenceTime time.Time
+	// Fusion selects how the retrieval stages are combined.
+	Fusion FusionMode
+	// Explain fills in each result's score breakdown, as in SearchRequest.
+	Explain bool
+	// Monitor receives callbacks at each stage of the search. May be nil.
+	Monitor SearchMonitor
+}
+
+// FindRelated finds records related to a stored record. It searches with the
+// record's stored vector and concepts, so it makes no embedding or concept
+// extraction calls; the record's contents stand in for the query text when
+// matching keywords and reranking. The record itself is never returned.
+// Returns storage.ErrNotFound if the record doesn't exist. If the record has
+// no vector yet, the semantic stage is skipped.
+func (s *Searcher) FindRelated(ctx context.Context, id core.ID, opts *RelatedOptions) (*SearchResponse, error) {
+	if opts == nil {
+		opts = &RelatedOptions{}
+	}
+	if opts.Fusion < FusionRanker || opts.Fusion > FusionWeightedSum {
+		return nil, ErrInvalidFusion
+	}
+
+	record, err := s.chatRepository.GetChatRecord(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	req := &SearchRequest{
+		Query:         record.Contents,
+		MaxHits:       opts.MaxHits,
+		Filter:        opts.Filter,
+		ReferenceTime: opts.ReferenceTime,
+		Fusion:        opts.Fusion,
+		Explain:       opts.Explain,
+		Monitor:       opts.Monitor,
+	}
+	if req.MaxHits == 0 {
+		req.MaxHits = DefaultRelatedHits
+	}
+	req.Filter.ExcludeIDs = append(slices.Clone(opts.Filter.ExcludeIDs), record.Id)
+	if conversation := record.Metadata[core.MetadataConversation]; opts.ExcludeConversation && conversation != "" {
+		req.Filter.Metadata = append(slices.Clone(opts.Filter.Metadata), storage.MetadataPredicate{
+			Key:   core.MetadataConversation,
+			Op:    storage.MetadataNotEquals,
+			Value: conversation,
+		})
+	}
+
+	monitor := searchMonitor(req)
+	monitor.Start(req.Query)
+
+	var skipped []SkippedStage
+	semantic := &semanticHits{set: make(map[uint64]bool), scores: make(map[uint64]float32)}
+	if len(record.Vector) > 0 {
+		semantic, err = s.searchVector(ctx, record.Vector, s.candidateLimit(req), monitor, &req.Filter)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		skipped = append(skipped, SkippedStage{Stage: StageSemantic, Err: ErrRecordNotEmbedded})
+	}
+
+	conceptual, err := s.conceptRecords(ctx, s.storedConcepts(ctx, record), monitor)
+	if err != nil {
+		return nil, err
+	}
+	return s.finish(ctx, req, monitor, semantic, conceptual, skipped)
+}
+
+// storedConcepts resolves a record's concept references to query concepts.
+// With concept expansion enabled, each concept is expanded using its stored vector.
+func (s *Searcher) storedConcepts(ctx context.Context, record *core.ChatRecord) []queryConcept {
+	queryConcepts := make([]queryConcept, 0, len(record.Concepts))
+	for _, ref := range record.Concepts {
+		concept, err := s.conceptRepository.GetConcept(ctx, ref.ConceptId)
+		if err != nil {
+			s.logger.Warn("error looking up record concept", "recordID", record.Id, "conceptID", ref.ConceptId, "err", err)
+			continue
+		}
+		qc := queryConcept{
+			tuple:   concept.Tuple(),
+			related: []*core.ConceptSearchResult{{Concept: concept, Score: 1}},
+		}
+		if s.conceptLimit > 0 && len(concept.Vector) > 0 {
+			similar, err := s.conceptRepository.FindSimilarConcepts(ctx, concept.Vector, s.conceptSimilarity, s.conceptLimit)
+			if err != nil {
+				s.logger.Warn("error finding similar concepts", "tuple", qc.tuple, "err", err)
+			}
+			for _, match := range similar {
+				if match.Concept.Id != concept.Id {
+					qc.related = append(qc.related, match)
+				}
+			}
+		}
+		queryConcepts = append(queryConcepts, qc)
+	}
+	return queryConcepts
+}
@@ -0,0 +1,83 @@
+// Copyright 2025 Poiesic Systems
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+
+package search
+
+import (
+	"time"
+
+	"github.com/poiesic/memorit/core"
+	"github.com/poiesic/memorit/storage"
+)
+
+// SearchRequest describes a single search.
+type SearchRequest struct {
+	// Query is the natural language search text. ParseQuery builds a
+	// request from a query string with field and phrase syntax.
+	Query string
+	// MaxHits is the maximum number of results to return.
+	MaxHits int
+	// Filter restricts which records can be returned. It is applied during
+	// retrieval, so a filtered search still returns up to MaxHits results.
+	// The zero value matches every record.
+	Filter storage.RecordFilter
+	// Phrases must all appear in a result's contents, ignoring case and punctuation.
+	Phrases []string
+	// Exclude lists words and phrases that must not appear in a result's contents.
+	Exclude []string
+	// Concepts must all be linked to a result. They are also looked up by
+	// conceptual retrieval alongside the concepts extracted from Query.
+	Concepts []ConceptConstraint
+	// Boosts multiply the retrieval relevance of results containing their
+	// terms. A reranker's scores replace boosted relevance.
+	Boosts []Boost
+	// TimeBoosts multiply the retrieval relevance of results whose
+	// timestamps fall in their ranges. Records in range are also retrieved
+	// by a semantic search restricted to the ranges, so they are candidates
+	// even when records outside them are more similar.
+	TimeBoosts []TimeBoost
+	// ReferenceTime is the time record ages are measured from for time
+	// decay, and relative time expressions are resolved against.
+	// The zero value uses the current time.
+	ReferenceTime time.Time
+	// Fusion selects how the retrieval stages are combined. The zero value
+	// scores records with the searcher's Ranker.
+	Fusion FusionMode
+	// Explain fills in the signals of each result's score breakdown and a
+	// human-readable formula showing how its score was computed.
+	Explain bool
+	// Monitor receives callbacks at each stage of the search. May be nil.
+	Monitor SearchMonitor
+
+	// analysis is the query's embedding and concepts, computed once by a
+	// MultiSearcher and shared by every source searcher.
+	analysis *queryAnalysis
+}
+
+// SearchResponse is the result of a search.
+type SearchResponse struct {
+	// Results are ranked by score, highest first, unless the searcher
+	// re-ranks them with maximal marginal relevance.
+	Results []*core.SearchResult
+	// Passages group Results with their neighboring messages, highest
+	// score first. Only set when the searcher has a context window.
+	Passages []*Passage
+	// Skipped lists the stages that failed and were left out, in the order
+	// they were skipped. Empty when every stage succeeded.
+	Skipped []SkippedStage
+	// TimeExpressions are the time expressions recognized in the query and
+	// removed from its text, when the searcher applies them.
+	TimeExpressions []TimeExpression
+}
@@ -0,0 +1,110 @@
+// Copyright 2025 Poiesic Systems
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+
+package search
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/poiesic/memorit/core"
+)
+
+// DefaultRerankPoolSize is the default number of top candidates sent to the reranker.
+const DefaultRerankPoolSize = 20
+
+// Reranker rescores the best candidates after retrieval, typically with a
+// slower but more precise model. Implementations must be safe for concurrent use.
+type Reranker interface {
+	// Rerank returns a relevance score for each record, in the same order.
+	// Higher scores rank first.
+	Rerank(ctx context.Context, query string, records []*core.ChatRecord) ([]float32, error)
+}
+
+// RerankerFunc adapts an ordinary function to the Reranker interface.
+type RerankerFunc func(ctx context.Context, query string, records []*core.ChatRecord) ([]float32, error)
+
+// Rerank calls f(ctx, query, records).
+func (f RerankerFunc) Rerank(ctx context.Context, query string, records []*core.ChatRecord) ([]float32, error) {
+	return f(ctx, query, records)
+}
+
+// rerank replaces the relevance of the top candidates with reranker scores.
+// Results must be sorted by score descending. The reranked pool is placed
+// first, followed by the remaining results in their original order, scaled
+// so that none scores above the pool: reranker and retrieval scores are on
+// different scales, and later stages compare them.
+func (s *Searcher) rerank(ctx context.Context, query string, results []*core.SearchResult) ([]*core.SearchResult, error) {
+	pool := results[:min(len(results), s.rerankPoolSize)]
+	if len(pool) == 0 {
+		return results, nil
+	}
+
+	ctx, cancel := withStageTimeout(ctx, s.rerankTimeout)
+	defer cancel()
+
+	records := make([]*core.ChatRecord, len(pool))
+	for i, result := range pool {
+		records[i] = result.Record
+	}
+	scores, err := s.reranker.Rerank(ctx, query, records)
+	if err != nil {
+		return nil, err
+	}
+	if len(scores) != len(pool) {
+		return nil, fmt.Errorf("reranker result mismatch. expected %d, received %d", len(pool), len(scores))
+	}
+
+	reranked := make([]*core.SearchResult, len(pool), len(results))
+	for i, result := range pool {
+		breakdown := *result.Breakdown
+		breakdown.Relevance = scores[i]
+		breakdown.Reranked = true
+		explainRerank(&breakdown, result.Breakdown.Relevance)
+		reranked[i] = &core.SearchResult{
+			Record:    result.Record,
+			Score:     s.decay(&breakdown),
+			Span:      result.Span,
+			Breakdown: &breakdown,
+		}
+	}
+	sortResults(reranked)
+
+	tail := results[len(pool):]
+	if len(tail) == 0 {
+		return reranked, nil
+	}
+	floor := reranked[len(reranked)-1].Score
+	if top := tail[0].Score; top > floor {
+		factor := float32(0)
+		if floor > 0 {
+			factor = floor / top
+		}
+		for _, result := range tail {
+			breakdown := *result.Breakdown
+			breakdown.Relevance *= factor
+			explainRerankTail(&breakdown, factor)
+			result = &core.SearchResult{
+				Record:    result.Record,
+				Score:     s.decay(&breakdown),
+				Span:      result.Span,
+				Breakdown: &breakdown,
+			}
+			reranked = append(reranked, result)
+		}
+		return reranked, nil
+	}
+	return append(reranked, tail...), nil
+}
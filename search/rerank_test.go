@@ -0,0 +1,129 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/poiesic/memorit/ai/mock"
+	"github.com/poiesic/memorit/ai/openai"
+	"github.com/poiesic/memorit/core"
+	"github.com/poiesic/memorit/storage/badger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var _ Reranker = (*openai.Reranker)(nil)
+
+func TestSearch_Rerank(t *testing.T) {
+	chatRepo, conceptRepo, backend, err := badger.NewMemoryRepositories()
+	require.NoError(t, err)
+	defer func() {
+		conceptRepo.Close()
+		chatRepo.Close()
+		backend.Close()
+	}()
+
+	ctx := context.Background()
+	now := time.Now().UTC()
+	records := []*core.ChatRecord{
+		{Speaker: core.SpeakerTypeHuman, Contents: "first", Timestamp: now, Vector: []float32{1.0, 0.0, 0.0}},
+		{Speaker: core.SpeakerTypeHuman, Contents: "second", Timestamp: now, Vector: []float32{0.9, 0.436, 0.0}},
+		{Speaker: core.SpeakerTypeHuman, Contents: "third", Timestamp: now, Vector: []float32{0.8, 0.6, 0.0}},
+	}
+	added, err := chatRepo.AddChatRecords(ctx, records...)
+	require.NoError(t, err)
+
+	mockEmbedder := mock.NewMockEmbedder()
+	mockEmbedder.EmbedTextFunc = func(ctx context.Context, text string) ([]float32, error) {
+		return []float32{1.0, 0.0, 0.0}, nil
+	}
+	mockProvider := mock.NewMockProviderWithServices(mockEmbedder, mock.NewMockConceptExtractor())
+
+	// Prefers later messages
+	reverse := RerankerFunc(func(ctx context.Context, query string, records []*core.ChatRecord) ([]float32, error) {
+		scores := make([]float32, len(records))
+		for i, record := range records {
+			scores[i] = float32(record.Id) / 10
+		}
+		return scores, nil
+	})
+
+	search := func(opts ...Option) []*core.SearchResult {
+		searcher, err := NewSearcher(chatRepo, conceptRepo, mockProvider, opts...)
+		require.NoError(t, err)
+		results, err := searcher.FindSimilar(ctx, "query", 10)
+		require.NoError(t, err)
+		return results
+	}
+
+	t.Run("reorders candidates", func(t *testing.T) {
+		results := search(WithReranker(reverse))
+		assert.Equal(t, []core.ID{added[2].Id, added[1].Id, added[0].Id}, resultIDs(results))
+		assert.InDelta(t, float32(added[2].Id)/10, results[0].Score, 0.0001)
+		assert.True(t, results[0].Breakdown.Reranked)
+	})
+
+	t.Run("pool size", func(t *testing.T) {
+		results := search(WithReranker(reverse), WithRerankPoolSize(2))
+		assert.Equal(t, []core.ID{added[1].Id, added[0].Id, added[2].Id}, resultIDs(results))
+		assert.True(t, results[1].Breakdown.Reranked)
+		assert.False(t, results[2].Breakdown.Reranked)
+		assert.LessOrEqual(t, results[2].Score, results[1].Score, "the tail is scaled below the pool")
+	})
+
+	t.Run("pool stays ahead of diversification", func(t *testing.T) {
+		// A low reranker score is still ahead of the higher retrieval scores of the tail
+		low := RerankerFunc(func(ctx context.Context, query string, records []*core.ChatRecord) ([]float32, error) {
+			scores := make([]float32, len(records))
+			for i := range scores {
+				scores[i] = 0.01
+			}
+			return scores, nil
+		})
+		results := search(WithReranker(low), WithRerankPoolSize(1), WithMMR(0.5), WithDuplicateCollapse(0.999))
+		require.Len(t, results, 3)
+		assert.Equal(t, added[0].Id, results[0].Record.Id)
+		assert.True(t, results[0].Breakdown.Reranked)
+		for _, result := range results[1:] {
+			assert.False(t, result.Breakdown.Reranked)
+			assert.LessOrEqual(t, result.Score, results[0].Score)
+		}
+	})
+
+	t.Run("failure keeps retrieval order", func(t *testing.T) {
+		failing := RerankerFunc(func(ctx context.Context, query string, records []*core.ChatRecord) ([]float32, error) {
+			return nil, errors.New("model unavailable")
+		})
+		results := search(WithReranker(failing))
+		assert.Equal(t, []core.ID{added[0].Id, added[1].Id, added[2].Id}, resultIDs(results))
+		assert.False(t, results[0].Breakdown.Reranked)
+	})
+
+	t.Run("mismatched scores keep retrieval order", func(t *testing.T) {
+		short := RerankerFunc(func(ctx context.Context, query string, records []*core.ChatRecord) ([]float32, error) {
+			return []float32{1}, nil
+		})
+		results := search(WithReranker(short))
+		assert.Equal(t, []core.ID{added[0].Id, added[1].Id, added[2].Id}, resultIDs(results))
+	})
+
+	t.Run("timeout keeps retrieval order", func(t *testing.T) {
+		slow := RerankerFunc(func(ctx context.Context, query string, records []*core.ChatRecord) ([]float32, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		})
+		start := time.Now()
+		results := search(WithReranker(slow), WithRerankTimeout(20*time.Millisecond))
+		assert.Less(t, time.Since(start), time.Second)
+		assert.Equal(t, []core.ID{added[0].Id, added[1].Id, added[2].Id}, resultIDs(results))
+	})
+
+	t.Run("options", func(t *testing.T) {
+		_, err := NewSearcher(chatRepo, conceptRepo, mockProvider, WithRerankPoolSize(0))
+		assert.Equal(t, ErrInvalidRerankPoolSize, err)
+		_, err = NewSearcher(chatRepo, conceptRepo, mockProvider, WithRerankTimeout(-time.Second))
+		assert.Equal(t, ErrInvalidTimeout, err)
+	})
+}
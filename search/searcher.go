@@ -16,10 +16,13 @@
 package search
 
 import (
+	"cmp"
 	"context"
+	"fmt"
 	"log/slog"
 	"maps"
-	"sort"
+	"slices"
+	"time"
 
 	"github.com/poiesic/memorit/ai"
 	"github.com/poiesic/memorit/core"
@@ -33,6 +36,33 @@ type Searcher struct {
 	embedder          ai.Embedder
 	extractor         ai.ConceptExtractor
 	logger            *slog.Logger
+	minSimilarity     float32
+	weights           Weights
+	ranker            Ranker
+	stageWeights      StageWeights
+	rrfConstant       float32
+	recency           *Recency
+	conceptSimilarity float32
+	conceptLimit      int // Related concepts per query concept; 0 disables expansion
+	contextBefore     int
+	contextAfter      int
+	reranker          Reranker
+	rerankPoolSize    int
+	rerankTimeout     time.Duration
+	semanticTimeout   time.Duration
+	conceptTimeout    time.Duration
+	strictStages      bool
+	mmr               bool
+	mmrLambda         float32
+	collapseThreshold float32 // Cosine similarity at which results are collapsed; 0 disables
+	snippetLength     int     // Maximum snippet length in bytes; 0 disables snippets
+	queryEmbeddings   *lru[string, []float32]
+	queryConcepts     *lru[string, []ai.ExtractedConcept]
+	resultCacheSize   int
+	resultCacheTTL    time.Duration
+	results           *resultCache
+	timeMode          TimeMode
+	timeBoost         float32
 }
 
 // Option configures a Searcher.
@@ -50,6 +80,307 @@ func WithLogger(logger *slog.Logger) Option {
 	}
 }
 
+// WithMinSimilarity sets the minimum cosine similarity for semantic hits.
+// Default is 0.60.
+func WithMinSimilarity(threshold float32) Option {
+	return func(s *Searcher) error {
+		if threshold < -1 || threshold > 1 {
+			return ErrInvalidSimilarity
+		}
+		s.minSimilarity = threshold
+		return nil
+	}
+}
+
+// WithHybridBoost sets the multiplier applied to the similarity of records
+// found by both semantic and conceptual search. Default is 1.5.
+func WithHybridBoost(boost float32) Option {
+	return func(s *Searcher) error {
+		if boost < 0 {
+			return ErrInvalidWeight
+		}
+		s.weights.HybridBoost = boost
+		return nil
+	}
+}
+
+// WithConceptOnlyScore sets the score of records found only by conceptual search.
+// Default is 1.2.
+func WithConceptOnlyScore(score float32) Option {
+	return func(s *Searcher) error {
+		if score < 0 {
+			return ErrInvalidWeight
+		}
+		s.weights.ConceptOnlyScore = score
+		return nil
+	}
+}
+
+// WithVerbatimBoost sets the score added when every query word appears in a record.
+// Default is 0.3.
+func WithVerbatimBoost(boost float32) Option {
+	return func(s *Searcher) error {
+		if boost < 0 {
+			return ErrInvalidWeight
+		}
+		s.weights.VerbatimBoost = boost
+		return nil
+	}
+}
+
+// WithRanker replaces the weighted ranker with a custom Ranker.
+// The weight options have no effect when a custom ranker is set.
+func WithRanker(ranker Ranker) Option {
+	return func(s *Searcher) error {
+		s.ranker = ranker
+		return nil
+	}
+}
+
+// WithStageWeights sets the weight of each retrieval stage used by rank fusion.
+// Default weights every stage equally.
+func WithStageWeights(weights StageWeights) Option {
+	return func(s *Searcher) error {
+		if weights.Semantic < 0 || weights.Conceptual < 0 || weights.Keyword < 0 {
+			return ErrInvalidWeight
+		}
+		s.stageWeights = weights
+		return nil
+	}
+}
+
+// WithRRFConstant sets k in reciprocal rank fusion. Larger values flatten
+// the difference between top and lower ranks. Default is 60.
+func WithRRFConstant(k float32) Option {
+	return func(s *Searcher) error {
+		if k <= 0 {
+			return ErrInvalidRRFConstant
+		}
+		s.rrfConstant = k
+		return nil
+	}
+}
+
+// WithRecency enables time decay in scoring so recent records outrank
+// older ones of similar relevance. Disabled by default.
+func WithRecency(recency Recency) Option {
+	return func(s *Searcher) error {
+		if err := recency.validate(); err != nil {
+			return err
+		}
+		s.recency = &recency
+		return nil
+	}
+}
+
+// WithConceptExpansion resolves query concepts by embedding similarity
+// instead of exact name and type. Each query concept matches up to limit
+// stored concepts whose vectors have at least minSimilarity, so "puppy"
+// can reach records tagged (animal,dog). Disabled by default.
+func WithConceptExpansion(minSimilarity float32, limit int) Option {
+	return func(s *Searcher) error {
+		if minSimilarity < -1 || minSimilarity > 1 {
+			return ErrInvalidSimilarity
+		}
+		if limit <= 0 {
+			return ErrInvalidConceptLimit
+		}
+		s.conceptSimilarity = minSimilarity
+		s.conceptLimit = limit
+		return nil
+	}
+}
+
+// WithContextWindow returns each hit together with up to before preceding and
+// after following messages, grouped into SearchResponse.Passages. Neighbors come
+// from the hit's conversation when it has one, otherwise from all messages by
+// timestamp. Overlapping windows are merged. Disabled by default.
+func WithContextWindow(before, after int) Option {
+	return func(s *Searcher) error {
+		if before < 0 || after < 0 {
+			return ErrInvalidContextWindow
+		}
+		s.contextBefore = before
+		s.contextAfter = after
+		return nil
+	}
+}
+
+// WithReranker adds a precision stage that rescores the top candidates with
+// reranker. If reranking fails or times out, the retrieval ranking is kept.
+// Disabled by default.
+func WithReranker(reranker Reranker) Option {
+	return func(s *Searcher) error {
+		s.reranker = reranker
+		return nil
+	}
+}
+
+// WithRerankPoolSize sets how many top candidates are sent to the reranker.
+// Default is 20.
+func WithRerankPoolSize(size int) Option {
+	return func(s *Searcher) error {
+		if size <= 0 {
+			return ErrInvalidRerankPoolSize
+		}
+		s.rerankPoolSize = size
+		return nil
+	}
+}
+
+// WithRerankTimeout bounds the time spent reranking, independent of the
+// search context's deadline. Zero means no separate limit, which is the default.
+func WithRerankTimeout(timeout time.Duration) Option {
+	return func(s *Searcher) error {
+		if timeout < 0 {
+			return ErrInvalidTimeout
+		}
+		s.rerankTimeout = timeout
+		return nil
+	}
+}
+
+// WithSemanticTimeout bounds the time spent embedding the query and searching
+// record vectors. Zero means no separate limit, which is the default.
+func WithSemanticTimeout(timeout time.Duration) Option {
+	return func(s *Searcher) error {
+		if timeout < 0 {
+			return ErrInvalidTimeout
+		}
+		s.semanticTimeout = timeout
+		return nil
+	}
+}
+
+// WithConceptTimeout bounds the time spent extracting query concepts and
+// looking up the records that mention them. Zero means no separate limit,
+// which is the default.
+func WithConceptTimeout(timeout time.Duration) Option {
+	return func(s *Searcher) error {
+		if timeout < 0 {
+			return ErrInvalidTimeout
+		}
+		s.conceptTimeout = timeout
+		return nil
+	}
+}
+
+// WithStrictStages makes a search fail when either retrieval stage fails,
+// instead of continuing with the results of the other stage.
+func WithStrictStages() Option {
+	return func(s *Searcher) error {
+		s.strictStages = true
+		return nil
+	}
+}
+
+// WithMMR re-ranks results with maximal marginal relevance so the top hits
+// cover distinct memories. lambda in [0, 1] trades relevance (1) against
+// diversity (0). Disabled by default.
+func WithMMR(lambda float32) Option {
+	return func(s *Searcher) error {
+		if lambda < 0 || lambda > 1 {
+			return ErrInvalidLambda
+		}
+		s.mmr = true
+		s.mmrLambda = lambda
+		return nil
+	}
+}
+
+// WithDuplicateCollapse drops results whose vectors have cosine similarity of
+// at least threshold with a higher-ranked result. threshold must be in (0, 1].
+// Disabled by default.
+func WithDuplicateCollapse(threshold float32) Option {
+	return func(s *Searcher) error {
+		if threshold <= 0 || threshold > 1 {
+			return ErrInvalidSimilarity
+		}
+		s.collapseThreshold = threshold
+		return nil
+	}
+}
+
+// WithSnippets adds a snippet of up to length bytes to each result, showing
+// why it matched: the passage with the most query terms highlighted, or for
+// semantic hits without query terms the sentence most similar to the query,
+// along with the names of matched concepts. Choosing a sentence embeds the
+// candidate sentences, one batch per search. Disabled by default.
+func WithSnippets(length int) Option {
+	return func(s *Searcher) error {
+		if length <= 0 {
+			return ErrInvalidSnippetLength
+		}
+		s.snippetLength = length
+		return nil
+	}
+}
+
+// WithQueryCache caches the embeddings and extracted concepts of up to size
+// recent queries, so repeating a query skips the embedding and concept
+// extraction calls. Failed calls are not cached. Disabled by default.
+func WithQueryCache(size int) Option {
+	return func(s *Searcher) error {
+		if size <= 0 {
+			return ErrInvalidCacheSize
+		}
+		s.queryEmbeddings = newLRU[string, []float32](size)
+		s.queryConcepts = newLRU[string, []ai.ExtractedConcept](size)
+		return nil
+	}
+}
+
+// WithResultCache caches the responses of up to size recent requests.
+// Entries are invalidated when chat records, chunks or concepts are written
+// through the repositories, which must implement storage.ChangeTracker, and
+// expire after ttl if it is positive. Recency scores are as of when a
+// response was cached, so set a ttl when using time decay. Time expressions
+// are part of the key as the ranges they resolved to, so "yesterday" misses
+// the cache once the day changes. Requests with a Monitor and responses with
+// skipped stages are not cached. Disabled by default.
+func WithResultCache(size int, ttl time.Duration) Option {
+	return func(s *Searcher) error {
+		if size <= 0 {
+			return ErrInvalidCacheSize
+		}
+		if ttl < 0 {
+			return ErrInvalidTimeout
+		}
+		s.resultCacheSize = size
+		s.resultCacheTTL = ttl
+		return nil
+	}
+}
+
+// WithTimeExpressions recognizes dates and relative times in queries, such
+// as "last tuesday" or "in march", removes them from the text that is
+// searched, and applies them according to mode: TimeModeFilter restricts
+// results to their time range and TimeModeBoost multiplies the relevance of
+// results in it. Relative times are resolved against
+// SearchRequest.ReferenceTime. See ParseTimeExpressions for the recognized
+// expressions. Disabled by default.
+func WithTimeExpressions(mode TimeMode) Option {
+	return func(s *Searcher) error {
+		if mode < TimeModeIgnore || mode > TimeModeBoost {
+			return ErrInvalidTimeMode
+		}
+		s.timeMode = mode
+		return nil
+	}
+}
+
+// WithTimeBoost sets the relevance multiplier for results in the time range
+// of a query in TimeModeBoost mode. Default is DefaultTimeBoost.
+func WithTimeBoost(factor float32) Option {
+	return func(s *Searcher) error {
+		if factor <= 0 {
+			return ErrInvalidTimeBoost
+		}
+		s.timeBoost = factor
+		return nil
+	}
+}
+
 // NewSearcher creates a new searcher.
 func NewSearcher(
 	chatRepository storage.ChatRepository,
@@ -73,6 +404,12 @@ func NewSearcher(
 		embedder:          provider.Embedder(),
 		extractor:         provider.ConceptExtractor(),
 		logger:            slog.Default(),
+		minSimilarity:     DefaultMinSimilarity,
+		weights:           DefaultWeights(),
+		stageWeights:      DefaultStageWeights(),
+		rrfConstant:       DefaultRRFConstant,
+		rerankPoolSize:    DefaultRerankPoolSize,
+		timeBoost:         DefaultTimeBoost,
 	}
 
 	// Apply options
@@ -82,6 +419,24 @@ func NewSearcher(
 		}
 	}
 
+	if s.ranker == nil {
+		s.ranker = NewWeightedRanker(s.weights)
+	}
+
+	if s.resultCacheSize > 0 {
+		chatTracker, chatOK := chatRepository.(storage.ChangeTracker)
+		conceptTracker, conceptOK := conceptRepository.(storage.ChangeTracker)
+		if !chatOK || !conceptOK {
+			return nil, ErrChangeTrackingRequired
+		}
+		s.results = &resultCache{
+			entries:  newLRU[string, cachedResponse](s.resultCacheSize),
+			ttl:      s.resultCacheTTL,
+			trackers: []storage.ChangeTracker{chatTracker, conceptTracker},
+			now:      time.Now,
+		}
+	}
+
 	return s, nil
 }
 
@@ -95,83 +450,103 @@ func (s *Searcher) FindSimilar(ctx context.Context, query string, maxHits int) (
 // The monitor receives callbacks at each stage of the search process.
 // Returns up to maxHits results, ranked by relevance score.
 func (s *Searcher) FindSimilarWithMonitor(ctx context.Context, query string, maxHits int, monitor SearchMonitor) ([]*core.SearchResult, error) {
-	// Use noop monitor if none provided
-	if monitor == nil {
-		monitor = &noopMonitor{}
+	response, err := s.Search(ctx, &SearchRequest{
+		Query:   query,
+		MaxHits: maxHits,
+		Monitor: monitor,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return response.Results, nil
+}
+
+// Search runs a search described by req.
+// Returns up to req.MaxHits results, ranked according to req.Fusion.
+func (s *Searcher) Search(ctx context.Context, req *SearchRequest) (*SearchResponse, error) {
+	if req.Fusion < FusionRanker || req.Fusion > FusionWeightedSum {
+		return nil, ErrInvalidFusion
+	}
+
+	// Search for the query without its time expressions
+	resolved, expressions := s.resolveTimeExpressions(req)
+
+	// Serve repeated requests from the result cache, unless monitored
+	var key string
+	var version uint64
+	cacheable := s.results != nil && req.Monitor == nil
+	if cacheable {
+		key = resultKey(req.Query, expressions, resolved)
+		if response, ok := s.results.get(key); ok {
+			return response, nil
+		}
+		// Read the version before searching, so a concurrent write leaves the entry stale
+		version = s.results.version()
 	}
+	req = resolved
 
-	monitor.Start(query)
+	monitor := searchMonitor(req)
+	monitor.Start(req.Query)
 
-	// 1. Perform semantic search
-	embedding, err := s.embedder.EmbedText(ctx, query)
+	// Run the semantic and conceptual retrieval stages concurrently
+	semantic, conceptual, skipped, err := s.retrieve(ctx, req, s.candidateLimit(req), monitor)
 	if err != nil {
-		s.logger.Error("error generating embedding for query", "query", query, "err", err)
 		return nil, err
 	}
-
-	// Find similar embeddings - use 0.60 threshold as in original
-	matches, err := s.chatRepository.FindSimilar(ctx, embedding, 0.60, maxHits)
+	response, err := s.finish(ctx, req, monitor, semantic, conceptual, skipped)
 	if err != nil {
-		s.logger.Error("error querying for similar records", "err", err)
 		return nil, err
 	}
+	response.TimeExpressions = expressions
+	if cacheable && len(response.Skipped) == 0 {
+		s.results.put(key, version, response)
+	}
+	return response, nil
+}
 
-	// Track semantic results
-	semanticSet := make(map[uint64]bool)
-	semanticScores := make(map[uint64]float32)
-	semanticIds := make([]uint64, 0, len(matches))
-	for _, match := range matches {
-		semanticSet[uint64(match.Record.Id)] = true
-		semanticScores[uint64(match.Record.Id)] = match.Score
-		semanticIds = append(semanticIds, uint64(match.Record.Id))
+// searchMonitor returns the monitor for a request. Stages report
+// concurrently, so callbacks to the request's monitor are serialized.
+func searchMonitor(req *SearchRequest) stageMonitor {
+	if req.Monitor == nil {
+		return &noopMonitor{}
 	}
-	monitor.AfterSemanticSearch(semanticIds)
+	return &lockedMonitor{monitor: req.Monitor}
+}
 
-	// 2. Extract concepts from query
-	extracted, err := s.extractor.ExtractConcepts(ctx, query)
-	if err != nil {
-		s.logger.Error("error extracting concepts from query", "err", err)
-		return nil, err
+// candidateLimit returns the number of semantic candidates to retrieve, with
+// extra candidates to replace near-duplicates or records failing constraints.
+func (s *Searcher) candidateLimit(req *SearchRequest) int {
+	if s.mmr || s.collapseThreshold > 0 || req.constrained() {
+		return req.MaxHits * diversityOversample
 	}
+	return req.MaxHits
+}
 
-	// Convert to full concepts by computing IDs and looking them up
-	concepts := make([]*core.Concept, 0, len(extracted))
-	for _, ec := range extracted {
-		tuple := "(" + ec.Type + "," + ec.Name + ")"
-		conceptID := core.IDFromContent(tuple)
-		concept, err := s.conceptRepository.GetConcept(ctx, conceptID)
-		if err != nil {
-			s.logger.Warn("error looking up concept", "tuple", tuple, "err", err)
-			continue
-		}
-		if concept == nil {
-			// Concept doesn't exist in database, skip
-			s.logger.Debug("concept not found in database", "tuple", tuple)
-			continue
-		}
-		concepts = append(concepts, concept)
+// finish combines the retrieval stages' hits, then scores, reranks,
+// diversifies and truncates the results.
+func (s *Searcher) finish(ctx context.Context, req *SearchRequest, monitor stageMonitor, semantic *semanticHits, conceptual *conceptHits, skipped []SkippedStage) (*SearchResponse, error) {
+	query := req.Query
+	maxHits := req.MaxHits
+	now := req.ReferenceTime
+	if now.IsZero() {
+		now = time.Now()
 	}
-	monitor.AfterQueryConceptExtraction(concepts)
 
-	// 3. Find messages via exact concept matching
-	conceptualSet := make(map[uint64]bool)
-	for _, concept := range concepts {
-		tuple := concept.Tuple()
-		monitor.FoundRelatedConcepts(tuple, []uint64{uint64(concept.Id)})
+	for _, stage := range skipped {
+		monitor.StageSkipped(stage.Stage, stage.Err)
+	}
+	semanticSet, semanticScores := semantic.set, semantic.scores
+	conceptualSet, conceptMatches := conceptual.set, conceptual.matches
 
-		// Get messages for this concept
-		recordIds, err := s.chatRepository.GetChatRecordsByConcept(ctx, concept.Id)
-		if err != nil {
-			s.logger.Warn("failed to get records for concept", "conceptID", concept.Id, "err", err)
-			continue
-		}
-		for _, recordId := range recordIds {
-			conceptualSet[uint64(recordId)] = true
+	// 1. Filter concept-only hits; semantic hits were filtered during the vector search
+	if !req.Filter.IsZero() {
+		if err := s.filterConceptual(ctx, &req.Filter, conceptualSet, semanticSet); err != nil {
+			return nil, err
 		}
 	}
 	monitor.AfterConceptuallyRelatedSearch(maps.Keys(conceptualSet))
 
-	// 4. Combine and score results
+	// 2. Combine and score results
 	allIds := make(map[uint64]bool)
 	for id := range semanticSet {
 		allIds[id] = true
@@ -181,7 +556,8 @@ func (s *Searcher) FindSimilarWithMonitor(ctx context.Context, query string, max
 	}
 
 	if len(allIds) == 0 {
-		return []*core.SearchResult{}, nil
+		monitor.Finish([]*core.SearchResult{})
+		return &SearchResponse{Results: []*core.SearchResult{}, Skipped: skipped}, nil
 	}
 
 	// Retrieve all records
@@ -197,53 +573,271 @@ func (s *Searcher) FindSimilarWithMonitor(ctx context.Context, query string, max
 	}
 	monitor.AfterRecordRetrieval(records)
 
-	// Score and build results
-	results := make([]*core.SearchResult, 0, len(records))
+	// Gather signals for each record
+	queryWords := tokenizeAndFilter(query)
+	signals := make([]*Signals, 0, len(records))
 
 	for _, record := range records {
-		if record == nil {
+		if record == nil || !req.admits(record) {
 			continue
 		}
 
 		inSemantic := semanticSet[uint64(record.Id)]
 		inConceptual := conceptualSet[uint64(record.Id)]
 
-		var score float32
 		if inSemantic && inConceptual {
-			// In both: boost by 1.5x, weighted by similarity score
-			similarityScore := semanticScores[uint64(record.Id)]
-			score = 1.5 * similarityScore
 			monitor.SemanticAndConceptualHit(record)
 		} else if inConceptual {
-			// Conceptual only: 1.2
-			score = 1.2
 			monitor.ConceptualHit(record)
 		} else {
-			// Semantic only: 1.0, weighted by similarity score
-			similarityScore := semanticScores[uint64(record.Id)]
-			score = 1.0 * similarityScore
 			monitor.SemanticHit(record)
 		}
 
-		// Apply verbatim match boost
-		if containsAllQueryWords(record.Contents, query) {
-			score += 0.3
-		}
-
-		results = append(results, &core.SearchResult{
-			Record: record,
-			Score:  score,
+		keywords := matchedQueryWords(record.Contents, queryWords)
+		signals = append(signals, &Signals{
+			Record:          record,
+			Semantic:        inSemantic,
+			Similarity:      semanticScores[uint64(record.Id)],
+			Conceptual:      inConceptual,
+			MatchedConcepts: matchConcepts(record, conceptMatches[uint64(record.Id)]),
+			KeywordMatches:  keywords,
+			QueryWords:      len(queryWords),
+			Verbatim:        len(queryWords) > 0 && len(keywords) == len(queryWords),
+			Age:             now.Sub(record.Timestamp),
 		})
 	}
+	assignStageRanks(signals)
+
+	// Score and build results
+	scores, err := s.score(req.Fusion, signals)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]*core.SearchResult, len(signals))
+	for i, sig := range signals {
+		results[i] = s.result(sig, scores[i])
+		if req.Explain {
+			s.explainSignals(results[i].Breakdown, sig, req.Fusion)
+		}
+		if factor := req.boostFactor(sig.Record); factor != 1 {
+			s.boost(results[i], factor)
+		}
+	}
 
 	// Sort by score descending
-	sort.Slice(results, func(i, j int) bool {
-		return results[i].Score > results[j].Score
-	})
+	sortResults(results)
+	if s.reranker != nil {
+		reranked, err := s.rerank(ctx, query, results)
+		if err != nil {
+			// The precision stage is optional; keep the retrieval ranking
+			s.logger.Warn("reranking failed, keeping retrieval order", "err", err)
+			skipped = append(skipped, SkippedStage{Stage: StageRerank, Err: err})
+			monitor.StageSkipped(StageRerank, err)
+		} else {
+			results = reranked
+		}
+	}
+	if s.collapseThreshold > 0 {
+		results = collapseDuplicates(results, s.collapseThreshold)
+	}
+	if s.mmr {
+		results = maximalMarginalRelevance(results, s.mmrLambda, maxHits)
+	}
 	if len(results) > maxHits {
 		results = results[:maxHits]
 	}
+	if req.Explain {
+		for _, result := range results {
+			explainScore(result)
+		}
+	}
+	if s.snippetLength > 0 {
+		byID := make(map[core.ID]*Signals, len(signals))
+		for _, sig := range signals {
+			byID[sig.Record.Id] = sig
+		}
+		terms := append(slices.Clone(req.Phrases), queryWords...)
+		s.addSnippets(ctx, results, byID, terms, semantic.vector)
+	}
 	monitor.Finish(results)
 
-	return results, nil
+	response := &SearchResponse{Results: results, Skipped: skipped}
+	if s.contextBefore > 0 || s.contextAfter > 0 {
+		response.Passages, err = s.buildPassages(ctx, results)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return response, nil
+}
+
+// result builds a search result, applying time decay to the relevance score.
+func (s *Searcher) result(sig *Signals, relevance float32) *core.SearchResult {
+	breakdown := &core.ScoreBreakdown{Relevance: relevance, Recency: 1}
+	if s.recency != nil {
+		breakdown.Recency = s.recency.factor(sig.Age)
+		breakdown.RecencyWeight = s.recency.Weight
+	}
+	return &core.SearchResult{
+		Record:    sig.Record,
+		Score:     s.decay(breakdown),
+		Breakdown: breakdown,
+	}
+}
+
+// decay returns the final score for a breakdown's relevance and recency factor.
+func (s *Searcher) decay(breakdown *core.ScoreBreakdown) float32 {
+	if s.recency == nil {
+		return breakdown.Relevance
+	}
+	return s.recency.apply(breakdown.Relevance, breakdown.Recency)
+}
+
+// boost multiplies a result's relevance by factor and recomputes its score.
+func (s *Searcher) boost(result *core.SearchResult, factor float32) {
+	result.Breakdown.Relevance *= factor
+	result.Score = s.decay(result.Breakdown)
+	explainBoost(result.Breakdown, factor)
+}
+
+// sortResults orders results by score descending, breaking ties by record
+// ID so equal scores come back in the same order on every search.
+func sortResults(results []*core.SearchResult) {
+	slices.SortFunc(results, func(a, b *core.SearchResult) int {
+		if c := cmp.Compare(b.Score, a.Score); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Record.Id, b.Record.Id)
+	})
+}
+
+// filterConceptual removes records that fail the filter from the conceptual hits.
+// Semantic hits were already filtered by the vector search.
+func (s *Searcher) filterConceptual(ctx context.Context, filter *storage.RecordFilter, conceptualSet, semanticSet map[uint64]bool) error {
+	ids := make([]core.ID, 0, len(conceptualSet))
+	for id := range conceptualSet {
+		if !semanticSet[id] {
+			ids = append(ids, core.ID(id))
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	records, err := s.chatRepository.GetChatRecords(ctx, ids...)
+	if err != nil {
+		s.logger.Error("error retrieving chat records", "recordCount", len(ids), "err", err)
+		return err
+	}
+	keep := make(map[uint64]bool, len(records))
+	for _, record := range records {
+		if record != nil && filter.Matches(record) {
+			keep[uint64(record.Id)] = true
+		}
+	}
+	for _, id := range ids {
+		if !keep[uint64(id)] {
+			delete(conceptualSet, uint64(id))
+		}
+	}
+	return nil
+}
+
+// score computes the final score of each record using the requested fusion mode.
+func (s *Searcher) score(mode FusionMode, signals []*Signals) ([]float32, error) {
+	switch mode {
+	case FusionRanker:
+		scores := make([]float32, len(signals))
+		for i, sig := range signals {
+			scores[i] = s.ranker.Score(sig)
+		}
+		return scores, nil
+	case FusionRRF:
+		return fuseRRF(signals, s.stageWeights, s.rrfConstant), nil
+	case FusionWeightedSum:
+		return fuseWeightedSum(signals, s.stageWeights), nil
+	default:
+		return nil, ErrInvalidFusion
+	}
+}
+
+// queryConcept is a concept extracted from the query and the stored concepts it resolved to.
+type queryConcept struct {
+	tuple   string
+	related []*core.ConceptSearchResult
+}
+
+// resolveConcepts maps extracted query concepts to stored concepts. Without
+// expansion only exact (type,name) matches are used; with expansion each
+// query concept also matches the stored concepts closest to its embedding.
+func (s *Searcher) resolveConcepts(ctx context.Context, extracted []ai.ExtractedConcept) ([]queryConcept, error) {
+	queryConcepts := make([]queryConcept, len(extracted))
+	for i, ec := range extracted {
+		tuple := "(" + ec.Type + "," + ec.Name + ")"
+		queryConcepts[i].tuple = tuple
+		concept, err := s.conceptRepository.GetConcept(ctx, core.IDFromContent(tuple))
+		if err != nil {
+			s.logger.Warn("error looking up concept", "tuple", tuple, "err", err)
+			continue
+		}
+		if concept == nil {
+			// Concept doesn't exist in database, skip
+			s.logger.Debug("concept not found in database", "tuple", tuple)
+			continue
+		}
+		queryConcepts[i].related = append(queryConcepts[i].related, &core.ConceptSearchResult{Concept: concept, Score: 1})
+	}
+	if s.conceptLimit == 0 || len(extracted) == 0 {
+		return queryConcepts, nil
+	}
+
+	// Stored concept vectors are embeddings of their tuples, so embed the query tuples the same way
+	tuples := make([]string, len(queryConcepts))
+	for i, qc := range queryConcepts {
+		tuples[i] = qc.tuple
+	}
+	embeddings, err := s.embedder.EmbedTexts(ctx, tuples)
+	if err != nil {
+		s.logger.Error("error generating embeddings for query concepts", "err", err)
+		return nil, err
+	}
+	if len(embeddings) != len(tuples) {
+		return nil, fmt.Errorf("query concept embedding result mismatch. expected %d, received %d", len(tuples), len(embeddings))
+	}
+
+	for i := range queryConcepts {
+		qc := &queryConcepts[i]
+		similar, err := s.conceptRepository.FindSimilarConcepts(ctx, embeddings[i], s.conceptSimilarity, s.conceptLimit)
+		if err != nil {
+			s.logger.Warn("error finding similar concepts", "tuple", qc.tuple, "err", err)
+			continue
+		}
+		for _, match := range similar {
+			// The exact match, if any, is already present with similarity 1
+			if len(qc.related) > 0 && qc.related[0].Concept.Id == match.Concept.Id {
+				continue
+			}
+			qc.related = append(qc.related, match)
+		}
+	}
+	return queryConcepts, nil
+}
+
+// matchConcepts pairs the query concepts found on a record with the record's importance for each.
+func matchConcepts(record *core.ChatRecord, concepts []*core.ConceptSearchResult) []ConceptMatch {
+	if len(concepts) == 0 {
+		return nil
+	}
+	matches := make([]ConceptMatch, 0, len(concepts))
+	for _, concept := range concepts {
+		match := ConceptMatch{Concept: concept.Concept, Similarity: concept.Score}
+		for _, ref := range record.Concepts {
+			if ref.ConceptId == concept.Concept.Id {
+				match.Importance = ref.Importance
+				break
+			}
+		}
+		matches = append(matches, match)
+	}
+	return matches
 }
@@ -2,6 +2,7 @@ package search
 
 import (
 	"context"
+	"fmt"
 	"iter"
 	"log/slog"
 	"testing"
@@ -10,6 +11,7 @@ import (
 	"github.com/poiesic/memorit/ai"
 	"github.com/poiesic/memorit/ai/mock"
 	"github.com/poiesic/memorit/core"
+	"github.com/poiesic/memorit/storage"
 	"github.com/poiesic/memorit/storage/badger"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -447,6 +449,7 @@ func TestFindSimilarWithMonitor(t *testing.T) {
 type testMonitor struct {
 	startCalled  bool
 	finishCalled bool
+	skipped      []Stage
 }
 
 func (m *testMonitor) Start(query string) {
@@ -469,6 +472,10 @@ func (m *testMonitor) SemanticHit(record *core.ChatRecord) {}
 
 func (m *testMonitor) ConceptualHit(record *core.ChatRecord) {}
 
+func (m *testMonitor) StageSkipped(stage Stage, err error) {
+	m.skipped = append(m.skipped, stage)
+}
+
 func (m *testMonitor) Finish(results []*core.SearchResult) {
 	m.finishCalled = true
 }
@@ -520,3 +527,1256 @@ func TestFindSimilar_ConceptNotInDatabase(t *testing.T) {
 	require.NoError(t, err)
 	assert.NotEmpty(t, results)
 }
+
+func TestNewSearcher_RankingOptions(t *testing.T) {
+	chatRepo, conceptRepo, backend, err := badger.NewMemoryRepositories()
+	require.NoError(t, err)
+	defer func() {
+		conceptRepo.Close()
+		chatRepo.Close()
+		backend.Close()
+	}()
+
+	provider := mock.NewMockProvider()
+
+	t.Run("valid options", func(t *testing.T) {
+		searcher, err := NewSearcher(chatRepo, conceptRepo, provider,
+			WithMinSimilarity(0.5),
+			WithHybridBoost(2),
+			WithConceptOnlyScore(1),
+			WithVerbatimBoost(0.5),
+		)
+		require.NoError(t, err)
+		assert.Equal(t, float32(0.5), searcher.minSimilarity)
+		assert.Equal(t, Weights{HybridBoost: 2, ConceptOnlyScore: 1, VerbatimBoost: 0.5}, searcher.weights)
+	})
+
+	t.Run("invalid similarity", func(t *testing.T) {
+		_, err := NewSearcher(chatRepo, conceptRepo, provider, WithMinSimilarity(1.5))
+		assert.Equal(t, ErrInvalidSimilarity, err)
+	})
+
+	t.Run("recency option", func(t *testing.T) {
+		searcher, err := NewSearcher(chatRepo, conceptRepo, provider, WithRecency(LinearDecay(time.Hour, 0.5)))
+		require.NoError(t, err)
+		require.NotNil(t, searcher.recency)
+		assert.Equal(t, DecayLinear, searcher.recency.Mode)
+
+		_, err = NewSearcher(chatRepo, conceptRepo, provider, WithRecency(ExponentialDecay(0, 0.5)))
+		assert.Equal(t, ErrInvalidRecency, err)
+	})
+
+	t.Run("concept expansion option", func(t *testing.T) {
+		searcher, err := NewSearcher(chatRepo, conceptRepo, provider, WithConceptExpansion(0.7, 3))
+		require.NoError(t, err)
+		assert.Equal(t, float32(0.7), searcher.conceptSimilarity)
+		assert.Equal(t, 3, searcher.conceptLimit)
+
+		_, err = NewSearcher(chatRepo, conceptRepo, provider, WithConceptExpansion(0.7, 0))
+		assert.Equal(t, ErrInvalidConceptLimit, err)
+		_, err = NewSearcher(chatRepo, conceptRepo, provider, WithConceptExpansion(2, 3))
+		assert.Equal(t, ErrInvalidSimilarity, err)
+	})
+
+	t.Run("context window option", func(t *testing.T) {
+		searcher, err := NewSearcher(chatRepo, conceptRepo, provider, WithContextWindow(2, 1))
+		require.NoError(t, err)
+		assert.Equal(t, 2, searcher.contextBefore)
+		assert.Equal(t, 1, searcher.contextAfter)
+
+		_, err = NewSearcher(chatRepo, conceptRepo, provider, WithContextWindow(-1, 1))
+		assert.Equal(t, ErrInvalidContextWindow, err)
+	})
+
+	t.Run("diversity options", func(t *testing.T) {
+		searcher, err := NewSearcher(chatRepo, conceptRepo, provider, WithMMR(0), WithDuplicateCollapse(0.95))
+		require.NoError(t, err)
+		assert.True(t, searcher.mmr)
+		assert.Equal(t, float32(0), searcher.mmrLambda)
+		assert.Equal(t, float32(0.95), searcher.collapseThreshold)
+
+		_, err = NewSearcher(chatRepo, conceptRepo, provider, WithMMR(1.1))
+		assert.Equal(t, ErrInvalidLambda, err)
+		_, err = NewSearcher(chatRepo, conceptRepo, provider, WithDuplicateCollapse(0))
+		assert.Equal(t, ErrInvalidSimilarity, err)
+	})
+
+	t.Run("fusion options", func(t *testing.T) {
+		searcher, err := NewSearcher(chatRepo, conceptRepo, provider,
+			WithStageWeights(StageWeights{Semantic: 2, Conceptual: 1, Keyword: 0.5}),
+			WithRRFConstant(10),
+		)
+		require.NoError(t, err)
+		assert.Equal(t, StageWeights{Semantic: 2, Conceptual: 1, Keyword: 0.5}, searcher.stageWeights)
+		assert.Equal(t, float32(10), searcher.rrfConstant)
+
+		_, err = NewSearcher(chatRepo, conceptRepo, provider, WithRRFConstant(0))
+		assert.Equal(t, ErrInvalidRRFConstant, err)
+		_, err = NewSearcher(chatRepo, conceptRepo, provider, WithStageWeights(StageWeights{Keyword: -1}))
+		assert.Equal(t, ErrInvalidWeight, err)
+	})
+
+	t.Run("stage timeouts", func(t *testing.T) {
+		searcher, err := NewSearcher(chatRepo, conceptRepo, provider,
+			WithSemanticTimeout(time.Second),
+			WithConceptTimeout(2*time.Second),
+		)
+		require.NoError(t, err)
+		assert.Equal(t, time.Second, searcher.semanticTimeout)
+		assert.Equal(t, 2*time.Second, searcher.conceptTimeout)
+
+		_, err = NewSearcher(chatRepo, conceptRepo, provider, WithSemanticTimeout(-time.Second))
+		assert.Equal(t, ErrInvalidTimeout, err)
+		_, err = NewSearcher(chatRepo, conceptRepo, provider, WithConceptTimeout(-time.Second))
+		assert.Equal(t, ErrInvalidTimeout, err)
+	})
+
+	t.Run("negative weight", func(t *testing.T) {
+		_, err := NewSearcher(chatRepo, conceptRepo, provider, WithHybridBoost(-1))
+		assert.Equal(t, ErrInvalidWeight, err)
+		_, err = NewSearcher(chatRepo, conceptRepo, provider, WithConceptOnlyScore(-1))
+		assert.Equal(t, ErrInvalidWeight, err)
+		_, err = NewSearcher(chatRepo, conceptRepo, provider, WithVerbatimBoost(-1))
+		assert.Equal(t, ErrInvalidWeight, err)
+	})
+}
+
+func TestFindSimilar_CustomRanking(t *testing.T) {
+	chatRepo, conceptRepo, backend, err := badger.NewMemoryRepositories()
+	require.NoError(t, err)
+	defer func() {
+		conceptRepo.Close()
+		chatRepo.Close()
+		backend.Close()
+	}()
+
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	concept := &core.Concept{Name: "machine", Type: "thing", InsertedAt: now, UpdatedAt: now}
+	concept.Id = core.IDFromContent(concept.Tuple())
+	_, err = conceptRepo.AddConcepts(ctx, concept)
+	require.NoError(t, err)
+
+	records := []*core.ChatRecord{
+		{
+			Speaker:   core.SpeakerTypeHuman,
+			Contents:  "Machine learning is fascinating",
+			Timestamp: now.Add(-time.Hour),
+			Vector:    []float32{1.0, 0.0, 0.0},
+			Concepts:  []core.ConceptRef{{ConceptId: concept.Id, Importance: 8}},
+		},
+		{
+			Speaker:   core.SpeakerTypeHuman,
+			Contents:  "Learning to cook",
+			Timestamp: now,
+			Vector:    []float32{0.6, 0.8, 0.0},
+		},
+		{
+			Speaker:   core.SpeakerTypeHuman,
+			Contents:  "The machine in the factory",
+			Timestamp: now,
+			Vector:    []float32{0.0, 0.0, 1.0},
+			Concepts:  []core.ConceptRef{{ConceptId: concept.Id, Importance: 3}},
+		},
+	}
+	added, err := chatRepo.AddChatRecords(ctx, records...)
+	require.NoError(t, err)
+
+	mockEmbedder := mock.NewMockEmbedder()
+	mockEmbedder.EmbedTextFunc = func(ctx context.Context, text string) ([]float32, error) {
+		return []float32{1.0, 0.0, 0.0}, nil
+	}
+	mockExtractor := mock.NewMockConceptExtractor()
+	mockExtractor.ExtractConceptsFunc = func(ctx context.Context, text string) ([]ai.ExtractedConcept, error) {
+		return []ai.ExtractedConcept{{Name: "machine", Type: "thing", Importance: 8}}, nil
+	}
+	mockProvider := mock.NewMockProviderWithServices(mockEmbedder, mockExtractor)
+
+	t.Run("weights", func(t *testing.T) {
+		searcher, err := NewSearcher(chatRepo, conceptRepo, mockProvider,
+			WithMinSimilarity(0.5),
+			WithHybridBoost(2),
+			WithConceptOnlyScore(0.1),
+			WithVerbatimBoost(1),
+		)
+		require.NoError(t, err)
+
+		results, err := searcher.FindSimilar(ctx, "machine learning", 10)
+		require.NoError(t, err)
+		require.Len(t, results, 3)
+
+		scores := make(map[core.ID]float32)
+		for _, result := range results {
+			scores[result.Record.Id] = result.Score
+		}
+		assert.InDelta(t, 3.0, scores[added[0].Id], 0.0001) // 2 * 1.0 + verbatim 1
+		assert.InDelta(t, 0.6, scores[added[1].Id], 0.0001) // semantic only
+		assert.InDelta(t, 0.1, scores[added[2].Id], 0.0001) // concept only
+	})
+
+	t.Run("filters", func(t *testing.T) {
+		searcher, err := NewSearcher(chatRepo, conceptRepo, mockProvider, WithMinSimilarity(0.5))
+		require.NoError(t, err)
+
+		ids := func(response *SearchResponse) []core.ID {
+			out := make([]core.ID, len(response.Results))
+			for i, result := range response.Results {
+				out[i] = result.Record.Id
+			}
+			return out
+		}
+
+		// The excluded best match doesn't take the only slot
+		response, err := searcher.Search(ctx, &SearchRequest{
+			Query:   "cooking",
+			MaxHits: 1,
+			Filter:  storage.RecordFilter{ExcludeIDs: []core.ID{added[0].Id, added[2].Id}, Speakers: []core.SpeakerType{core.SpeakerTypeHuman}},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []core.ID{added[1].Id}, ids(response))
+
+		// Time bounds apply to semantic and conceptual hits
+		response, err = searcher.Search(ctx, &SearchRequest{
+			Query:   "machine learning",
+			MaxHits: 10,
+			Filter:  storage.RecordFilter{End: now.Add(-time.Minute)},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []core.ID{added[0].Id}, ids(response))
+
+		response, err = searcher.Search(ctx, &SearchRequest{
+			Query:   "machine learning",
+			MaxHits: 10,
+			Filter:  storage.RecordFilter{Start: now.Add(-time.Minute)},
+		})
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []core.ID{added[1].Id, added[2].Id}, ids(response))
+
+		response, err = searcher.Search(ctx, &SearchRequest{
+			Query:   "machine learning",
+			MaxHits: 10,
+			Filter:  storage.RecordFilter{Speakers: []core.SpeakerType{core.SpeakerTypeAI}},
+		})
+		require.NoError(t, err)
+		assert.Empty(t, response.Results)
+	})
+
+	t.Run("rank fusion", func(t *testing.T) {
+		searcher, err := NewSearcher(chatRepo, conceptRepo, mockProvider, WithMinSimilarity(0.5))
+		require.NoError(t, err)
+
+		// Concept-only hits outrank semantic hits with the default ranker
+		response, err := searcher.Search(ctx, &SearchRequest{Query: "machine learning", MaxHits: 10})
+		require.NoError(t, err)
+		require.Len(t, response.Results, 3)
+		assert.Equal(t, added[2].Id, response.Results[1].Record.Id)
+
+		// With RRF a record found by two stages beats one found by a single stage
+		response, err = searcher.Search(ctx, &SearchRequest{Query: "machine learning", MaxHits: 10, Fusion: FusionRRF})
+		require.NoError(t, err)
+		require.Len(t, response.Results, 3)
+		assert.Equal(t, added[0].Id, response.Results[0].Record.Id)
+		assert.Equal(t, added[1].Id, response.Results[1].Record.Id)
+		assert.Equal(t, added[2].Id, response.Results[2].Record.Id)
+		assert.InDelta(t, 3.0/61, response.Results[0].Score, 0.00001)
+
+		response, err = searcher.Search(ctx, &SearchRequest{Query: "machine learning", MaxHits: 10, Fusion: FusionWeightedSum})
+		require.NoError(t, err)
+		require.Len(t, response.Results, 3)
+		assert.Equal(t, added[0].Id, response.Results[0].Record.Id)
+		assert.InDelta(t, 3.0, response.Results[0].Score, 0.00001)
+
+		_, err = searcher.Search(ctx, &SearchRequest{Query: "machine learning", MaxHits: 10, Fusion: FusionMode(42)})
+		assert.Equal(t, ErrInvalidFusion, err)
+	})
+
+	t.Run("custom ranker receives signals", func(t *testing.T) {
+		signals := make(map[core.ID]Signals)
+		ranker := RankerFunc(func(s *Signals) float32 {
+			signals[s.Record.Id] = *s
+			// Rank by concept importance only
+			var score float32
+			for _, match := range s.MatchedConcepts {
+				score += float32(match.Importance)
+			}
+			return score
+		})
+
+		searcher, err := NewSearcher(chatRepo, conceptRepo, mockProvider, WithMinSimilarity(0.5), WithRanker(ranker))
+		require.NoError(t, err)
+
+		results, err := searcher.FindSimilar(ctx, "machine learning", 10)
+		require.NoError(t, err)
+		require.Len(t, results, 3)
+		assert.Equal(t, added[0].Id, results[0].Record.Id)
+		assert.Equal(t, float32(8), results[0].Score)
+		assert.Equal(t, added[2].Id, results[1].Record.Id)
+
+		first := signals[added[0].Id]
+		assert.True(t, first.Semantic)
+		assert.True(t, first.Conceptual)
+		assert.InDelta(t, 1.0, first.Similarity, 0.0001)
+		require.Len(t, first.MatchedConcepts, 1)
+		assert.Equal(t, concept.Id, first.MatchedConcepts[0].Concept.Id)
+		assert.Equal(t, 8, first.MatchedConcepts[0].Importance)
+		assert.Equal(t, []string{"machine", "learning"}, first.KeywordMatches)
+		assert.Equal(t, 2, first.QueryWords)
+		assert.True(t, first.Verbatim)
+		assert.GreaterOrEqual(t, first.Age, time.Hour)
+
+		second := signals[added[1].Id]
+		assert.True(t, second.Semantic)
+		assert.False(t, second.Conceptual)
+		assert.Equal(t, []string{"learning"}, second.KeywordMatches)
+		assert.False(t, second.Verbatim)
+		assert.Less(t, second.Age, time.Hour)
+	})
+}
+
+func TestSearch_Recency(t *testing.T) {
+	chatRepo, conceptRepo, backend, err := badger.NewMemoryRepositories()
+	require.NoError(t, err)
+	defer func() {
+		conceptRepo.Close()
+		chatRepo.Close()
+		backend.Close()
+	}()
+
+	ctx := context.Background()
+	reference := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+
+	records := []*core.ChatRecord{
+		{
+			Speaker:   core.SpeakerTypeHuman,
+			Contents:  "I started a keto diet",
+			Timestamp: reference.Add(-700 * day),
+			Vector:    []float32{1.0, 0.0, 0.0},
+		},
+		{
+			Speaker:   core.SpeakerTypeHuman,
+			Contents:  "I stopped eating sugar",
+			Timestamp: reference.Add(-2 * day),
+			Vector:    []float32{0.95, 0.31, 0.0},
+		},
+	}
+	added, err := chatRepo.AddChatRecords(ctx, records...)
+	require.NoError(t, err)
+
+	mockEmbedder := mock.NewMockEmbedder()
+	mockEmbedder.EmbedTextFunc = func(ctx context.Context, text string) ([]float32, error) {
+		return []float32{1.0, 0.0, 0.0}, nil
+	}
+	mockProvider := mock.NewMockProviderWithServices(mockEmbedder, mock.NewMockConceptExtractor())
+	req := &SearchRequest{Query: "what did I say about my diet", MaxHits: 10, ReferenceTime: reference}
+
+	t.Run("disabled", func(t *testing.T) {
+		searcher, err := NewSearcher(chatRepo, conceptRepo, mockProvider)
+		require.NoError(t, err)
+
+		response, err := searcher.Search(ctx, req)
+		require.NoError(t, err)
+		require.Len(t, response.Results, 2)
+		assert.Equal(t, added[0].Id, response.Results[0].Record.Id)
+
+		breakdown := response.Results[0].Breakdown
+		require.NotNil(t, breakdown)
+		assert.Equal(t, response.Results[0].Score, breakdown.Relevance)
+		assert.Equal(t, float32(1), breakdown.Recency)
+		assert.Equal(t, float32(0), breakdown.RecencyWeight)
+	})
+
+	t.Run("exponential decay favors recent records", func(t *testing.T) {
+		searcher, err := NewSearcher(chatRepo, conceptRepo, mockProvider, WithRecency(ExponentialDecay(7*day, 0.5)))
+		require.NoError(t, err)
+
+		response, err := searcher.Search(ctx, req)
+		require.NoError(t, err)
+		require.Len(t, response.Results, 2)
+		assert.Equal(t, added[1].Id, response.Results[0].Record.Id)
+		assert.Equal(t, added[0].Id, response.Results[1].Record.Id)
+
+		recent := response.Results[0].Breakdown
+		require.NotNil(t, recent)
+		assert.InDelta(t, 0.8204, recent.Recency, 0.001) // 2^(-2/7)
+		assert.Equal(t, float32(0.5), recent.RecencyWeight)
+		assert.InDelta(t, recent.Relevance*(0.5+0.5*recent.Recency), response.Results[0].Score, 0.0001)
+
+		stale := response.Results[1].Breakdown
+		require.NotNil(t, stale)
+		assert.InDelta(t, 0, stale.Recency, 0.0001)
+		assert.InDelta(t, stale.Relevance*0.5, response.Results[1].Score, 0.0001)
+	})
+
+	t.Run("linear decay", func(t *testing.T) {
+		searcher, err := NewSearcher(chatRepo, conceptRepo, mockProvider, WithRecency(LinearDecay(10*day, 1)))
+		require.NoError(t, err)
+
+		response, err := searcher.Search(ctx, req)
+		require.NoError(t, err)
+		require.Len(t, response.Results, 2)
+		assert.Equal(t, added[1].Id, response.Results[0].Record.Id)
+		assert.InDelta(t, 0.8, response.Results[0].Breakdown.Recency, 0.0001)
+		assert.Equal(t, float32(0), response.Results[1].Score)
+	})
+}
+
+func TestSearch_ConceptExpansion(t *testing.T) {
+	chatRepo, conceptRepo, backend, err := badger.NewMemoryRepositories()
+	require.NoError(t, err)
+	defer func() {
+		conceptRepo.Close()
+		chatRepo.Close()
+		backend.Close()
+	}()
+
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	// Concept vectors: dog is close to puppy, wolf less so, car is unrelated
+	conceptVectors := map[string][]float32{
+		"(animal,puppy)": {1.0, 0.0, 0.0},
+		"(animal,dog)":   {0.9, 0.436, 0.0},
+		"(animal,wolf)":  {0.75, 0.661, 0.0},
+		"(vehicle,car)":  {0.0, 1.0, 0.0},
+	}
+	dog, err := conceptRepo.GetOrCreateConcept(ctx, "dog", "animal", conceptVectors["(animal,dog)"])
+	require.NoError(t, err)
+	wolf, err := conceptRepo.GetOrCreateConcept(ctx, "wolf", "animal", conceptVectors["(animal,wolf)"])
+	require.NoError(t, err)
+	car, err := conceptRepo.GetOrCreateConcept(ctx, "car", "vehicle", conceptVectors["(vehicle,car)"])
+	require.NoError(t, err)
+
+	// Record vectors are orthogonal to the query so only concepts can find them
+	records := []*core.ChatRecord{
+		{
+			Speaker:   core.SpeakerTypeHuman,
+			Contents:  "Walked the dog this morning",
+			Timestamp: now,
+			Vector:    []float32{0.0, 0.0, 1.0},
+			Concepts:  []core.ConceptRef{{ConceptId: dog.Id, Importance: 5}},
+		},
+		{
+			Speaker:   core.SpeakerTypeHuman,
+			Contents:  "Saw a wolf documentary",
+			Timestamp: now,
+			Vector:    []float32{0.0, 0.0, 1.0},
+			Concepts:  []core.ConceptRef{{ConceptId: wolf.Id, Importance: 9}},
+		},
+		{
+			Speaker:   core.SpeakerTypeHuman,
+			Contents:  "Washed the car",
+			Timestamp: now,
+			Vector:    []float32{0.0, 0.0, 1.0},
+			Concepts:  []core.ConceptRef{{ConceptId: car.Id, Importance: 9}},
+		},
+	}
+	added, err := chatRepo.AddChatRecords(ctx, records...)
+	require.NoError(t, err)
+
+	mockEmbedder := mock.NewMockEmbedder()
+	mockEmbedder.EmbedTextFunc = func(ctx context.Context, text string) ([]float32, error) {
+		return []float32{1.0, 0.0, 0.0}, nil
+	}
+	mockEmbedder.EmbedTextsFunc = func(ctx context.Context, texts []string) ([][]float32, error) {
+		embeddings := make([][]float32, len(texts))
+		for i, text := range texts {
+			embeddings[i] = conceptVectors[text]
+		}
+		return embeddings, nil
+	}
+	mockExtractor := mock.NewMockConceptExtractor()
+	mockExtractor.ExtractConceptsFunc = func(ctx context.Context, text string) ([]ai.ExtractedConcept, error) {
+		return []ai.ExtractedConcept{{Name: "puppy", Type: "animal", Importance: 8}}, nil
+	}
+	mockProvider := mock.NewMockProviderWithServices(mockEmbedder, mockExtractor)
+
+	t.Run("exact matching misses related concepts", func(t *testing.T) {
+		searcher, err := NewSearcher(chatRepo, conceptRepo, mockProvider)
+		require.NoError(t, err)
+
+		results, err := searcher.FindSimilar(ctx, "my puppy", 10)
+		require.NoError(t, err)
+		assert.Empty(t, results)
+	})
+
+	t.Run("expansion weights by similarity", func(t *testing.T) {
+		searcher, err := NewSearcher(chatRepo, conceptRepo, mockProvider, WithConceptExpansion(0.7, 5))
+		require.NoError(t, err)
+
+		results, err := searcher.FindSimilar(ctx, "my puppy", 10)
+		require.NoError(t, err)
+		require.Len(t, results, 2)
+		assert.Equal(t, added[0].Id, results[0].Record.Id)
+		assert.Equal(t, added[1].Id, results[1].Record.Id)
+		assert.InDelta(t, DefaultConceptOnlyScore*0.9, results[0].Score, 0.0001)
+		assert.InDelta(t, DefaultConceptOnlyScore*0.75, results[1].Score, 0.0001)
+	})
+
+	t.Run("embedding count mismatch skips the stage", func(t *testing.T) {
+		shortEmbedder := mock.NewMockEmbedder()
+		shortEmbedder.EmbedTextFunc = mockEmbedder.EmbedTextFunc
+		shortEmbedder.EmbedTextsFunc = func(ctx context.Context, texts []string) ([][]float32, error) {
+			return [][]float32{}, nil
+		}
+		searcher, err := NewSearcher(chatRepo, conceptRepo, mock.NewMockProviderWithServices(shortEmbedder, mockExtractor), WithConceptExpansion(0.7, 5))
+		require.NoError(t, err)
+
+		response, err := searcher.Search(ctx, &SearchRequest{Query: "my puppy", MaxHits: 10})
+		require.NoError(t, err)
+		require.Len(t, response.Skipped, 1)
+		assert.Equal(t, StageConceptual, response.Skipped[0].Stage)
+		assert.ErrorContains(t, response.Skipped[0].Err, "mismatch")
+	})
+
+	t.Run("concept score weights by importance", func(t *testing.T) {
+		ranker := RankerFunc(func(signals *Signals) float32 {
+			return signals.ConceptScore
+		})
+		searcher, err := NewSearcher(chatRepo, conceptRepo, mockProvider, WithConceptExpansion(0.7, 5), WithRanker(ranker))
+		require.NoError(t, err)
+
+		response, err := searcher.Search(ctx, &SearchRequest{Query: "my puppy", MaxHits: 10})
+		require.NoError(t, err)
+		require.Len(t, response.Results, 2)
+		// wolf: 9 * 0.75 beats dog: 5 * 0.9
+		assert.Equal(t, added[1].Id, response.Results[0].Record.Id)
+		assert.InDelta(t, 6.75, response.Results[0].Score, 0.001)
+		assert.InDelta(t, 4.5, response.Results[1].Score, 0.001)
+	})
+
+	t.Run("limit", func(t *testing.T) {
+		searcher, err := NewSearcher(chatRepo, conceptRepo, mockProvider, WithConceptExpansion(0.7, 1))
+		require.NoError(t, err)
+
+		results, err := searcher.FindSimilar(ctx, "my puppy", 10)
+		require.NoError(t, err)
+		require.Len(t, results, 1)
+		assert.Equal(t, added[0].Id, results[0].Record.Id)
+	})
+}
+
+func TestSearch_ContextWindow(t *testing.T) {
+	chatRepo, conceptRepo, backend, err := badger.NewMemoryRepositories()
+	require.NoError(t, err)
+	defer func() {
+		conceptRepo.Close()
+		chatRepo.Close()
+		backend.Close()
+	}()
+
+	ctx := context.Background()
+	base := time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)
+	offTopic := []float32{0.0, 1.0, 0.0}
+
+	// Conversation "a" has a message every minute; hits are messages 2, 3 and 7
+	vectors := map[int][]float32{
+		2: {0.9, 0.436, 0.0},
+		3: {0.8, 0.6, 0.0},
+		7: {1.0, 0.0, 0.0},
+	}
+	var records []*core.ChatRecord
+	for i := 0; i < 8; i++ {
+		vector, ok := vectors[i]
+		if !ok {
+			vector = offTopic
+		}
+		records = append(records, &core.ChatRecord{
+			Speaker:   core.SpeakerTypeHuman,
+			Contents:  fmt.Sprintf("a%d", i),
+			Timestamp: base.Add(time.Duration(i) * time.Minute),
+			Vector:    vector,
+			Metadata:  map[string]string{core.MetadataConversation: "a"},
+		})
+	}
+	// A message from another conversation sits between a5 and a6
+	records = append(records, &core.ChatRecord{
+		Speaker:   core.SpeakerTypeHuman,
+		Contents:  "b0",
+		Timestamp: base.Add(6*time.Minute - time.Second),
+		Vector:    offTopic,
+		Metadata:  map[string]string{core.MetadataConversation: "b"},
+	})
+	_, err = chatRepo.AddChatRecords(ctx, records...)
+	require.NoError(t, err)
+
+	mockEmbedder := mock.NewMockEmbedder()
+	mockEmbedder.EmbedTextFunc = func(ctx context.Context, text string) ([]float32, error) {
+		return []float32{1.0, 0.0, 0.0}, nil
+	}
+	mockProvider := mock.NewMockProviderWithServices(mockEmbedder, mock.NewMockConceptExtractor())
+
+	contents := func(p *Passage) []string {
+		out := make([]string, len(p.Records))
+		for i, record := range p.Records {
+			out[i] = record.Contents
+		}
+		return out
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		searcher, err := NewSearcher(chatRepo, conceptRepo, mockProvider)
+		require.NoError(t, err)
+
+		response, err := searcher.Search(ctx, &SearchRequest{Query: "hits", MaxHits: 10})
+		require.NoError(t, err)
+		assert.Len(t, response.Results, 3)
+		assert.Nil(t, response.Passages)
+	})
+
+	t.Run("overlapping windows merge", func(t *testing.T) {
+		searcher, err := NewSearcher(chatRepo, conceptRepo, mockProvider, WithContextWindow(1, 1))
+		require.NoError(t, err)
+
+		response, err := searcher.Search(ctx, &SearchRequest{Query: "hits", MaxHits: 10})
+		require.NoError(t, err)
+		require.Len(t, response.Passages, 2)
+
+		first := response.Passages[0]
+		assert.Equal(t, []string{"a6", "a7"}, contents(first))
+		assert.Equal(t, []int{1}, first.Matches)
+		assert.Equal(t, "a", first.Conversation)
+		assert.InDelta(t, 1.0, first.Score, 0.0001)
+
+		second := response.Passages[1]
+		assert.Equal(t, []string{"a1", "a2", "a3", "a4"}, contents(second))
+		assert.Equal(t, []int{1, 2}, second.Matches)
+		assert.True(t, second.Matched(2))
+		assert.False(t, second.Matched(0))
+		assert.InDelta(t, 0.9, second.Score, 0.0001)
+	})
+
+	t.Run("neighbors stay in the conversation", func(t *testing.T) {
+		searcher, err := NewSearcher(chatRepo, conceptRepo, mockProvider, WithContextWindow(2, 0))
+		require.NoError(t, err)
+
+		response, err := searcher.Search(ctx, &SearchRequest{Query: "hits", MaxHits: 1})
+		require.NoError(t, err)
+		require.Len(t, response.Passages, 1)
+		assert.Equal(t, []string{"a5", "a6", "a7"}, contents(response.Passages[0]))
+	})
+}
+
+func TestSearch_Diversity(t *testing.T) {
+	chatRepo, conceptRepo, backend, err := badger.NewMemoryRepositories()
+	require.NoError(t, err)
+	defer func() {
+		conceptRepo.Close()
+		chatRepo.Close()
+		backend.Close()
+	}()
+
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	// The user said the same thing three times, and one different relevant thing
+	records := []*core.ChatRecord{
+		{Speaker: core.SpeakerTypeHuman, Contents: "I'm allergic to peanuts", Timestamp: now, Vector: []float32{1.0, 0.0, 0.0}},
+		{Speaker: core.SpeakerTypeHuman, Contents: "Remember I'm allergic to peanuts", Timestamp: now, Vector: []float32{0.995, 0.0998, 0.0}},
+		{Speaker: core.SpeakerTypeHuman, Contents: "Peanut allergy, as I said", Timestamp: now, Vector: []float32{0.99, 0.0, 0.141}},
+		{Speaker: core.SpeakerTypeHuman, Contents: "I also avoid shellfish", Timestamp: now, Vector: []float32{0.8, 0.6, 0.0}},
+	}
+	added, err := chatRepo.AddChatRecords(ctx, records...)
+	require.NoError(t, err)
+
+	mockEmbedder := mock.NewMockEmbedder()
+	mockEmbedder.EmbedTextFunc = func(ctx context.Context, text string) ([]float32, error) {
+		return []float32{1.0, 0.0, 0.0}, nil
+	}
+	mockProvider := mock.NewMockProviderWithServices(mockEmbedder, mock.NewMockConceptExtractor())
+
+	search := func(opts ...Option) []core.ID {
+		searcher, err := NewSearcher(chatRepo, conceptRepo, mockProvider, opts...)
+		require.NoError(t, err)
+		results, err := searcher.FindSimilar(ctx, "food allergies", 2)
+		require.NoError(t, err)
+		return resultIDs(results)
+	}
+
+	assert.Equal(t, []core.ID{added[0].Id, added[1].Id}, search())
+	assert.Equal(t, []core.ID{added[0].Id, added[3].Id}, search(WithDuplicateCollapse(0.98)))
+	assert.Equal(t, []core.ID{added[0].Id, added[3].Id}, search(WithMMR(0.3)))
+}
+
+func TestSearch_Explain(t *testing.T) {
+	chatRepo, conceptRepo, backend, err := badger.NewMemoryRepositories()
+	require.NoError(t, err)
+	defer func() {
+		conceptRepo.Close()
+		chatRepo.Close()
+		backend.Close()
+	}()
+
+	ctx := context.Background()
+	reference := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	concept := &core.Concept{Name: "machine", Type: "thing", InsertedAt: reference, UpdatedAt: reference}
+	concept.Id = core.IDFromContent(concept.Tuple())
+	_, err = conceptRepo.AddConcepts(ctx, concept)
+	require.NoError(t, err)
+
+	records := []*core.ChatRecord{
+		{
+			Speaker:   core.SpeakerTypeHuman,
+			Contents:  "Machine learning is fascinating",
+			Timestamp: reference,
+			Vector:    []float32{1.0, 0.0, 0.0},
+			Concepts:  []core.ConceptRef{{ConceptId: concept.Id, Importance: 8}},
+		},
+		{
+			Speaker:   core.SpeakerTypeHuman,
+			Contents:  "The machine in the factory",
+			Timestamp: reference,
+			Vector:    []float32{0.0, 0.0, 1.0},
+			Concepts:  []core.ConceptRef{{ConceptId: concept.Id, Importance: 7}},
+		},
+	}
+	added, err := chatRepo.AddChatRecords(ctx, records...)
+	require.NoError(t, err)
+
+	mockEmbedder := mock.NewMockEmbedder()
+	mockEmbedder.EmbedTextFunc = func(ctx context.Context, text string) ([]float32, error) {
+		return []float32{1.0, 0.0, 0.0}, nil
+	}
+	mockExtractor := mock.NewMockConceptExtractor()
+	mockExtractor.ExtractConceptsFunc = func(ctx context.Context, text string) ([]ai.ExtractedConcept, error) {
+		return []ai.ExtractedConcept{{Name: "machine", Type: "thing", Importance: 8}}, nil
+	}
+	mockProvider := mock.NewMockProviderWithServices(mockEmbedder, mockExtractor)
+	req := SearchRequest{Query: "machine learning", MaxHits: 10, ReferenceTime: reference, Explain: true}
+
+	t.Run("disabled", func(t *testing.T) {
+		searcher, err := NewSearcher(chatRepo, conceptRepo, mockProvider)
+		require.NoError(t, err)
+
+		req := req
+		req.Explain = false
+		response, err := searcher.Search(ctx, &req)
+		require.NoError(t, err)
+		require.Len(t, response.Results, 2)
+		assert.Empty(t, response.Results[0].Breakdown.Formula)
+		assert.Empty(t, response.Results[0].Breakdown.Concepts)
+	})
+
+	t.Run("weighted ranker", func(t *testing.T) {
+		searcher, err := NewSearcher(chatRepo, conceptRepo, mockProvider)
+		require.NoError(t, err)
+
+		response, err := searcher.Search(ctx, &req)
+		require.NoError(t, err)
+		require.Len(t, response.Results, 2)
+
+		hybrid := response.Results[0].Breakdown
+		assert.Equal(t, added[0].Id, response.Results[0].Record.Id)
+		assert.True(t, hybrid.Semantic)
+		assert.InDelta(t, 1, hybrid.Similarity, 0.0001)
+		assert.Equal(t, []core.ConceptContribution{{Name: "machine", Type: "thing", Importance: 8, Similarity: 1}}, hybrid.Concepts)
+		assert.ElementsMatch(t, []string{"machine", "learning"}, hybrid.KeywordMatches)
+		assert.True(t, hybrid.Verbatim)
+		assert.Equal(t, "1.5000 × 1.0000 [hybrid] + 0.3000 [verbatim] = 1.8000", hybrid.Formula)
+
+		conceptOnly := response.Results[1].Breakdown
+		assert.False(t, conceptOnly.Semantic)
+		assert.False(t, conceptOnly.Verbatim)
+		assert.Equal(t, []string{"machine"}, conceptOnly.KeywordMatches)
+		assert.Equal(t, "1.2000 × 1.0000 [concept only] = 1.2000", conceptOnly.Formula)
+	})
+
+	t.Run("recency", func(t *testing.T) {
+		searcher, err := NewSearcher(chatRepo, conceptRepo, mockProvider, WithRecency(LinearDecay(24*time.Hour, 0.5)))
+		require.NoError(t, err)
+
+		req := req
+		req.ReferenceTime = reference.Add(12 * time.Hour)
+		response, err := searcher.Search(ctx, &req)
+		require.NoError(t, err)
+		require.Len(t, response.Results, 2)
+		assert.Equal(t, "(1.5000 × 1.0000 [hybrid] + 0.3000 [verbatim]) × (0.50 + 0.50 × 0.5000 [recency]) = 1.3500",
+			response.Results[0].Breakdown.Formula)
+	})
+
+	t.Run("rank fusion", func(t *testing.T) {
+		searcher, err := NewSearcher(chatRepo, conceptRepo, mockProvider)
+		require.NoError(t, err)
+
+		req := req
+		req.Fusion = FusionRRF
+		response, err := searcher.Search(ctx, &req)
+		require.NoError(t, err)
+		require.Len(t, response.Results, 2)
+		assert.Equal(t, "1.00/(60+1) [semantic] + 1.00/(60+1) [concept] + 1.00/(60+1) [keyword] = 0.0492",
+			response.Results[0].Breakdown.Formula)
+	})
+
+	t.Run("reranked", func(t *testing.T) {
+		reranker := RerankerFunc(func(ctx context.Context, query string, records []*core.ChatRecord) ([]float32, error) {
+			scores := make([]float32, len(records))
+			for i := range records {
+				scores[i] = float32(i) / 10
+			}
+			return scores, nil
+		})
+		searcher, err := NewSearcher(chatRepo, conceptRepo, mockProvider, WithReranker(reranker))
+		require.NoError(t, err)
+
+		response, err := searcher.Search(ctx, &req)
+		require.NoError(t, err)
+		require.Len(t, response.Results, 2)
+		assert.Equal(t, added[1].Id, response.Results[0].Record.Id)
+		assert.Equal(t, "0.1000 [reranked; retrieval 1.2000 × 1.0000 [concept only] = 1.2000] = 0.1000",
+			response.Results[0].Breakdown.Formula)
+	})
+}
+
+func TestSearch_ConcurrentStages(t *testing.T) {
+	chatRepo, conceptRepo, backend, err := badger.NewMemoryRepositories()
+	require.NoError(t, err)
+	defer func() {
+		conceptRepo.Close()
+		chatRepo.Close()
+		backend.Close()
+	}()
+
+	ctx := context.Background()
+	_, err = chatRepo.AddChatRecords(ctx, &core.ChatRecord{
+		Speaker:   core.SpeakerTypeHuman,
+		Contents:  "Machine learning is fascinating",
+		Timestamp: time.Now(),
+		Vector:    []float32{1.0, 0.0, 0.0},
+	})
+	require.NoError(t, err)
+
+	embed := func(ctx context.Context, text string) ([]float32, error) {
+		return []float32{1.0, 0.0, 0.0}, nil
+	}
+	req := &SearchRequest{Query: "machine learning", MaxHits: 10}
+
+	t.Run("stages overlap", func(t *testing.T) {
+		extracting := make(chan struct{})
+		mockEmbedder := mock.NewMockEmbedder()
+		mockEmbedder.EmbedTextFunc = func(ctx context.Context, text string) ([]float32, error) {
+			// Run sequentially, the embedder would finish before extraction starts
+			select {
+			case <-extracting:
+				return embed(ctx, text)
+			case <-time.After(5 * time.Second):
+				return nil, fmt.Errorf("concept extraction did not start concurrently")
+			}
+		}
+		mockExtractor := mock.NewMockConceptExtractor()
+		mockExtractor.ExtractConceptsFunc = func(ctx context.Context, text string) ([]ai.ExtractedConcept, error) {
+			close(extracting)
+			return nil, nil
+		}
+		searcher, err := NewSearcher(chatRepo, conceptRepo, mock.NewMockProviderWithServices(mockEmbedder, mockExtractor))
+		require.NoError(t, err)
+
+		response, err := searcher.Search(ctx, req)
+		require.NoError(t, err)
+		assert.Len(t, response.Results, 1)
+	})
+
+	t.Run("stage timeout", func(t *testing.T) {
+		mockEmbedder := mock.NewMockEmbedder()
+		mockEmbedder.EmbedTextFunc = embed
+		mockExtractor := mock.NewMockConceptExtractor()
+		mockExtractor.ExtractConceptsFunc = func(ctx context.Context, text string) ([]ai.ExtractedConcept, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}
+		searcher, err := NewSearcher(chatRepo, conceptRepo, mock.NewMockProviderWithServices(mockEmbedder, mockExtractor),
+			WithConceptTimeout(10*time.Millisecond), WithStrictStages())
+		require.NoError(t, err)
+
+		_, err = searcher.Search(ctx, req)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+
+	t.Run("strict failed stage cancels the other", func(t *testing.T) {
+		extractErr := fmt.Errorf("extraction failed")
+		mockEmbedder := mock.NewMockEmbedder()
+		mockEmbedder.EmbedTextFunc = func(ctx context.Context, text string) ([]float32, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}
+		mockExtractor := mock.NewMockConceptExtractor()
+		mockExtractor.ExtractConceptsFunc = func(ctx context.Context, text string) ([]ai.ExtractedConcept, error) {
+			return nil, extractErr
+		}
+		searcher, err := NewSearcher(chatRepo, conceptRepo, mock.NewMockProviderWithServices(mockEmbedder, mockExtractor),
+			WithStrictStages())
+		require.NoError(t, err)
+
+		_, err = searcher.Search(ctx, req)
+		assert.Equal(t, extractErr, err)
+	})
+}
+
+func TestSearch_Degradation(t *testing.T) {
+	chatRepo, conceptRepo, backend, err := badger.NewMemoryRepositories()
+	require.NoError(t, err)
+	defer func() {
+		conceptRepo.Close()
+		chatRepo.Close()
+		backend.Close()
+	}()
+
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	concept := &core.Concept{Name: "machine", Type: "thing", InsertedAt: now, UpdatedAt: now}
+	concept.Id = core.IDFromContent(concept.Tuple())
+	_, err = conceptRepo.AddConcepts(ctx, concept)
+	require.NoError(t, err)
+
+	added, err := chatRepo.AddChatRecords(ctx,
+		&core.ChatRecord{
+			Speaker:   core.SpeakerTypeHuman,
+			Contents:  "Machine learning is fascinating",
+			Timestamp: now,
+			Vector:    []float32{1.0, 0.0, 0.0},
+		},
+		&core.ChatRecord{
+			Speaker:   core.SpeakerTypeHuman,
+			Contents:  "The machine in the factory",
+			Timestamp: now,
+			Vector:    []float32{0.0, 0.0, 1.0},
+			Concepts:  []core.ConceptRef{{ConceptId: concept.Id, Importance: 7}},
+		},
+	)
+	require.NoError(t, err)
+
+	embedErr := fmt.Errorf("embedding service unavailable")
+	extractErr := fmt.Errorf("classifier returned invalid JSON")
+	embed := func(ctx context.Context, text string) ([]float32, error) {
+		return []float32{1.0, 0.0, 0.0}, nil
+	}
+	extract := func(ctx context.Context, text string) ([]ai.ExtractedConcept, error) {
+		return []ai.ExtractedConcept{{Name: "machine", Type: "thing", Importance: 8}}, nil
+	}
+	newSearcher := func(t *testing.T, embed func(context.Context, string) ([]float32, error),
+		extract func(context.Context, string) ([]ai.ExtractedConcept, error), opts ...Option) *Searcher {
+		mockEmbedder := mock.NewMockEmbedder()
+		mockEmbedder.EmbedTextFunc = embed
+		mockExtractor := mock.NewMockConceptExtractor()
+		mockExtractor.ExtractConceptsFunc = extract
+		searcher, err := NewSearcher(chatRepo, conceptRepo, mock.NewMockProviderWithServices(mockEmbedder, mockExtractor), opts...)
+		require.NoError(t, err)
+		return searcher
+	}
+
+	t.Run("concept extraction fails", func(t *testing.T) {
+		searcher := newSearcher(t, embed, func(ctx context.Context, text string) ([]ai.ExtractedConcept, error) {
+			return nil, extractErr
+		})
+		monitor := &testMonitor{}
+
+		response, err := searcher.Search(ctx, &SearchRequest{Query: "machine learning", MaxHits: 10, Monitor: monitor})
+		require.NoError(t, err)
+		require.Len(t, response.Results, 1)
+		assert.Equal(t, added[0].Id, response.Results[0].Record.Id)
+		assert.Equal(t, []SkippedStage{{Stage: StageConceptual, Err: extractErr}}, response.Skipped)
+		assert.Equal(t, []Stage{StageConceptual}, monitor.skipped)
+
+		// Monitors without StageSkipped are still supported
+		plain := &testMonitor{}
+		response, err = searcher.Search(ctx, &SearchRequest{Query: "machine learning", MaxHits: 10, Monitor: struct{ SearchMonitor }{plain}})
+		require.NoError(t, err)
+		assert.Len(t, response.Skipped, 1)
+		assert.True(t, plain.finishCalled)
+		assert.Empty(t, plain.skipped)
+	})
+
+	t.Run("embedding fails", func(t *testing.T) {
+		searcher := newSearcher(t, func(ctx context.Context, text string) ([]float32, error) {
+			return nil, embedErr
+		}, extract)
+		monitor := &testMonitor{}
+
+		response, err := searcher.Search(ctx, &SearchRequest{Query: "machine", MaxHits: 10, Monitor: monitor})
+		require.NoError(t, err)
+		require.Len(t, response.Results, 1)
+		assert.Equal(t, added[1].Id, response.Results[0].Record.Id)
+		assert.True(t, response.Results[0].Breakdown.Relevance > DefaultConceptOnlyScore, "verbatim keyword boost should still apply")
+		assert.Equal(t, []SkippedStage{{Stage: StageSemantic, Err: embedErr}}, response.Skipped)
+		assert.Equal(t, []Stage{StageSemantic}, monitor.skipped)
+	})
+
+	t.Run("both stages fail", func(t *testing.T) {
+		searcher := newSearcher(t, func(ctx context.Context, text string) ([]float32, error) {
+			return nil, embedErr
+		}, func(ctx context.Context, text string) ([]ai.ExtractedConcept, error) {
+			return nil, extractErr
+		})
+
+		_, err := searcher.Search(ctx, &SearchRequest{Query: "machine", MaxHits: 10})
+		require.Error(t, err)
+	})
+
+	t.Run("stage timeout", func(t *testing.T) {
+		searcher := newSearcher(t, embed, func(ctx context.Context, text string) ([]ai.ExtractedConcept, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}, WithConceptTimeout(10*time.Millisecond))
+
+		response, err := searcher.Search(ctx, &SearchRequest{Query: "machine learning", MaxHits: 10})
+		require.NoError(t, err)
+		require.Len(t, response.Skipped, 1)
+		assert.Equal(t, StageConceptual, response.Skipped[0].Stage)
+		assert.ErrorIs(t, response.Skipped[0].Err, context.DeadlineExceeded)
+	})
+
+	t.Run("cancelled search fails", func(t *testing.T) {
+		searcher := newSearcher(t, embed, func(ctx context.Context, text string) ([]ai.ExtractedConcept, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		})
+		ctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+		defer cancel()
+
+		_, err := searcher.Search(ctx, &SearchRequest{Query: "machine learning", MaxHits: 10})
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+
+	t.Run("reranker fails", func(t *testing.T) {
+		rerankErr := fmt.Errorf("reranker unavailable")
+		searcher := newSearcher(t, embed, extract, WithReranker(RerankerFunc(
+			func(ctx context.Context, query string, records []*core.ChatRecord) ([]float32, error) {
+				return nil, rerankErr
+			})))
+
+		response, err := searcher.Search(ctx, &SearchRequest{Query: "machine learning", MaxHits: 10})
+		require.NoError(t, err)
+		assert.Len(t, response.Results, 2)
+		assert.Equal(t, []SkippedStage{{Stage: StageRerank, Err: rerankErr}}, response.Skipped)
+	})
+}
+
+func TestSearch_ParsedQuery(t *testing.T) {
+	chatRepo, conceptRepo, backend, err := badger.NewMemoryRepositories()
+	require.NoError(t, err)
+	defer func() {
+		conceptRepo.Close()
+		chatRepo.Close()
+		backend.Close()
+	}()
+
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	terraform := &core.Concept{Name: "terraform", Type: "software", InsertedAt: now, UpdatedAt: now}
+	terraform.Id = core.IDFromContent(terraform.Tuple())
+	_, err = conceptRepo.AddConcepts(ctx, terraform)
+	require.NoError(t, err)
+
+	added, err := chatRepo.AddChatRecords(ctx,
+		&core.ChatRecord{
+			Speaker:   core.SpeakerTypeHuman,
+			Contents:  "Our kubernetes deploys use a helm chart",
+			Timestamp: now,
+			Vector:    []float32{1.0, 0.0, 0.0},
+		},
+		&core.ChatRecord{
+			Speaker:   core.SpeakerTypeHuman,
+			Contents:  "The kubernetes cluster upgrade went fine",
+			Timestamp: now,
+			Vector:    []float32{0.9, 0.1, 0.0},
+		},
+		&core.ChatRecord{
+			Speaker:   core.SpeakerTypeHuman,
+			Contents:  "Terraform provisions the kubernetes nodes",
+			Timestamp: now,
+			Vector:    []float32{0.0, 0.0, 1.0},
+			Concepts:  []core.ConceptRef{{ConceptId: terraform.Id, Importance: 6}},
+		},
+	)
+	require.NoError(t, err)
+
+	mockEmbedder := mock.NewMockEmbedder()
+	mockEmbedder.EmbedTextFunc = func(ctx context.Context, text string) ([]float32, error) {
+		return []float32{1.0, 0.0, 0.0}, nil
+	}
+	mockExtractor := mock.NewMockConceptExtractor()
+	mockExtractor.ExtractConceptsFunc = func(ctx context.Context, text string) ([]ai.ExtractedConcept, error) {
+		return nil, nil
+	}
+	searcher, err := NewSearcher(chatRepo, conceptRepo, mock.NewMockProviderWithServices(mockEmbedder, mockExtractor))
+	require.NoError(t, err)
+
+	search := func(t *testing.T, query string) []*core.SearchResult {
+		req, err := ParseQuery(query)
+		require.NoError(t, err)
+		req.MaxHits = 10
+		response, err := searcher.Search(ctx, req)
+		require.NoError(t, err)
+		return response.Results
+	}
+
+	t.Run("excluded phrase", func(t *testing.T) {
+		results := search(t, `kubernetes -"helm chart"`)
+		require.Len(t, results, 1)
+		assert.Equal(t, added[1].Id, results[0].Record.Id)
+	})
+
+	t.Run("required phrase", func(t *testing.T) {
+		results := search(t, `"cluster upgrade"`)
+		require.Len(t, results, 1)
+		assert.Equal(t, added[1].Id, results[0].Record.Id)
+	})
+
+	t.Run("required concept is retrieved", func(t *testing.T) {
+		results := search(t, "kubernetes concept:software/terraform")
+		require.Len(t, results, 1)
+		assert.Equal(t, added[2].Id, results[0].Record.Id)
+	})
+
+	t.Run("boost", func(t *testing.T) {
+		results := search(t, "kubernetes upgrade^3")
+		require.Len(t, results, 2)
+		assert.Equal(t, added[1].Id, results[0].Record.Id)
+	})
+}
+
+func TestFindRelated(t *testing.T) {
+	chatRepo, conceptRepo, backend, err := badger.NewMemoryRepositories()
+	require.NoError(t, err)
+	defer func() {
+		conceptRepo.Close()
+		chatRepo.Close()
+		backend.Close()
+	}()
+
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	garden := &core.Concept{Name: "garden", Type: "place", InsertedAt: now, UpdatedAt: now}
+	garden.Id = core.IDFromContent(garden.Tuple())
+	_, err = conceptRepo.AddConcepts(ctx, garden)
+	require.NoError(t, err)
+
+	inConversation := func(id string) map[string]string {
+		return map[string]string{core.MetadataConversation: id}
+	}
+	added, err := chatRepo.AddChatRecords(ctx,
+		&core.ChatRecord{
+			Speaker:   core.SpeakerTypeHuman,
+			Contents:  "I planted tomatoes in the garden",
+			Timestamp: now,
+			Vector:    []float32{1.0, 0.0, 0.0},
+			Metadata:  inConversation("a"),
+			Concepts:  []core.ConceptRef{{ConceptId: garden.Id, Importance: 8}},
+		},
+		&core.ChatRecord{
+			Speaker:   core.SpeakerTypeAI,
+			Contents:  "Tomatoes need plenty of sun",
+			Timestamp: now,
+			Vector:    []float32{0.95, 0.05, 0.0},
+			Metadata:  inConversation("a"),
+		},
+		&core.ChatRecord{
+			Speaker:   core.SpeakerTypeHuman,
+			Contents:  "My tomato plants are flowering",
+			Timestamp: now,
+			Vector:    []float32{0.9, 0.1, 0.0},
+			Metadata:  inConversation("b"),
+		},
+		&core.ChatRecord{
+			Speaker:   core.SpeakerTypeHuman,
+			Contents:  "The garden gate is broken",
+			Timestamp: now,
+			Vector:    []float32{0.0, 0.0, 1.0},
+			Metadata:  inConversation("c"),
+			Concepts:  []core.ConceptRef{{ConceptId: garden.Id, Importance: 5}},
+		},
+		&core.ChatRecord{
+			Speaker:   core.SpeakerTypeHuman,
+			Contents:  "Weeding the garden today",
+			Timestamp: now,
+			Concepts:  []core.ConceptRef{{ConceptId: garden.Id, Importance: 6}},
+		},
+	)
+	require.NoError(t, err)
+
+	mockEmbedder := mock.NewMockEmbedder()
+	mockExtractor := mock.NewMockConceptExtractor()
+	searcher, err := NewSearcher(chatRepo, conceptRepo, mock.NewMockProviderWithServices(mockEmbedder, mockExtractor))
+	require.NoError(t, err)
+
+	t.Run("uses stored vector and concepts", func(t *testing.T) {
+		response, err := searcher.FindRelated(ctx, added[0].Id, nil)
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []core.ID{added[1].Id, added[2].Id, added[3].Id, added[4].Id}, resultIDs(response.Results))
+		assert.Empty(t, response.Skipped)
+		assert.Equal(t, 0, mockEmbedder.CallCount())
+		assert.Equal(t, 0, mockExtractor.CallCount())
+	})
+
+	t.Run("excludes own conversation", func(t *testing.T) {
+		response, err := searcher.FindRelated(ctx, added[0].Id, &RelatedOptions{MaxHits: 10, ExcludeConversation: true})
+		require.NoError(t, err)
+		// Records without a conversation are not in the source's conversation
+		assert.ElementsMatch(t, []core.ID{added[2].Id, added[3].Id, added[4].Id}, resultIDs(response.Results))
+	})
+
+	t.Run("max hits", func(t *testing.T) {
+		response, err := searcher.FindRelated(ctx, added[0].Id, &RelatedOptions{MaxHits: 1})
+		require.NoError(t, err)
+		assert.Len(t, response.Results, 1)
+	})
+
+	t.Run("record without vector", func(t *testing.T) {
+		response, err := searcher.FindRelated(ctx, added[4].Id, nil)
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []core.ID{added[0].Id, added[3].Id}, resultIDs(response.Results))
+		assert.Equal(t, []SkippedStage{{Stage: StageSemantic, Err: ErrRecordNotEmbedded}}, response.Skipped)
+	})
+
+	t.Run("unknown record", func(t *testing.T) {
+		_, err := searcher.FindRelated(ctx, core.ID(12345), nil)
+		assert.ErrorIs(t, err, storage.ErrNotFound)
+	})
+}
+
+func TestSearch_TiesOrderedByID(t *testing.T) {
+	chatRepo, conceptRepo, backend, err := badger.NewMemoryRepositories()
+	require.NoError(t, err)
+	defer func() {
+		conceptRepo.Close()
+		chatRepo.Close()
+		backend.Close()
+	}()
+
+	ctx := context.Background()
+	now := time.Now().UTC()
+	records := make([]*core.ChatRecord, 8)
+	for i := range records {
+		records[i] = &core.ChatRecord{
+			Speaker:   core.SpeakerTypeHuman,
+			Contents:  fmt.Sprintf("note %d", i),
+			Timestamp: now,
+			Vector:    []float32{1.0, 0.0, 0.0},
+		}
+	}
+	added, err := chatRepo.AddChatRecords(ctx, records...)
+	require.NoError(t, err)
+
+	mockEmbedder := mock.NewMockEmbedder()
+	mockEmbedder.EmbedTextFunc = func(ctx context.Context, text string) ([]float32, error) {
+		return []float32{1.0, 0.0, 0.0}, nil
+	}
+	searcher, err := NewSearcher(chatRepo, conceptRepo, mock.NewMockProviderWithServices(mockEmbedder, mock.NewMockConceptExtractor()))
+	require.NoError(t, err)
+
+	expected := make([]core.ID, 5)
+	for i := range expected {
+		expected[i] = added[i].Id
+	}
+
+	// Equal scores come back in ID order on every search
+	for range 5 {
+		results, err := searcher.FindSimilar(ctx, "query", 5)
+		require.NoError(t, err)
+		assert.Equal(t, expected, resultIDs(results))
+	}
+}
@@ -0,0 +1,307 @@
+// Copyright 2025 Poiesic Systems
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+
+package search
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/poiesic/memorit/core"
+)
+
+// DefaultSnippetLength is a suggested maximum snippet length in bytes for WithSnippets.
+const DefaultSnippetLength = 160
+
+// wordSpan is a lowercase word and its byte range in the original text.
+type wordSpan struct {
+	word string
+	span core.Span
+}
+
+// wordSpans splits text like splitWords, keeping each word's byte range.
+func wordSpans(text string) []wordSpan {
+	var words []wordSpan
+	start := -1
+	add := func(end int) {
+		field := text[start:end]
+		left := strings.TrimLeft(field, wordPunctuation)
+		trimmed := strings.TrimRight(left, wordPunctuation)
+		if trimmed != "" {
+			offset := start + len(field) - len(left)
+			words = append(words, wordSpan{
+				word: strings.ToLower(trimmed),
+				span: core.Span{Start: offset, End: offset + len(trimmed)},
+			})
+		}
+	}
+	for i, r := range text {
+		if unicode.IsSpace(r) {
+			if start >= 0 {
+				add(i)
+				start = -1
+			}
+		} else if start < 0 {
+			start = i
+		}
+	}
+	if start >= 0 {
+		add(len(text))
+	}
+	return words
+}
+
+// termOccurrence is a match of one of several terms in a text.
+type termOccurrence struct {
+	term int // Index of the matched term
+	span core.Span
+}
+
+// findTerms returns every occurrence of terms in words, ordered by position.
+// Terms match whole consecutive words, ignoring case and punctuation.
+func findTerms(words []wordSpan, terms []string) []termOccurrence {
+	var occurrences []termOccurrence
+	for t, term := range terms {
+		want := splitWords(term)
+		if len(want) == 0 {
+			continue
+		}
+		for i := 0; i+len(want) <= len(words); i++ {
+			match := true
+			for j, word := range want {
+				if words[i+j].word != word {
+					match = false
+					break
+				}
+			}
+			if match {
+				occurrences = append(occurrences, termOccurrence{
+					term: t,
+					span: core.Span{Start: words[i].span.Start, End: words[i+len(want)-1].span.End},
+				})
+			}
+		}
+	}
+	// Order by position, longest first, so overlapping terms highlight the longer match
+	slices.SortFunc(occurrences, func(a, b termOccurrence) int {
+		if a.span.Start != b.span.Start {
+			return a.span.Start - b.span.Start
+		}
+		return b.span.End - a.span.End
+	})
+	return occurrences
+}
+
+// keywordSnippet returns the passage of up to length bytes containing the
+// most distinct terms, then the most occurrences, with each occurrence
+// highlighted. It returns nil if no term occurs in contents.
+func keywordSnippet(contents string, terms []string, length int) *core.Snippet {
+	words := wordSpans(contents)
+	occurrences := findTerms(words, terms)
+	if len(occurrences) == 0 {
+		return nil
+	}
+
+	// Slide a window starting at each occurrence and keep the best
+	bestStart, bestEnd, bestDistinct, bestCount := 0, 0, -1, 0
+	for i, first := range occurrences {
+		distinct := make(map[int]bool)
+		end, count := first.span.End, 0
+		for _, occurrence := range occurrences[i:] {
+			if occurrence.span.End-first.span.Start > length && count > 0 {
+				break
+			}
+			distinct[occurrence.term] = true
+			end = max(end, occurrence.span.End)
+			count++
+		}
+		if len(distinct) > bestDistinct || (len(distinct) == bestDistinct && count > bestCount) {
+			bestStart, bestEnd, bestDistinct, bestCount = first.span.Start, end, len(distinct), count
+		}
+	}
+
+	span := widenSpan(contents, words, core.Span{Start: bestStart, End: bestEnd}, length)
+	snippet := &core.Snippet{Kind: core.SnippetKeyword, Span: span}
+	pos := span.Start
+	for _, occurrence := range occurrences {
+		// Skip occurrences outside the passage or overlapping a previous highlight
+		if occurrence.span.Start >= pos && occurrence.span.End <= span.End {
+			snippet.Highlights = append(snippet.Highlights, occurrence.span)
+			pos = occurrence.span.End
+		}
+	}
+	return snippet
+}
+
+// widenSpan centers a span within a window of up to length bytes of contents,
+// snapped to whole words. The span itself is never narrowed.
+func widenSpan(contents string, words []wordSpan, span core.Span, length int) core.Span {
+	slack := length - (span.End - span.Start)
+	if slack <= 0 {
+		return span
+	}
+	start := max(0, span.Start-slack/2)
+	end := min(len(contents), start+length)
+	start = max(0, min(start, end-length))
+
+	// Snap inward to word boundaries, keeping the original span, unless the
+	// window reaches an end of contents
+	snapped := core.Span{Start: span.Start, End: span.End}
+	if start == 0 {
+		snapped.Start = len(contents) - len(strings.TrimLeftFunc(contents, unicode.IsSpace))
+	}
+	if end == len(contents) {
+		snapped.End = len(strings.TrimRightFunc(contents, unicode.IsSpace))
+	}
+	for _, word := range words {
+		if word.span.Start >= start && word.span.Start < snapped.Start {
+			snapped.Start = word.span.Start
+			break
+		}
+	}
+	for i := len(words) - 1; i >= 0; i-- {
+		if words[i].span.End <= end && words[i].span.End > snapped.End {
+			snapped.End = words[i].span.End
+			break
+		}
+	}
+	return snapped
+}
+
+// leadingSpan returns up to length bytes from the start of span, cut at the
+// last word boundary that fits, or at a character boundary for a single long word.
+func leadingSpan(contents string, span core.Span, length int) core.Span {
+	if span.End-span.Start <= length {
+		return span
+	}
+	end := span.Start + length
+	if cut := strings.LastIndexFunc(contents[span.Start:end+1], unicode.IsSpace); cut > 0 {
+		end = span.Start + len(strings.TrimRightFunc(contents[span.Start:span.Start+cut], unicode.IsSpace))
+	} else {
+		for end > span.Start && !utf8.RuneStart(contents[end]) {
+			end--
+		}
+	}
+	return core.Span{Start: span.Start, End: end}
+}
+
+// sentenceSpans splits contents into sentences, ending at terminal
+// punctuation followed by whitespace or at line breaks. Surrounding
+// whitespace is trimmed and empty sentences are dropped.
+func sentenceSpans(contents string) []core.Span {
+	var sentences []core.Span
+	add := func(start, end int) {
+		text := contents[start:end]
+		trimmed := strings.TrimLeftFunc(text, unicode.IsSpace)
+		start += len(text) - len(trimmed)
+		trimmed = strings.TrimRightFunc(trimmed, unicode.IsSpace)
+		if trimmed != "" {
+			sentences = append(sentences, core.Span{Start: start, End: start + len(trimmed)})
+		}
+	}
+	start := 0
+	for i := 0; i < len(contents); i++ {
+		switch c := contents[i]; {
+		case c == '\n':
+			add(start, i)
+			start = i + 1
+		case c == '.' || c == '!' || c == '?':
+			if i+1 == len(contents) || unicode.IsSpace(rune(contents[i+1])) {
+				add(start, i+1)
+				start = i + 1
+			}
+		}
+	}
+	add(start, len(contents))
+	return sentences
+}
+
+// addSnippets sets a snippet on each result. Results with query terms get
+// the passage containing the most terms; semantic hits get the sentence most
+// similar to the query vector, with sentences embedded in one batch. Other
+// results get the start of their contents. Snippets never fail a search: if
+// the sentences cannot be embedded, the leading passage is used.
+func (s *Searcher) addSnippets(ctx context.Context, results []*core.SearchResult, signals map[core.ID]*Signals, terms []string, queryVector []float32) {
+	type candidate struct {
+		result    *core.SearchResult
+		sentences []core.Span
+	}
+	var candidates []candidate
+	var texts []string
+
+	for _, result := range results {
+		contents := result.Record.Contents
+		sig := signals[result.Record.Id]
+		snippet := keywordSnippet(contents, terms, s.snippetLength)
+		if snippet == nil {
+			snippet = &core.Snippet{
+				Kind: core.SnippetLeading,
+				Span: leadingSpan(contents, core.Span{End: len(contents)}, s.snippetLength),
+			}
+			if sig != nil && sig.Semantic && len(queryVector) > 0 {
+				sentences := sentenceSpans(contents)
+				if result.Span != nil {
+					// Only consider sentences in the chunk that matched
+					sentences = slices.DeleteFunc(sentences, func(sentence core.Span) bool {
+						return sentence.End <= result.Span.Start || sentence.Start >= result.Span.End
+					})
+				}
+				switch len(sentences) {
+				case 0:
+				case 1:
+					snippet.Kind = core.SnippetSemantic
+					snippet.Span = leadingSpan(contents, sentences[0], s.snippetLength)
+				default:
+					candidates = append(candidates, candidate{result: result, sentences: sentences})
+					for _, sentence := range sentences {
+						texts = append(texts, sentence.Of(contents))
+					}
+				}
+			}
+		}
+		if sig != nil {
+			for _, match := range sig.MatchedConcepts {
+				if !slices.Contains(snippet.Concepts, match.Concept.Name) {
+					snippet.Concepts = append(snippet.Concepts, match.Concept.Name)
+				}
+			}
+		}
+		result.Snippet = snippet
+	}
+	if len(texts) == 0 {
+		return
+	}
+
+	vectors, err := s.embedder.EmbedTexts(ctx, texts)
+	if err != nil || len(vectors) != len(texts) {
+		s.logger.Warn("error embedding sentences for snippets, using leading passages", "sentences", len(texts), "err", err)
+		return
+	}
+	next := 0
+	for _, c := range candidates {
+		best, bestSimilarity := 0, float32(-2)
+		for i := range c.sentences {
+			if similarity := cosineSimilarity(queryVector, vectors[next+i]); similarity > bestSimilarity {
+				best, bestSimilarity = i, similarity
+			}
+		}
+		next += len(c.sentences)
+		c.result.Snippet.Kind = core.SnippetSemantic
+		c.result.Snippet.Span = leadingSpan(c.result.Record.Contents, c.sentences[best], s.snippetLength)
+	}
+}
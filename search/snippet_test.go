@@ -0,0 +1,179 @@
+package search
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/poiesic/memorit/ai"
+	"github.com/poiesic/memorit/ai/mock"
+	"github.com/poiesic/memorit/core"
+	"github.com/poiesic/memorit/storage/badger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWordSpans(t *testing.T) {
+	text := `"Hello," said  the café-owner.`
+	words := wordSpans(text)
+	require.Len(t, words, 4)
+	assert.Equal(t, "hello", words[0].word)
+	assert.Equal(t, "Hello", words[0].span.Of(text))
+	assert.Equal(t, "café-owner", words[3].span.Of(text))
+}
+
+func TestSentenceSpans(t *testing.T) {
+	text := "First one. Second, with 3.5 items!\n  Third line\nv1.2 is out?"
+	var sentences []string
+	for _, span := range sentenceSpans(text) {
+		sentences = append(sentences, span.Of(text))
+	}
+	assert.Equal(t, []string{"First one.", "Second, with 3.5 items!", "Third line", "v1.2 is out?"}, sentences)
+	assert.Empty(t, sentenceSpans("  \n "))
+}
+
+func TestLeadingSpan(t *testing.T) {
+	text := "The quick brown fox jumps"
+	assert.Equal(t, "The quick", leadingSpan(text, core.Span{End: len(text)}, 12).Of(text))
+	assert.Equal(t, "The quick brown", leadingSpan(text, core.Span{End: len(text)}, 15).Of(text))
+	assert.Equal(t, text, leadingSpan(text, core.Span{End: len(text)}, 100).Of(text))
+
+	// A single long word is cut at a character boundary
+	long := "ééééé"
+	assert.Equal(t, "éé", leadingSpan(long, core.Span{End: len(long)}, 5).Of(long))
+}
+
+func TestKeywordSnippet(t *testing.T) {
+	contents := strings.Repeat("filler words here. ", 10) +
+		"Our kubernetes cluster uses a helm chart for deployment. " +
+		strings.Repeat("more filler text. ", 10) + "Kubernetes again."
+
+	snippet := keywordSnippet(contents, []string{"helm chart", "kubernetes", "deployment"}, 80)
+	require.NotNil(t, snippet)
+	assert.Equal(t, core.SnippetKeyword, snippet.Kind)
+	assert.LessOrEqual(t, snippet.Span.End-snippet.Span.Start, 80)
+	assert.Contains(t, snippet.Text(contents), "kubernetes cluster uses a helm chart for deployment")
+	assert.Contains(t, snippet.Highlight(contents, "[", "]"), "[kubernetes] cluster uses a [helm chart] for [deployment]")
+
+	// Overlapping terms highlight the longer match
+	snippet = keywordSnippet("a helm chart", []string{"helm", "helm chart"}, 80)
+	require.NotNil(t, snippet)
+	assert.Equal(t, []core.Span{{Start: 2, End: 12}}, snippet.Highlights)
+
+	assert.Nil(t, keywordSnippet(contents, []string{"terraform"}, 80))
+}
+
+func TestSearch_Snippets(t *testing.T) {
+	chatRepo, conceptRepo, backend, err := badger.NewMemoryRepositories()
+	require.NoError(t, err)
+	defer func() {
+		conceptRepo.Close()
+		chatRepo.Close()
+		backend.Close()
+	}()
+
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	concept := &core.Concept{Name: "pet", Type: "animal", InsertedAt: now, UpdatedAt: now}
+	concept.Id = core.IDFromContent(concept.Tuple())
+	_, err = conceptRepo.AddConcepts(ctx, concept)
+	require.NoError(t, err)
+
+	added, err := chatRepo.AddChatRecords(ctx,
+		&core.ChatRecord{
+			Speaker:   core.SpeakerTypeHuman,
+			Contents:  "The weather was awful all week. Our puppy finally learned to sit. Dinner was late.",
+			Timestamp: now,
+			Vector:    []float32{1.0, 0.0, 0.0},
+			Concepts:  []core.ConceptRef{{ConceptId: concept.Id, Importance: 6}},
+		},
+		&core.ChatRecord{
+			Speaker:   core.SpeakerTypeHuman,
+			Contents:  "I want a dog someday, maybe a beagle.",
+			Timestamp: now,
+			Vector:    []float32{0.9, 0.1, 0.0},
+		},
+	)
+	require.NoError(t, err)
+
+	sentenceVectors := map[string][]float32{
+		"The weather was awful all week.":   {0.0, 1.0, 0.0},
+		"Our puppy finally learned to sit.": {1.0, 0.0, 0.0},
+		"Dinner was late.":                  {0.0, 0.0, 1.0},
+	}
+	mockEmbedder := mock.NewMockEmbedder()
+	mockEmbedder.EmbedTextFunc = func(ctx context.Context, text string) ([]float32, error) {
+		return []float32{1.0, 0.0, 0.0}, nil
+	}
+	mockEmbedder.EmbedTextsFunc = func(ctx context.Context, texts []string) ([][]float32, error) {
+		vectors := make([][]float32, len(texts))
+		for i, text := range texts {
+			vectors[i] = sentenceVectors[text]
+		}
+		return vectors, nil
+	}
+	mockExtractor := mock.NewMockConceptExtractor()
+	mockExtractor.ExtractConceptsFunc = func(ctx context.Context, text string) ([]ai.ExtractedConcept, error) {
+		return []ai.ExtractedConcept{{Name: "pet", Type: "animal", Importance: 8}}, nil
+	}
+	provider := mock.NewMockProviderWithServices(mockEmbedder, mockExtractor)
+
+	_, err = NewSearcher(chatRepo, conceptRepo, provider, WithSnippets(0))
+	assert.ErrorIs(t, err, ErrInvalidSnippetLength)
+
+	t.Run("disabled by default", func(t *testing.T) {
+		searcher, err := NewSearcher(chatRepo, conceptRepo, provider)
+		require.NoError(t, err)
+		response, err := searcher.Search(ctx, &SearchRequest{Query: "dog training", MaxHits: 10})
+		require.NoError(t, err)
+		require.NotEmpty(t, response.Results)
+		for _, result := range response.Results {
+			assert.Nil(t, result.Snippet)
+		}
+	})
+
+	searcher, err := NewSearcher(chatRepo, conceptRepo, provider, WithSnippets(DefaultSnippetLength))
+	require.NoError(t, err)
+	response, err := searcher.Search(ctx, &SearchRequest{Query: "dog training", MaxHits: 10})
+	require.NoError(t, err)
+	require.Len(t, response.Results, 2)
+	snippets := make(map[core.ID]*core.Snippet)
+	for _, result := range response.Results {
+		require.NotNil(t, result.Snippet)
+		snippets[result.Record.Id] = result.Snippet
+	}
+
+	t.Run("semantic hit gets the most similar sentence", func(t *testing.T) {
+		snippet := snippets[added[0].Id]
+		assert.Equal(t, core.SnippetSemantic, snippet.Kind)
+		assert.Equal(t, "Our puppy finally learned to sit.", snippet.Text(added[0].Contents))
+		assert.Empty(t, snippet.Highlights)
+	})
+
+	t.Run("concept hit lists matched concepts", func(t *testing.T) {
+		assert.Equal(t, []string{"pet"}, snippets[added[0].Id].Concepts)
+		assert.Empty(t, snippets[added[1].Id].Concepts)
+	})
+
+	t.Run("keyword match is highlighted", func(t *testing.T) {
+		snippet := snippets[added[1].Id]
+		assert.Equal(t, core.SnippetKeyword, snippet.Kind)
+		assert.Equal(t, "I want a <b>dog</b> someday, maybe a beagle.", snippet.Highlight(added[1].Contents, "<b>", "</b>"))
+	})
+
+	t.Run("sentence embedding failure keeps leading passage", func(t *testing.T) {
+		mockEmbedder.EmbedTextsFunc = func(ctx context.Context, texts []string) ([][]float32, error) {
+			return nil, assert.AnError
+		}
+		response, err := searcher.Search(ctx, &SearchRequest{Query: "dog training", MaxHits: 10})
+		require.NoError(t, err)
+		for _, result := range response.Results {
+			if result.Record.Id == added[0].Id {
+				assert.Equal(t, core.SnippetLeading, result.Snippet.Kind)
+				assert.Equal(t, added[0].Contents, result.Snippet.Text(result.Record.Contents))
+			}
+		}
+	})
+}
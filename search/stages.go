@@ -0,0 +1,291 @@
+// Copyright 2025 Poiesic Systems
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+
+package search
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/poiesic/memorit/ai"
+	"github.com/poiesic/memorit/core"
+	"github.com/poiesic/memorit/storage"
+)
+
+// Stage names a search stage that can be skipped when it fails.
+type Stage string
+
+const (
+	// StageSemantic embeds the query and searches record vectors.
+	StageSemantic Stage = "semantic"
+	// StageConceptual extracts query concepts and finds the records that mention them.
+	StageConceptual Stage = "conceptual"
+	// StageRerank rescores the top candidates with the searcher's Reranker.
+	StageRerank Stage = "rerank"
+)
+
+// SkippedStage records a stage that failed and was left out of a search.
+type SkippedStage struct {
+	Stage Stage
+	Err   error
+}
+
+// semanticHits are the records returned by vector search.
+type semanticHits struct {
+	set    map[uint64]bool
+	scores map[uint64]float32
+	vector []float32 // The vector searched for; nil if the stage was skipped
+}
+
+// conceptHits are the records that mention a concept related to the query.
+type conceptHits struct {
+	set     map[uint64]bool
+	matches map[uint64][]*core.ConceptSearchResult
+}
+
+// retrieve runs the semantic and conceptual retrieval stages concurrently,
+// each under its own timeout. If one stage fails, the search continues with
+// the other's hits and the failure is reported as skipped. A strict searcher
+// instead cancels the other stage and returns the first error. retrieve
+// always fails if both stages fail or ctx is done.
+func (s *Searcher) retrieve(ctx context.Context, req *SearchRequest, limit int, monitor SearchMonitor) (*semanticHits, *conceptHits, []SkippedStage, error) {
+	stageCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg          sync.WaitGroup
+		once        sync.Once
+		firstErr    error
+		semantic    *semanticHits
+		conceptual  *conceptHits
+		semanticErr error
+		conceptErr  error
+	)
+	fail := func(err error) {
+		once.Do(func() {
+			firstErr = err
+			if s.strictStages {
+				cancel()
+			}
+		})
+	}
+
+	wg.Go(func() {
+		ctx, cancel := withStageTimeout(stageCtx, s.semanticTimeout)
+		defer cancel()
+		if semantic, semanticErr = s.retrieveSemantic(ctx, req, limit, monitor); semanticErr != nil {
+			fail(semanticErr)
+		}
+	})
+	wg.Go(func() {
+		ctx, cancel := withStageTimeout(stageCtx, s.conceptTimeout)
+		defer cancel()
+		if conceptual, conceptErr = s.retrieveConceptual(ctx, req, monitor); conceptErr != nil {
+			fail(conceptErr)
+		}
+	})
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return nil, nil, nil, err
+	}
+	if firstErr != nil && (s.strictStages || (semanticErr != nil && conceptErr != nil)) {
+		return nil, nil, nil, firstErr
+	}
+
+	var skipped []SkippedStage
+	if semanticErr != nil {
+		s.logger.Warn("semantic search failed, continuing with conceptual and keyword matches", "err", semanticErr)
+		skipped = append(skipped, SkippedStage{Stage: StageSemantic, Err: semanticErr})
+		semantic = &semanticHits{set: make(map[uint64]bool), scores: make(map[uint64]float32)}
+	}
+	if conceptErr != nil {
+		s.logger.Warn("conceptual search failed, continuing with semantic and keyword matches", "err", conceptErr)
+		skipped = append(skipped, SkippedStage{Stage: StageConceptual, Err: conceptErr})
+		conceptual = &conceptHits{set: make(map[uint64]bool), matches: make(map[uint64][]*core.ConceptSearchResult)}
+	}
+	return semantic, conceptual, skipped, nil
+}
+
+// retrieveSemantic embeds the query, unless the request carries a shared
+// analysis, and finds the most similar records. With time boosts, the most
+// similar records in their ranges are found too.
+func (s *Searcher) retrieveSemantic(ctx context.Context, req *SearchRequest, limit int, monitor SearchMonitor) (*semanticHits, error) {
+	embedding, err := s.embedQuery(ctx, req)
+	if err != nil {
+		s.logger.Error("error generating embedding for query", "query", req.Query, "err", err)
+		return nil, err
+	}
+	filters := []*storage.RecordFilter{&req.Filter}
+	if filter, ok := req.timeBoostFilter(); ok {
+		filters = append(filters, filter)
+	}
+	return s.searchVector(ctx, embedding, limit, monitor, filters...)
+}
+
+// embedQuery returns the query's embedding, from the shared analysis or
+// the query cache when available.
+func (s *Searcher) embedQuery(ctx context.Context, req *SearchRequest) ([]float32, error) {
+	if req.analysis != nil && req.analysis.query == req.Query {
+		return req.analysis.embedding, req.analysis.embedErr
+	}
+	if s.queryEmbeddings == nil {
+		return s.embedder.EmbedText(ctx, req.Query)
+	}
+	if embedding, ok := s.queryEmbeddings.get(req.Query); ok {
+		return embedding, nil
+	}
+	embedding, err := s.embedder.EmbedText(ctx, req.Query)
+	if err != nil {
+		return nil, err
+	}
+	s.queryEmbeddings.put(req.Query, embedding)
+	return embedding, nil
+}
+
+// extractQuery returns the concepts extracted from the query, from the
+// shared analysis or the query cache when available.
+func (s *Searcher) extractQuery(ctx context.Context, req *SearchRequest) ([]ai.ExtractedConcept, error) {
+	if req.analysis != nil && req.analysis.query == req.Query {
+		return slices.Clone(req.analysis.concepts), req.analysis.extractErr
+	}
+	if s.queryConcepts == nil {
+		return s.extractor.ExtractConcepts(ctx, req.Query)
+	}
+	if concepts, ok := s.queryConcepts.get(req.Query); ok {
+		return slices.Clone(concepts), nil
+	}
+	concepts, err := s.extractor.ExtractConcepts(ctx, req.Query)
+	if err != nil {
+		return nil, err
+	}
+	s.queryConcepts.put(req.Query, slices.Clone(concepts))
+	return concepts, nil
+}
+
+// searchVector finds up to limit records most similar to a vector that
+// match each filter, and merges them.
+func (s *Searcher) searchVector(ctx context.Context, vector []float32, limit int, monitor SearchMonitor, filters ...*storage.RecordFilter) (*semanticHits, error) {
+	hits := &semanticHits{
+		set:    make(map[uint64]bool, limit),
+		scores: make(map[uint64]float32, limit),
+		vector: vector,
+	}
+	ids := make([]uint64, 0, limit)
+	for _, filter := range filters {
+		matches, err := s.chatRepository.FindSimilarWithFilter(ctx, vector, s.minSimilarity, limit, filter)
+		if err != nil {
+			s.logger.Error("error querying for similar records", "err", err)
+			return nil, err
+		}
+		for _, match := range matches {
+			id := uint64(match.Record.Id)
+			if !hits.set[id] {
+				hits.set[id] = true
+				hits.scores[id] = match.Score
+				ids = append(ids, id)
+			}
+		}
+	}
+	monitor.AfterSemanticSearch(ids)
+	return hits, nil
+}
+
+// retrieveConceptual extracts concepts from the query, adds the required
+// concepts, resolves them against stored concepts, and finds the records that
+// mention them.
+func (s *Searcher) retrieveConceptual(ctx context.Context, req *SearchRequest, monitor SearchMonitor) (*conceptHits, error) {
+	extracted, err := s.extractQuery(ctx, req)
+	if err != nil {
+		s.logger.Error("error extracting concepts from query", "err", err)
+		return nil, err
+	}
+	for _, concept := range req.Concepts {
+		if !slices.ContainsFunc(extracted, func(ec ai.ExtractedConcept) bool {
+			return ec.Type == concept.Type && ec.Name == concept.Name
+		}) {
+			extracted = append(extracted, ai.ExtractedConcept{Name: concept.Name, Type: concept.Type})
+		}
+	}
+
+	// Resolve the extracted concepts against stored concepts
+	queryConcepts, err := s.resolveConcepts(ctx, extracted)
+	if err != nil {
+		return nil, err
+	}
+	return s.conceptRecords(ctx, queryConcepts, monitor)
+}
+
+// conceptRecords finds the records that mention any of the stored concepts
+// related to the query concepts.
+func (s *Searcher) conceptRecords(ctx context.Context, queryConcepts []queryConcept, monitor SearchMonitor) (*conceptHits, error) {
+	concepts := make([]*core.Concept, 0, len(queryConcepts))
+	similarities := make(map[core.ID]float32)
+	for _, qc := range queryConcepts {
+		for _, related := range qc.related {
+			best, seen := similarities[related.Concept.Id]
+			if !seen {
+				concepts = append(concepts, related.Concept)
+			}
+			if !seen || related.Score > best {
+				similarities[related.Concept.Id] = related.Score
+			}
+		}
+	}
+	monitor.AfterQueryConceptExtraction(concepts)
+
+	for _, qc := range queryConcepts {
+		ids := make([]uint64, len(qc.related))
+		for i, related := range qc.related {
+			ids[i] = uint64(related.Concept.Id)
+		}
+		monitor.FoundRelatedConcepts(qc.tuple, ids)
+	}
+
+	// Find messages via concept matching
+	hits := &conceptHits{
+		set:     make(map[uint64]bool),
+		matches: make(map[uint64][]*core.ConceptSearchResult),
+	}
+	for _, concept := range concepts {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		match := &core.ConceptSearchResult{Concept: concept, Score: similarities[concept.Id]}
+
+		// Get messages for this concept
+		recordIds, err := s.chatRepository.GetChatRecordsByConcept(ctx, concept.Id)
+		if err != nil {
+			s.logger.Warn("failed to get records for concept", "conceptID", concept.Id, "err", err)
+			continue
+		}
+		for _, recordId := range recordIds {
+			hits.set[uint64(recordId)] = true
+			hits.matches[uint64(recordId)] = append(hits.matches[uint64(recordId)], match)
+		}
+	}
+	return hits, nil
+}
+
+// withStageTimeout bounds a stage by timeout in addition to ctx's own deadline.
+// A zero timeout leaves ctx unchanged.
+func withStageTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, timeout)
+}
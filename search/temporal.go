@@ -0,0 +1,670 @@
+// Copyright 2025 Poiesic Systems
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+
+package search
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/poiesic/memorit/storage"
+)
+
+// TimeMode selects how a Searcher applies the time expressions in queries.
+type TimeMode int
+
+const (
+	// TimeModeIgnore searches time expressions as ordinary text. This is the default.
+	TimeModeIgnore TimeMode = iota
+	// TimeModeFilter removes time expressions from the query and restricts
+	// results to the time range they describe.
+	TimeModeFilter
+	// TimeModeBoost removes time expressions from the query and multiplies the
+	// relevance of results in the time ranges they describe.
+	TimeModeBoost
+)
+
+// DefaultTimeBoost is the default relevance multiplier for results in the
+// time range of a query in TimeModeBoost mode.
+const DefaultTimeBoost float32 = 2
+
+// String returns the name of the time mode.
+func (m TimeMode) String() string {
+	switch m {
+	case TimeModeIgnore:
+		return "ignore"
+	case TimeModeFilter:
+		return "filter"
+	case TimeModeBoost:
+		return "boost"
+	default:
+		return "unknown"
+	}
+}
+
+// ParseTimeMode returns the time mode with the given name.
+func ParseTimeMode(name string) (TimeMode, error) {
+	switch name {
+	case "ignore", "":
+		return TimeModeIgnore, nil
+	case "filter":
+		return TimeModeFilter, nil
+	case "boost":
+		return TimeModeBoost, nil
+	default:
+		return TimeModeIgnore, fmt.Errorf("%w %q", ErrInvalidTimeMode, name)
+	}
+}
+
+// TimeExpression is a date or time range recognized in a query.
+type TimeExpression struct {
+	// Text is the expression as written, including a leading preposition
+	// such as "on" or "since".
+	Text string
+	// Start is the inclusive start of the range; zero if unbounded.
+	Start time.Time
+	// End is the exclusive end of the range; zero if unbounded.
+	End time.Time
+}
+
+// TimeBoost multiplies the relevance of results with timestamps in [Start, End).
+// A zero Start or End is unbounded.
+type TimeBoost struct {
+	Start  time.Time
+	End    time.Time
+	Factor float32
+}
+
+// contains reports whether t falls in the boost's range.
+func (b TimeBoost) contains(t time.Time) bool {
+	return (b.Start.IsZero() || !t.Before(b.Start)) && (b.End.IsZero() || t.Before(b.End))
+}
+
+// timeRange is a resolved time range; a zero bound is unbounded.
+type timeRange struct {
+	start, end time.Time
+}
+
+// timeUnit is a calendar unit in expressions like "3 weeks ago".
+type timeUnit int
+
+const (
+	unitDay timeUnit = iota
+	unitWeek
+	unitMonth
+	unitYear
+)
+
+var weekdays = map[string]time.Weekday{
+	"sunday": time.Sunday, "monday": time.Monday, "tuesday": time.Tuesday,
+	"wednesday": time.Wednesday, "thursday": time.Thursday, "friday": time.Friday,
+	"saturday": time.Saturday,
+}
+
+var months = map[string]time.Month{
+	"january": time.January, "february": time.February, "march": time.March,
+	"april": time.April, "may": time.May, "june": time.June, "july": time.July,
+	"august": time.August, "september": time.September, "october": time.October,
+	"november": time.November, "december": time.December,
+}
+
+// monthAbbreviations are only recognized next to a day or year, as in "Mar 5".
+var monthAbbreviations = map[string]time.Month{
+	"jan": time.January, "feb": time.February, "mar": time.March, "apr": time.April,
+	"jun": time.June, "jul": time.July, "aug": time.August, "sep": time.September,
+	"sept": time.September, "oct": time.October, "nov": time.November, "dec": time.December,
+}
+
+var countWords = map[string]int{
+	"a": 1, "an": 1, "one": 1, "two": 2, "three": 3, "four": 4, "five": 5, "six": 6,
+	"seven": 7, "eight": 8, "nine": 9, "ten": 10, "eleven": 11, "twelve": 12,
+}
+
+// dayParts are the hours of the day parts in "this morning" or "yesterday evening".
+// Night runs past midnight into the next day.
+var dayParts = map[string][2]int{
+	"morning": {0, 12}, "afternoon": {12, 18}, "evening": {18, 24}, "night": {18, 30},
+}
+
+// ParseTimeExpressions finds the time expressions in query, resolving
+// relative ones against reference in its location, and returns the query
+// with them removed. Recognized expressions include:
+//
+//	today, yesterday, tonight, the day before yesterday
+//	this morning, yesterday evening, last night
+//	monday, last friday, this weekend, last weekend
+//	this week, last month, past year, the last 3 days
+//	3 days ago, a week ago, two months ago
+//	march 5, 5th of march, mar 5 2024, 2024-03-05
+//	in march, last april, march 2024, in 2023
+//	between march and june
+//
+// Expressions may follow on, in, during, from, over or earlier, which are
+// removed with them, or since, after, before, until or till, which make the
+// range open-ended. Bare month names and years are only recognized after
+// one of those words, so "the march release" is left alone.
+// Weeks start on Monday.
+func ParseTimeExpressions(query string, reference time.Time) (string, []TimeExpression) {
+	fields := strings.Fields(query)
+	words := make([]string, len(fields))
+	for i, field := range fields {
+		words[i] = strings.ToLower(strings.Trim(field, wordPunctuation))
+	}
+
+	var kept []string
+	var expressions []TimeExpression
+	for i := 0; i < len(fields); {
+		r, n := matchTimeExpression(words[i:], reference)
+		if n == 0 {
+			kept = append(kept, fields[i])
+			i++
+			continue
+		}
+		expressions = append(expressions, TimeExpression{
+			Text:  strings.Trim(strings.Join(fields[i:i+n], " "), wordPunctuation),
+			Start: r.start,
+			End:   r.end,
+		})
+		i += n
+	}
+	return strings.Join(kept, " "), expressions
+}
+
+// matchTimeExpression matches a time expression, with any leading
+// preposition, at the start of words. n is the number of words matched, or
+// zero if there is no expression.
+func matchTimeExpression(words []string, ref time.Time) (r timeRange, n int) {
+	switch word(words, 0) {
+	case "on", "in", "during", "from", "over", "earlier":
+		if r, n := matchTimeRange(words[1:], ref, words[0]); n > 0 {
+			return r, n + 1
+		}
+		return timeRange{}, 0
+	case "since", "after", "before", "until", "till":
+		r, n := matchTimeRange(words[1:], ref, words[0])
+		if n == 0 {
+			return timeRange{}, 0
+		}
+		switch words[0] {
+		case "since":
+			return timeRange{start: r.start}, n + 1
+		case "after":
+			return timeRange{start: r.end}, n + 1
+		case "before":
+			return timeRange{end: r.start}, n + 1
+		default:
+			return timeRange{end: r.end}, n + 1
+		}
+	case "between":
+		first, n := matchTimeRange(words[1:], ref, words[0])
+		if n == 0 || word(words, n+1) != "and" {
+			return timeRange{}, 0
+		}
+		last, m := matchTimeRange(words[n+2:], ref, words[0])
+		if m == 0 {
+			return timeRange{}, 0
+		}
+		return timeRange{start: first.start, end: last.end}, n + m + 2
+	}
+	return matchTimeRange(words, ref, "")
+}
+
+// matchTimeRange matches a date or time range at the start of words.
+// qualifier is the preposition before it, if any.
+func matchTimeRange(words []string, ref time.Time, qualifier string) (timeRange, int) {
+	w0, w1 := word(words, 0), word(words, 1)
+	today := startOfDay(ref)
+
+	switch w0 {
+	case "today":
+		return dayRange(today), 1
+	case "tonight":
+		return dayPart(today, "night"), 1
+	case "yesterday":
+		yesterday := today.AddDate(0, 0, -1)
+		if _, ok := dayParts[w1]; ok {
+			return dayPart(yesterday, w1), 2
+		}
+		return dayRange(yesterday), 1
+	case "the":
+		switch {
+		case w1 == "weekend":
+			return weekendRange(today, false), 2
+		case w1 == "day" && word(words, 2) == "before" && word(words, 3) == "yesterday":
+			return dayRange(today.AddDate(0, 0, -2)), 4
+		case w1 == "past" || w1 == "last":
+			if r, n := matchTimeRange(words[1:], ref, qualifier); n > 0 {
+				return r, n + 1
+			}
+		default:
+			// "the 5th of march"
+			if r, n := matchDate(words[1:], ref, today); n > 0 {
+				return r, n + 1
+			}
+		}
+		return timeRange{}, 0
+	case "this":
+		if _, ok := dayParts[w1]; ok {
+			return dayPart(today, w1), 2
+		}
+		switch w1 {
+		case "week":
+			start := startOfWeek(today)
+			return timeRange{start, start.AddDate(0, 0, 7)}, 2
+		case "month":
+			return monthRange(ref.Year(), ref.Month(), ref.Location()), 2
+		case "year":
+			return yearRange(ref.Year(), ref.Location()), 2
+		case "weekend":
+			return weekendRange(today, false), 2
+		}
+		if weekday, ok := weekdays[w1]; ok {
+			return dayRange(previousWeekday(today, weekday, false)), 2
+		}
+		// "this may" is rarely about the month
+		if month, ok := months[w1]; ok && month != time.May {
+			return monthRange(ref.Year(), month, ref.Location()), 2
+		}
+		return timeRange{}, 0
+	case "last", "past":
+		return matchLast(words, ref, today)
+	}
+
+	if weekday, ok := weekdays[w0]; ok {
+		return dayRange(previousWeekday(today, weekday, false)), 1
+	}
+	if r, n := matchAgo(words, today); n > 0 {
+		return r, n
+	}
+	if r, n := matchDate(words, ref, today); n > 0 {
+		return r, n
+	}
+	if month, ok := months[w0]; ok && qualifier != "" {
+		year := ref.Year()
+		if month > ref.Month() {
+			year--
+		}
+		return monthRange(year, month, ref.Location()), 1
+	}
+	if year, ok := parseYear(w0); ok && qualifier != "" {
+		return yearRange(year, ref.Location()), 1
+	}
+	return timeRange{}, 0
+}
+
+// matchLast matches expressions starting with "last" or "past". Calendar
+// units after "last" are the previous week, month or year; after "past", or
+// with a count as in "last 3 days", they are rolling periods up to now.
+func matchLast(words []string, ref, today time.Time) (timeRange, int) {
+	rolling := words[0] == "past"
+	w1 := word(words, 1)
+
+	if w1 == "night" {
+		return dayPart(today.AddDate(0, 0, -1), "night"), 2
+	}
+	if w1 == "weekend" {
+		return weekendRange(today, true), 2
+	}
+	if weekday, ok := weekdays[w1]; ok {
+		return dayRange(previousWeekday(today, weekday, true)), 2
+	}
+	if month, ok := months[w1]; ok {
+		year := ref.Year()
+		if month >= ref.Month() {
+			year--
+		}
+		return monthRange(year, month, ref.Location()), 2
+	}
+	if unit, ok := parseUnit(w1); ok {
+		if rolling {
+			return timeRange{start: subtractUnits(ref, unit, 1)}, 2
+		}
+		return calendarRange(today, unit, 1), 2
+	}
+	// "last 3 days" counts back from now
+	if count, ok := parseCount(w1); ok {
+		if unit, ok := parseUnit(word(words, 2)); ok {
+			return timeRange{start: subtractUnits(ref, unit, count)}, 3
+		}
+	}
+	return timeRange{}, 0
+}
+
+// matchAgo matches "N units ago", as in "3 days ago" or "a week ago".
+// The result is the calendar day, week, month or year N units back.
+func matchAgo(words []string, today time.Time) (timeRange, int) {
+	count, ok := parseCount(word(words, 0))
+	if !ok {
+		return timeRange{}, 0
+	}
+	unit, ok := parseUnit(word(words, 1))
+	if !ok || word(words, 2) != "ago" {
+		return timeRange{}, 0
+	}
+	return calendarRange(today, unit, count), 3
+}
+
+// matchDate matches absolute dates: ISO dates and months, a month with a
+// day and/or year in either order, as in "march 5", "5th of march, 2024" or
+// "mar 2024". Dates without a year are the most recent occurrence.
+func matchDate(words []string, ref, today time.Time) (timeRange, int) {
+	loc := ref.Location()
+	w0 := word(words, 0)
+	if t, err := time.ParseInLocation(time.DateOnly, w0, loc); err == nil {
+		return dayRange(t), 1
+	}
+	if t, err := time.ParseInLocation("2006-01", w0, loc); err == nil {
+		return monthRange(t.Year(), t.Month(), loc), 1
+	}
+
+	// Day first: "5 march", "5th of march"
+	if day, ok := parseDay(w0); ok {
+		n := 1
+		if word(words, n) == "of" {
+			n++
+		}
+		month, ok := parseMonth(word(words, n))
+		// "2 may" is usually a verb, but "2nd may" and "2 of may" are dates
+		if ok && (month != time.May || n == 2 || w0 != strconv.Itoa(day)) {
+			return resolveDate(words, n+1, month, day, today)
+		}
+		return timeRange{}, 0
+	}
+
+	// Month first: "march 5", "march 5, 2024", "march 2024"
+	month, ok := parseMonth(w0)
+	if !ok {
+		return timeRange{}, 0
+	}
+	if day, ok := parseDay(word(words, 1)); ok {
+		if _, hasYear := parseYear(word(words, 2)); month != time.May || hasYear || word(words, 1) != strconv.Itoa(day) {
+			return resolveDate(words, 2, month, day, today)
+		}
+		return timeRange{}, 0
+	}
+	if year, ok := parseYear(word(words, 1)); ok {
+		return monthRange(year, month, loc), 2
+	}
+	return timeRange{}, 0
+}
+
+// resolveDate returns the range of a month and day, followed by an optional
+// year at words[n]. Without a year, the most recent such date is used.
+func resolveDate(words []string, n int, month time.Month, day int, today time.Time) (timeRange, int) {
+	year, hasYear := parseYear(word(words, n))
+	if !hasYear {
+		year = today.Year()
+		if month > today.Month() || (month == today.Month() && day > today.Day()) {
+			year--
+		}
+	}
+	date := time.Date(year, month, day, 0, 0, 0, 0, today.Location())
+	if date.Day() != day {
+		// No such day in the month, such as February 30
+		return timeRange{}, 0
+	}
+	if hasYear {
+		return dayRange(date), n + 1
+	}
+	return dayRange(date), n
+}
+
+// word returns words[i], or "" past the end.
+func word(words []string, i int) string {
+	if i < len(words) {
+		return words[i]
+	}
+	return ""
+}
+
+// parseCount parses a small count written as digits or a word.
+func parseCount(w string) (int, bool) {
+	if count, ok := countWords[w]; ok {
+		return count, true
+	}
+	count, err := strconv.Atoi(w)
+	if err != nil || count <= 0 || count > 1000 {
+		return 0, false
+	}
+	return count, true
+}
+
+// parseUnit parses a singular or plural calendar unit.
+func parseUnit(w string) (timeUnit, bool) {
+	switch strings.TrimSuffix(w, "s") {
+	case "day":
+		return unitDay, true
+	case "week":
+		return unitWeek, true
+	case "month":
+		return unitMonth, true
+	case "year":
+		return unitYear, true
+	default:
+		return 0, false
+	}
+}
+
+// parseDay parses a day of the month, with an optional ordinal suffix.
+func parseDay(w string) (int, bool) {
+	for _, suffix := range []string{"st", "nd", "rd", "th"} {
+		w = strings.TrimSuffix(w, suffix)
+	}
+	day, err := strconv.Atoi(w)
+	if err != nil || day < 1 || day > 31 {
+		return 0, false
+	}
+	return day, true
+}
+
+// parseMonth parses a month name or abbreviation.
+func parseMonth(w string) (time.Month, bool) {
+	if month, ok := months[w]; ok {
+		return month, true
+	}
+	month, ok := monthAbbreviations[w]
+	return month, ok
+}
+
+// parseYear parses a four-digit year.
+func parseYear(w string) (int, bool) {
+	if len(w) != 4 {
+		return 0, false
+	}
+	year, err := strconv.Atoi(w)
+	if err != nil || year < 1900 || year > 2999 {
+		return 0, false
+	}
+	return year, true
+}
+
+// startOfDay returns midnight at the start of t's day, in t's location.
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// startOfWeek returns the Monday on or before day.
+func startOfWeek(day time.Time) time.Time {
+	return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+}
+
+// previousWeekday returns the most recent weekday on or before today, or
+// strictly before today if exclusive is set.
+func previousWeekday(today time.Time, weekday time.Weekday, exclusive bool) time.Time {
+	days := (int(today.Weekday()) - int(weekday) + 7) % 7
+	if days == 0 && exclusive {
+		days = 7
+	}
+	return today.AddDate(0, 0, -days)
+}
+
+func dayRange(day time.Time) timeRange {
+	return timeRange{day, day.AddDate(0, 0, 1)}
+}
+
+func dayPart(day time.Time, part string) timeRange {
+	hours := dayParts[part]
+	return timeRange{
+		day.Add(time.Duration(hours[0]) * time.Hour),
+		day.Add(time.Duration(hours[1]) * time.Hour),
+	}
+}
+
+func monthRange(year int, month time.Month, loc *time.Location) timeRange {
+	start := time.Date(year, month, 1, 0, 0, 0, 0, loc)
+	return timeRange{start, start.AddDate(0, 1, 0)}
+}
+
+func yearRange(year int, loc *time.Location) timeRange {
+	start := time.Date(year, time.January, 1, 0, 0, 0, 0, loc)
+	return timeRange{start, start.AddDate(1, 0, 0)}
+}
+
+// weekendRange returns the most recent weekend that has started by today,
+// or the one before it if previous is set, from Saturday to Monday.
+func weekendRange(today time.Time, previous bool) timeRange {
+	saturday := previousWeekday(today, time.Saturday, false)
+	if previous && (today.Weekday() == time.Saturday || today.Weekday() == time.Sunday) {
+		saturday = saturday.AddDate(0, 0, -7)
+	}
+	return timeRange{saturday, saturday.AddDate(0, 0, 2)}
+}
+
+// calendarRange returns the calendar day, week, month or year count units
+// before the one containing today.
+func calendarRange(today time.Time, unit timeUnit, count int) timeRange {
+	switch unit {
+	case unitWeek:
+		start := startOfWeek(today).AddDate(0, 0, -7*count)
+		return timeRange{start, start.AddDate(0, 0, 7)}
+	case unitMonth:
+		first := time.Date(today.Year(), today.Month(), 1, 0, 0, 0, 0, today.Location()).AddDate(0, -count, 0)
+		return monthRange(first.Year(), first.Month(), today.Location())
+	case unitYear:
+		return yearRange(today.Year()-count, today.Location())
+	default:
+		return dayRange(today.AddDate(0, 0, -count))
+	}
+}
+
+// subtractUnits returns t moved back count units.
+func subtractUnits(t time.Time, unit timeUnit, count int) time.Time {
+	switch unit {
+	case unitWeek:
+		return t.AddDate(0, 0, -7*count)
+	case unitMonth:
+		return t.AddDate(0, -count, 0)
+	case unitYear:
+		return t.AddDate(-count, 0, 0)
+	default:
+		return t.AddDate(0, 0, -count)
+	}
+}
+
+// timeBounds combines the ranges of expressions into one range. Bounded
+// ranges are merged into the span covering all of them, which open-ended
+// ranges, as from "since" or "before", then narrow.
+func timeBounds(expressions []TimeExpression) (start, end time.Time) {
+	bounded := false
+	for _, e := range expressions {
+		if e.Start.IsZero() || e.End.IsZero() {
+			continue
+		}
+		if !bounded || e.Start.Before(start) {
+			start = e.Start
+		}
+		if !bounded || e.End.After(end) {
+			end = e.End
+		}
+		bounded = true
+	}
+	for _, e := range expressions {
+		if !e.Start.IsZero() && !e.End.IsZero() {
+			continue
+		}
+		start, end = narrowRange(start, end, e.Start, e.End)
+	}
+	return start, end
+}
+
+// narrowRange returns the intersection of [start, end) and [lo, hi), where
+// zero bounds are unbounded.
+func narrowRange(start, end, lo, hi time.Time) (time.Time, time.Time) {
+	if !lo.IsZero() && lo.After(start) {
+		start = lo
+	}
+	if !hi.IsZero() && (end.IsZero() || hi.Before(end)) {
+		end = hi
+	}
+	return start, end
+}
+
+// resolveTimeExpressions applies the time expressions in req's query
+// according to the searcher's time mode. It returns a copy of req with the
+// expressions removed from Query and turned into a filter or time boosts,
+// or req itself if there are none. A query made only of time expressions is
+// kept as is so there is still text to search for.
+func (s *Searcher) resolveTimeExpressions(req *SearchRequest) (*SearchRequest, []TimeExpression) {
+	if s.timeMode == TimeModeIgnore {
+		return req, nil
+	}
+	reference := req.ReferenceTime
+	if reference.IsZero() {
+		reference = time.Now()
+	}
+	query, expressions := ParseTimeExpressions(req.Query, reference)
+	if len(expressions) == 0 {
+		return req, nil
+	}
+
+	resolved := *req
+	if strings.TrimSpace(query) != "" {
+		resolved.Query = query
+	}
+	switch s.timeMode {
+	case TimeModeFilter:
+		start, end := timeBounds(expressions)
+		resolved.Filter.Start, resolved.Filter.End = narrowRange(req.Filter.Start, req.Filter.End, start, end)
+	case TimeModeBoost:
+		resolved.TimeBoosts = slices.Clone(req.TimeBoosts)
+		for _, e := range expressions {
+			resolved.TimeBoosts = append(resolved.TimeBoosts, TimeBoost{Start: e.Start, End: e.End, Factor: s.timeBoost})
+		}
+	}
+	return &resolved, expressions
+}
+
+// timeBoostFilter returns the request's filter narrowed to the span of its
+// time boosts, so records in range can be retrieved through the date index.
+// ok is false if there are no boosts or they are unbounded.
+func (r *SearchRequest) timeBoostFilter() (filter *storage.RecordFilter, ok bool) {
+	if len(r.TimeBoosts) == 0 {
+		return nil, false
+	}
+	expressions := make([]TimeExpression, len(r.TimeBoosts))
+	for i, boost := range r.TimeBoosts {
+		expressions[i] = TimeExpression{Start: boost.Start, End: boost.End}
+	}
+	start, end := timeBounds(expressions)
+	if start.IsZero() && end.IsZero() {
+		return nil, false
+	}
+	narrowed := r.Filter
+	narrowed.Start, narrowed.End = narrowRange(r.Filter.Start, r.Filter.End, start, end)
+	return &narrowed, true
+}
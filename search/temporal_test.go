@@ -0,0 +1,269 @@
+package search
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/poiesic/memorit/ai"
+	"github.com/poiesic/memorit/ai/mock"
+	"github.com/poiesic/memorit/core"
+	"github.com/poiesic/memorit/storage/badger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTimeExpressions(t *testing.T) {
+	// A Wednesday afternoon
+	ref := time.Date(2025, time.October, 15, 14, 30, 0, 0, time.UTC)
+	day := func(month time.Month, d, year int) time.Time {
+		return time.Date(year, month, d, 0, 0, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		query     string
+		remaining string
+		text      string
+		start     time.Time
+		end       time.Time
+	}{
+		{"what did we decide about pricing last Tuesday?", "what did we decide about pricing", "last Tuesday", day(10, 14, 2025), day(10, 15, 2025)},
+		{"standup notes today", "standup notes", "today", day(10, 15, 2025), day(10, 16, 2025)},
+		{"notes from yesterday evening", "notes", "from yesterday evening", day(10, 14, 2025).Add(18 * time.Hour), day(10, 15, 2025)},
+		{"the day before yesterday", "", "the day before yesterday", day(10, 13, 2025), day(10, 14, 2025)},
+		{"what did I say last night", "what did I say", "last night", day(10, 14, 2025).Add(18 * time.Hour), day(10, 15, 2025).Add(6 * time.Hour)},
+		{"what came up this morning", "what came up", "this morning", day(10, 15, 2025), day(10, 15, 2025).Add(12 * time.Hour)},
+		{"plans on Wednesday", "plans", "on Wednesday", day(10, 15, 2025), day(10, 16, 2025)},
+		{"plans last wednesday", "plans", "last wednesday", day(10, 8, 2025), day(10, 9, 2025)},
+		{"friday deploy", "deploy", "friday", day(10, 10, 2025), day(10, 11, 2025)},
+		{"what happened over the weekend", "what happened", "over the weekend", day(10, 11, 2025), day(10, 13, 2025)},
+		{"this week", "", "this week", day(10, 13, 2025), day(10, 20, 2025)},
+		{"last week", "", "last week", day(10, 6, 2025), day(10, 13, 2025)},
+		{"last month", "", "last month", day(9, 1, 2025), day(10, 1, 2025)},
+		{"this year", "", "this year", day(1, 1, 2025), day(1, 1, 2026)},
+		{"last year", "", "last year", day(1, 1, 2024), day(1, 1, 2025)},
+		{"the past week", "", "the past week", ref.AddDate(0, 0, -7), time.Time{}},
+		{"bugs in the last 3 days", "bugs", "in the last 3 days", ref.AddDate(0, 0, -3), time.Time{}},
+		{"3 days ago", "", "3 days ago", day(10, 12, 2025), day(10, 13, 2025)},
+		{"a week ago", "", "a week ago", day(10, 6, 2025), day(10, 13, 2025)},
+		{"two months ago", "", "two months ago", day(8, 1, 2025), day(9, 1, 2025)},
+		{"pricing in March", "pricing", "in March", day(3, 1, 2025), day(4, 1, 2025)},
+		{"pricing in November", "pricing", "in November", day(11, 1, 2024), day(12, 1, 2024)},
+		{"last October", "", "last October", day(10, 1, 2024), day(11, 1, 2024)},
+		{"since March", "", "since March", day(3, 1, 2025), time.Time{}},
+		{"before June 2024", "", "before June 2024", time.Time{}, day(6, 1, 2024)},
+		{"after March 5", "", "after March 5", day(3, 6, 2025), time.Time{}},
+		{"until Monday", "", "until Monday", time.Time{}, day(10, 14, 2025)},
+		{"March 5, what was said", "what was said", "March 5", day(3, 5, 2025), day(3, 6, 2025)},
+		{"on the 5th of March 2023", "", "on the 5th of March 2023", day(3, 5, 2023), day(3, 6, 2023)},
+		{"Dec 25", "", "Dec 25", day(12, 25, 2024), day(12, 26, 2024)},
+		{"on 2024-02-29", "", "on 2024-02-29", day(2, 29, 2024), day(3, 1, 2024)},
+		{"in 2024-02", "", "in 2024-02", day(2, 1, 2024), day(3, 1, 2024)},
+		{"during 2023", "", "during 2023", day(1, 1, 2023), day(1, 1, 2024)},
+		{"between March and June", "", "between March and June", day(3, 1, 2025), day(7, 1, 2025)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			remaining, expressions := ParseTimeExpressions(tt.query, ref)
+			assert.Equal(t, tt.remaining, remaining)
+			require.Len(t, expressions, 1)
+			assert.Equal(t, tt.text, expressions[0].Text)
+			assert.True(t, tt.start.Equal(expressions[0].Start), "start %v, want %v", expressions[0].Start, tt.start)
+			assert.True(t, tt.end.Equal(expressions[0].End), "end %v, want %v", expressions[0].End, tt.end)
+		})
+	}
+
+	// Words that only look like dates are left alone
+	for _, query := range []string{
+		"the march release",
+		"this may be wrong",
+		"they may 2 times",
+		"budget for 2023",
+		"ship it in 3 days",
+		"the last time we met",
+		"since we talked",
+		"february 30",
+	} {
+		remaining, expressions := ParseTimeExpressions(query, ref)
+		assert.Equal(t, query, remaining)
+		assert.Empty(t, expressions, query)
+	}
+
+	// Several expressions in one query
+	remaining, expressions := ParseTimeExpressions("kubernetes yesterday and today", ref)
+	assert.Equal(t, "kubernetes and", remaining)
+	assert.Len(t, expressions, 2)
+
+	// Days follow the reference time's location
+	eastern := time.FixedZone("EST", -5*60*60)
+	_, expressions = ParseTimeExpressions("today", time.Date(2025, time.October, 16, 2, 0, 0, 0, time.UTC).In(eastern))
+	require.Len(t, expressions, 1)
+	assert.True(t, time.Date(2025, time.October, 15, 5, 0, 0, 0, time.UTC).Equal(expressions[0].Start))
+}
+
+func TestParseTimeExpressions_Weekend(t *testing.T) {
+	saturday := time.Date(2025, time.October, 18, 10, 0, 0, 0, time.UTC)
+	_, expressions := ParseTimeExpressions("this weekend", saturday)
+	require.Len(t, expressions, 1)
+	assert.Equal(t, 18, expressions[0].Start.Day())
+
+	_, expressions = ParseTimeExpressions("last weekend", saturday)
+	require.Len(t, expressions, 1)
+	assert.Equal(t, 11, expressions[0].Start.Day())
+	assert.Equal(t, 13, expressions[0].End.Day())
+}
+
+func TestTimeBounds(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2025, time.March, d, 0, 0, 0, 0, time.UTC) }
+
+	// Bounded ranges are merged
+	start, end := timeBounds([]TimeExpression{{Start: day(3), End: day(4)}, {Start: day(1), End: day(2)}})
+	assert.Equal(t, day(1), start)
+	assert.Equal(t, day(4), end)
+
+	// Open-ended ranges narrow them
+	start, end = timeBounds([]TimeExpression{{Start: day(1), End: day(10)}, {Start: day(5)}})
+	assert.Equal(t, day(5), start)
+	assert.Equal(t, day(10), end)
+	start, end = timeBounds([]TimeExpression{{Start: day(1)}, {End: day(7)}})
+	assert.Equal(t, day(1), start)
+	assert.Equal(t, day(7), end)
+}
+
+func TestParseTimeMode(t *testing.T) {
+	for _, mode := range []TimeMode{TimeModeIgnore, TimeModeFilter, TimeModeBoost} {
+		parsed, err := ParseTimeMode(mode.String())
+		require.NoError(t, err)
+		assert.Equal(t, mode, parsed)
+	}
+	_, err := ParseTimeMode("sometimes")
+	assert.ErrorIs(t, err, ErrInvalidTimeMode)
+}
+
+func TestSearch_TimeExpressions(t *testing.T) {
+	chatRepo, conceptRepo, backend, err := badger.NewMemoryRepositories()
+	require.NoError(t, err)
+	defer func() {
+		conceptRepo.Close()
+		chatRepo.Close()
+		backend.Close()
+	}()
+
+	ctx := context.Background()
+	ref := time.Date(2025, time.October, 15, 14, 30, 0, 0, time.UTC)
+	added, err := chatRepo.AddChatRecords(ctx,
+		&core.ChatRecord{
+			Speaker:   core.SpeakerTypeHuman,
+			Contents:  "We agreed pricing stays at ten dollars",
+			Timestamp: ref.AddDate(0, -2, 0),
+			Vector:    []float32{1.0, 0.0, 0.0},
+		},
+		&core.ChatRecord{
+			Speaker:   core.SpeakerTypeHuman,
+			Contents:  "Pricing moves to twelve dollars next quarter",
+			Timestamp: ref.AddDate(0, 0, -1),
+			Vector:    []float32{0.8, 0.6, 0.0},
+		},
+	)
+	require.NoError(t, err)
+	older, recent := added[0], added[1]
+
+	var embedded []string
+	mockEmbedder := mock.NewMockEmbedder()
+	mockEmbedder.EmbedTextFunc = func(ctx context.Context, text string) ([]float32, error) {
+		embedded = append(embedded, text)
+		return []float32{1.0, 0.0, 0.0}, nil
+	}
+	mockExtractor := mock.NewMockConceptExtractor()
+	mockExtractor.ExtractConceptsFunc = func(ctx context.Context, text string) ([]ai.ExtractedConcept, error) {
+		return []ai.ExtractedConcept{}, nil
+	}
+	provider := mock.NewMockProviderWithServices(mockEmbedder, mockExtractor)
+
+	t.Run("options", func(t *testing.T) {
+		_, err := NewSearcher(chatRepo, conceptRepo, provider, WithTimeExpressions(TimeMode(7)))
+		assert.ErrorIs(t, err, ErrInvalidTimeMode)
+		_, err = NewSearcher(chatRepo, conceptRepo, provider, WithTimeBoost(0))
+		assert.ErrorIs(t, err, ErrInvalidTimeBoost)
+	})
+
+	t.Run("ignored by default", func(t *testing.T) {
+		embedded = nil
+		searcher, err := NewSearcher(chatRepo, conceptRepo, provider)
+		require.NoError(t, err)
+		response, err := searcher.Search(ctx, &SearchRequest{Query: "pricing yesterday", MaxHits: 10, ReferenceTime: ref})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"pricing yesterday"}, embedded)
+		assert.Empty(t, response.TimeExpressions)
+		assert.Len(t, response.Results, 2)
+	})
+
+	t.Run("filter", func(t *testing.T) {
+		embedded = nil
+		searcher, err := NewSearcher(chatRepo, conceptRepo, provider, WithTimeExpressions(TimeModeFilter))
+		require.NoError(t, err)
+		response, err := searcher.Search(ctx, &SearchRequest{Query: "pricing yesterday", MaxHits: 10, ReferenceTime: ref})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"pricing"}, embedded)
+		require.Len(t, response.TimeExpressions, 1)
+		assert.Equal(t, "yesterday", response.TimeExpressions[0].Text)
+		require.Len(t, response.Results, 1)
+		assert.Equal(t, recent.Id, response.Results[0].Record.Id)
+
+		// Explicit bounds are narrowed, not replaced
+		req := &SearchRequest{Query: "pricing this year", MaxHits: 10, ReferenceTime: ref}
+		req.Filter.End = ref.AddDate(0, -1, 0)
+		response, err = searcher.Search(ctx, req)
+		require.NoError(t, err)
+		require.Len(t, response.Results, 1)
+		assert.Equal(t, older.Id, response.Results[0].Record.Id)
+		assert.True(t, req.Filter.Start.IsZero(), "the caller's request is not modified")
+
+		// A query of only time expressions is still searched for
+		embedded = nil
+		response, err = searcher.Search(ctx, &SearchRequest{Query: "yesterday", MaxHits: 10, ReferenceTime: ref})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"yesterday"}, embedded)
+		assert.Len(t, response.Results, 1)
+	})
+
+	t.Run("boost", func(t *testing.T) {
+		searcher, err := NewSearcher(chatRepo, conceptRepo, provider, WithTimeExpressions(TimeModeBoost), WithTimeBoost(3))
+		require.NoError(t, err)
+
+		// The recent record is retrieved from its range even though the
+		// older one fills the single unfiltered candidate slot
+		response, err := searcher.Search(ctx, &SearchRequest{Query: "pricing yesterday", MaxHits: 1, ReferenceTime: ref, Explain: true})
+		require.NoError(t, err)
+		require.Len(t, response.Results, 1)
+		assert.Equal(t, recent.Id, response.Results[0].Record.Id)
+		assert.Contains(t, response.Results[0].Breakdown.Formula, "[boost]")
+
+		response, err = searcher.Search(ctx, &SearchRequest{Query: "pricing yesterday", MaxHits: 10, ReferenceTime: ref})
+		require.NoError(t, err)
+		assert.Len(t, response.Results, 2)
+	})
+
+	t.Run("multi searcher shares the analysis of the remaining text", func(t *testing.T) {
+		embedded = nil
+		filtered, err := NewSearcher(chatRepo, conceptRepo, provider, WithTimeExpressions(TimeModeFilter))
+		require.NoError(t, err)
+		ignoring, err := NewSearcher(chatRepo, conceptRepo, provider)
+		require.NoError(t, err)
+
+		multi, err := NewMultiSearcher([]Source{{Name: "a", Searcher: filtered}, {Name: "b", Searcher: filtered}})
+		require.NoError(t, err)
+		_, err = multi.Search(ctx, &SearchRequest{Query: "pricing yesterday", MaxHits: 10, ReferenceTime: ref})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"pricing"}, embedded)
+
+		// A source that keeps the time expressions analyzes the query itself
+		embedded = nil
+		multi, err = NewMultiSearcher([]Source{{Name: "a", Searcher: filtered}, {Name: "b", Searcher: ignoring}})
+		require.NoError(t, err)
+		response, err := multi.Search(ctx, &SearchRequest{Query: "pricing yesterday", MaxHits: 10, ReferenceTime: ref})
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []string{"pricing", "pricing yesterday"}, embedded)
+		assert.Len(t, response.Results, 3)
+	})
+}
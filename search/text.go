@@ -15,7 +15,10 @@
 
 package search
 
-import "strings"
+import (
+	"slices"
+	"strings"
+)
 
 // Stop words to filter out when checking for verbatim matches
 var stopWords = map[string]bool{
@@ -28,28 +31,56 @@ var stopWords = map[string]bool{
 
 // tokenizeAndFilter splits text into words, lowercases, trims punctuation, and removes stop words
 func tokenizeAndFilter(text string) []string {
-	words := strings.Fields(text)
+	words := splitWords(text)
 	filtered := make([]string, 0, len(words))
 
 	for _, word := range words {
-		// Lowercase and trim punctuation
-		cleaned := strings.ToLower(strings.Trim(word, ".,!?;:'\"-()[]{}"))
-
-		// Skip stop words and empty strings
-		if cleaned != "" && !stopWords[cleaned] {
-			filtered = append(filtered, cleaned)
+		// Skip stop words
+		if !stopWords[word] {
+			filtered = append(filtered, word)
 		}
 	}
 
 	return filtered
 }
 
-// containsAllQueryWords checks if all query words (after filtering) appear in the document
-func containsAllQueryWords(document, query string) bool {
-	queryWords := tokenizeAndFilter(query)
-	if len(queryWords) == 0 {
+// wordPunctuation is trimmed from both ends of every word
+const wordPunctuation = ".,!?;:'\"-()[]{}"
+
+// splitWords splits text into lowercase words with surrounding punctuation trimmed
+func splitWords(text string) []string {
+	fields := strings.Fields(text)
+	words := make([]string, 0, len(fields))
+	for _, field := range fields {
+		cleaned := strings.ToLower(strings.Trim(field, wordPunctuation))
+		if cleaned != "" {
+			words = append(words, cleaned)
+		}
+	}
+	return words
+}
+
+// containsTerm reports whether the words of term appear consecutively in text,
+// ignoring case and punctuation
+func containsTerm(text, term string) bool {
+	want := splitWords(term)
+	if len(want) == 0 {
 		return false
 	}
+	have := splitWords(text)
+	for i := 0; i+len(want) <= len(have); i++ {
+		if slices.Equal(have[i:i+len(want)], want) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchedQueryWords returns the filtered query words that appear in the document
+func matchedQueryWords(document string, queryWords []string) []string {
+	if len(queryWords) == 0 {
+		return nil
+	}
 
 	docWords := tokenizeAndFilter(document)
 	docWordSet := make(map[string]bool, len(docWords))
@@ -57,12 +88,11 @@ func containsAllQueryWords(document, query string) bool {
 		docWordSet[word] = true
 	}
 
-	// Check if all query words exist in document
+	matches := make([]string, 0, len(queryWords))
 	for _, qWord := range queryWords {
-		if !docWordSet[qWord] {
-			return false
+		if docWordSet[qWord] {
+			matches = append(matches, qWord)
 		}
 	}
-
-	return true
+	return matches
 }
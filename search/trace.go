@@ -0,0 +1,347 @@
+// Copyright 2025 Poiesic Systems
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+
+package search
+
+import (
+	"iter"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/poiesic/memorit/core"
+)
+
+// Names of the phases reported by TraceMonitor and TraceSpanMonitor.
+// The semantic and concepts phases run concurrently from the start of a search.
+const (
+	PhaseSemantic   = "semantic"   // Query embedding and vector search
+	PhaseConcepts   = "concepts"   // Query concept extraction and resolution
+	PhaseConceptual = "conceptual" // Record lookup by concept, ending once both retrieval stages are combined
+	PhaseRetrieve   = "retrieve"   // Loading candidate records
+	PhaseRank       = "rank"       // Scoring, reranking, diversification and truncation
+)
+
+// SearchTrace is a structured record of one search, suitable for JSON encoding.
+// Offsets and durations are in nanoseconds.
+type SearchTrace struct {
+	Query    string         `json:"query"`
+	Duration time.Duration  `json:"duration"`
+	Stages   []StageTrace   `json:"stages"`
+	Concepts []ConceptTrace `json:"concepts,omitempty"`
+	Hits     []HitTrace     `json:"hits,omitempty"`
+	Results  []ResultTrace  `json:"results"`
+}
+
+// StageTrace records the timing and output of one search phase.
+type StageTrace struct {
+	Name  string        `json:"name"`
+	Start time.Duration `json:"start"` // Offset from the start of the search
+	End   time.Duration `json:"end"`   // Offset from the start of the search
+	IDs   []uint64      `json:"ids,omitempty"`
+	Error string        `json:"error,omitempty"`
+}
+
+// ConceptTrace records the stored concepts related to one query concept.
+type ConceptTrace struct {
+	Tuple   string   `json:"tuple"`
+	Related []uint64 `json:"related"`
+}
+
+// HitTrace records which retrieval stages found a candidate record.
+type HitTrace struct {
+	ID         uint64 `json:"id"`
+	Semantic   bool   `json:"semantic"`
+	Conceptual bool   `json:"conceptual"`
+}
+
+// ResultTrace records a returned result.
+type ResultTrace struct {
+	ID    uint64  `json:"id"`
+	Score float32 `json:"score"`
+}
+
+// TraceMonitor collects a SearchTrace: phase timings, the IDs each phase
+// produced, concept matches, candidate hits and final results. Phase IDs are
+// record IDs, except for the concepts phase, which lists concept IDs.
+// A stage that fails ends when the search reports it skipped.
+// It tracks a single search: use a new TraceMonitor for each request.
+type TraceMonitor struct {
+	now   func() time.Time
+	start time.Time
+	trace SearchTrace
+	open  map[string]int // Index of each unfinished phase in trace.Stages
+}
+
+var (
+	_ SearchMonitor    = (*TraceMonitor)(nil)
+	_ StageSkipMonitor = (*TraceMonitor)(nil)
+)
+
+// NewTraceMonitor creates a trace-collecting monitor.
+func NewTraceMonitor() *TraceMonitor {
+	return &TraceMonitor{now: time.Now, open: make(map[string]int)}
+}
+
+// Trace returns the trace collected so far.
+func (m *TraceMonitor) Trace() *SearchTrace {
+	trace := m.trace
+	trace.Stages = slices.Clone(m.trace.Stages)
+	return &trace
+}
+
+func (m *TraceMonitor) elapsed() time.Duration {
+	return m.now().Sub(m.start)
+}
+
+func (m *TraceMonitor) begin(phase string) {
+	m.open[phase] = len(m.trace.Stages)
+	m.trace.Stages = append(m.trace.Stages, StageTrace{Name: phase, Start: m.elapsed()})
+}
+
+func (m *TraceMonitor) end(phase string, ids []uint64, err error) {
+	i, ok := m.open[phase]
+	if !ok {
+		return
+	}
+	delete(m.open, phase)
+	m.trace.Stages[i].End = m.elapsed()
+	m.trace.Stages[i].IDs = ids
+	if err != nil {
+		m.trace.Stages[i].Error = err.Error()
+	}
+}
+
+func (m *TraceMonitor) Start(query string) {
+	m.start = m.now()
+	m.trace = SearchTrace{Query: query}
+	clear(m.open)
+	m.begin(PhaseSemantic)
+	m.begin(PhaseConcepts)
+}
+
+func (m *TraceMonitor) AfterSemanticSearch(ids []uint64) {
+	m.end(PhaseSemantic, slices.Clone(ids), nil)
+}
+
+func (m *TraceMonitor) AfterQueryConceptExtraction(concepts []*core.Concept) {
+	ids := make([]uint64, len(concepts))
+	for i, concept := range concepts {
+		ids[i] = uint64(concept.Id)
+	}
+	m.end(PhaseConcepts, ids, nil)
+	m.begin(PhaseConceptual)
+}
+
+func (m *TraceMonitor) FoundRelatedConcepts(tuple string, conceptIds []uint64) {
+	m.trace.Concepts = append(m.trace.Concepts, ConceptTrace{Tuple: tuple, Related: slices.Clone(conceptIds)})
+}
+
+func (m *TraceMonitor) AfterConceptuallyRelatedSearch(ids iter.Seq[uint64]) {
+	collected := slices.Sorted(ids)
+	m.end(PhaseConceptual, collected, nil)
+	m.begin(PhaseRetrieve)
+}
+
+func (m *TraceMonitor) AfterRecordRetrieval(records []*core.ChatRecord) {
+	m.end(PhaseRetrieve, recordIDs(records), nil)
+	m.begin(PhaseRank)
+}
+
+func (m *TraceMonitor) SemanticAndConceptualHit(record *core.ChatRecord) {
+	m.trace.Hits = append(m.trace.Hits, HitTrace{ID: uint64(record.Id), Semantic: true, Conceptual: true})
+}
+
+func (m *TraceMonitor) SemanticHit(record *core.ChatRecord) {
+	m.trace.Hits = append(m.trace.Hits, HitTrace{ID: uint64(record.Id), Semantic: true})
+}
+
+func (m *TraceMonitor) ConceptualHit(record *core.ChatRecord) {
+	m.trace.Hits = append(m.trace.Hits, HitTrace{ID: uint64(record.Id), Conceptual: true})
+}
+
+func (m *TraceMonitor) StageSkipped(stage Stage, err error) {
+	switch stage {
+	case StageSemantic:
+		m.end(PhaseSemantic, nil, err)
+	case StageConceptual:
+		m.end(PhaseConcepts, nil, err)
+		m.end(PhaseConceptual, nil, err)
+	case StageRerank:
+		// Ranking continues with the retrieval order
+		if i, ok := m.open[PhaseRank]; ok {
+			m.trace.Stages[i].Error = err.Error()
+		}
+	}
+}
+
+func (m *TraceMonitor) Finish(results []*core.SearchResult) {
+	m.trace.Results = make([]ResultTrace, len(results))
+	ids := make([]uint64, len(results))
+	for i, result := range results {
+		m.trace.Results[i] = ResultTrace{ID: uint64(result.Record.Id), Score: result.Score}
+		ids[i] = uint64(result.Record.Id)
+	}
+	m.end(PhaseRank, ids, nil)
+	// A search without candidates finishes before loading records
+	m.end(PhaseRetrieve, nil, nil)
+	m.trace.Duration = m.elapsed()
+}
+
+// Tracer starts spans for TraceSpanMonitor. Implement it to bridge to a
+// tracing library such as OpenTelemetry.
+type Tracer interface {
+	// StartSpan starts a span. parent is nil for the root span of a search.
+	StartSpan(parent TraceSpan, name string) TraceSpan
+}
+
+// TraceSpan is a timed operation started by a Tracer. TraceSpanMonitor sets
+// attributes with string, int64 and []string values.
+type TraceSpan interface {
+	SetAttribute(key string, value any)
+	RecordError(err error)
+	End()
+}
+
+// TraceSpanMonitor records a search as a root "search" span with a child
+// span for each phase, named "search." followed by the phase name.
+// If a search fails outright, its open spans are not ended.
+// It tracks a single search: use a new TraceSpanMonitor for each request.
+type TraceSpanMonitor struct {
+	tracer Tracer
+	root   TraceSpan
+	open   map[string]TraceSpan
+	hits   int
+}
+
+var (
+	_ SearchMonitor    = (*TraceSpanMonitor)(nil)
+	_ StageSkipMonitor = (*TraceSpanMonitor)(nil)
+)
+
+// NewTraceSpanMonitor creates a monitor that records spans with tracer.
+func NewTraceSpanMonitor(tracer Tracer) *TraceSpanMonitor {
+	return &TraceSpanMonitor{tracer: tracer, open: make(map[string]TraceSpan)}
+}
+
+func (m *TraceSpanMonitor) begin(phase string) {
+	m.open[phase] = m.tracer.StartSpan(m.root, "search."+phase)
+}
+
+// end sets attributes on a phase's span and ends it, if the phase is open.
+func (m *TraceSpanMonitor) end(phase string, attrs ...slog.Attr) {
+	span, ok := m.open[phase]
+	if !ok {
+		return
+	}
+	delete(m.open, phase)
+	for _, attr := range attrs {
+		span.SetAttribute(attr.Key, attr.Value.Any())
+	}
+	span.End()
+}
+
+func (m *TraceSpanMonitor) fail(phase string, err error) {
+	if span, ok := m.open[phase]; ok {
+		span.RecordError(err)
+		m.end(phase)
+	}
+}
+
+func (m *TraceSpanMonitor) Start(query string) {
+	clear(m.open)
+	m.hits = 0
+	m.root = m.tracer.StartSpan(nil, "search")
+	m.root.SetAttribute("query", query)
+	m.begin(PhaseSemantic)
+	m.begin(PhaseConcepts)
+}
+
+func (m *TraceSpanMonitor) AfterSemanticSearch(ids []uint64) {
+	m.end(PhaseSemantic, slog.Int("hits", len(ids)))
+}
+
+func (m *TraceSpanMonitor) AfterQueryConceptExtraction(concepts []*core.Concept) {
+	tuples := make([]string, len(concepts))
+	for i, concept := range concepts {
+		tuples[i] = concept.Tuple()
+	}
+	m.end(PhaseConcepts, slog.Any("concepts", tuples))
+	m.begin(PhaseConceptual)
+}
+
+func (m *TraceSpanMonitor) FoundRelatedConcepts(_ string, _ []uint64) {}
+
+func (m *TraceSpanMonitor) AfterConceptuallyRelatedSearch(ids iter.Seq[uint64]) {
+	hits := 0
+	for range ids {
+		hits++
+	}
+	m.end(PhaseConceptual, slog.Int("hits", hits))
+	m.begin(PhaseRetrieve)
+}
+
+func (m *TraceSpanMonitor) AfterRecordRetrieval(records []*core.ChatRecord) {
+	m.end(PhaseRetrieve, slog.Int("records", len(records)))
+	m.begin(PhaseRank)
+}
+
+func (m *TraceSpanMonitor) SemanticAndConceptualHit(_ *core.ChatRecord) {
+	m.hits++
+}
+
+func (m *TraceSpanMonitor) SemanticHit(_ *core.ChatRecord) {
+	m.hits++
+}
+
+func (m *TraceSpanMonitor) ConceptualHit(_ *core.ChatRecord) {
+	m.hits++
+}
+
+func (m *TraceSpanMonitor) StageSkipped(stage Stage, err error) {
+	switch stage {
+	case StageSemantic:
+		m.fail(PhaseSemantic, err)
+	case StageConceptual:
+		m.fail(PhaseConcepts, err)
+		m.fail(PhaseConceptual, err)
+	case StageRerank:
+		if span, ok := m.open[PhaseRank]; ok {
+			span.RecordError(err)
+		}
+	}
+}
+
+func (m *TraceSpanMonitor) Finish(results []*core.SearchResult) {
+	m.end(PhaseRank, slog.Int("candidates", m.hits), slog.Int("results", len(results)))
+	// A search without candidates finishes before loading records
+	m.end(PhaseRetrieve, slog.Int("records", 0))
+	if m.root != nil {
+		m.root.SetAttribute("results", int64(len(results)))
+		m.root.End()
+		m.root = nil
+	}
+}
+
+// recordIDs returns the IDs of records, skipping nil records.
+func recordIDs(records []*core.ChatRecord) []uint64 {
+	ids := make([]uint64, 0, len(records))
+	for _, record := range records {
+		if record != nil {
+			ids = append(ids, uint64(record.Id))
+		}
+	}
+	return ids
+}
@@ -16,13 +16,14 @@
 package badger
 
 import (
-	"bytes"
+	"cmp"
 	"context"
 	"fmt"
 	"log/slog"
 	"os"
 	"slices"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/dgraph-io/badger/v4"
@@ -42,6 +43,25 @@ type Backend struct {
 	ctx        context.Context
 	cancelFunc context.CancelFunc
 	wg         sync.WaitGroup
+	cache      *vectorCache
+	version    atomic.Uint64 // Incremented after every committed write to records or concepts
+
+	gcInterval     time.Duration
+	gcDiscardRatio float64
+
+	quantization QuantizationMode
+	oversample   int
+}
+
+// WithVectorCache keeps every record and chunk vector in an in-memory matrix.
+// The matrix is loaded when the backend opens and kept in sync by repository
+// writes, so FindSimilar can run an exact parallel scan without reading BadgerDB.
+// workers sets the number of scan goroutines; <= 0 uses GOMAXPROCS.
+func WithVectorCache(workers int) BackendOption {
+	return func(o *backendOptions) {
+		o.vectorCache = true
+		o.searchWorkers = workers
+	}
 }
 
 // badgerLoggerAdapter adapts slog.Logger to badger.Logger interface.
@@ -69,7 +89,25 @@ func (bl *badgerLoggerAdapter) Debugf(msg string, items ...any) {
 
 // openBackend opens a BadgerDB database at the specified path.
 // Creates the directory if it doesn't exist.
-func OpenBackend(filePath string, inMemory bool) (*Backend, error) {
+func OpenBackend(filePath string, inMemory bool, backendOpts ...BackendOption) (*Backend, error) {
+	cfg := backendOptions{
+		logger:         slog.Default(),
+		gcInterval:     defaultGCInterval,
+		gcDiscardRatio: defaultGCDiscardRatio,
+	}
+	for _, opt := range backendOpts {
+		opt(&cfg)
+	}
+	if cfg.logger == nil {
+		cfg.logger = slog.Default()
+	}
+	if cfg.gcInterval <= 0 {
+		return nil, fmt.Errorf("GC interval must be positive")
+	}
+	if cfg.gcDiscardRatio <= 0 || cfg.gcDiscardRatio >= 1 {
+		return nil, fmt.Errorf("GC discard ratio must be between 0 and 1")
+	}
+
 	var opts badger.Options
 
 	if inMemory {
@@ -103,7 +141,15 @@ func OpenBackend(filePath string, inMemory bool) (*Backend, error) {
 		opts.CompactL0OnClose = true
 	}
 
-	opts.Logger = &badgerLoggerAdapter{logger: slog.Default()}
+	for _, tune := range cfg.tuning {
+		tune(&opts)
+	}
+	// BadgerDB panics instead of returning an error for this combination
+	if opts.Compression != options.None && opts.BlockCacheSize == 0 {
+		return nil, fmt.Errorf("block cache size must be set when compression is enabled")
+	}
+
+	opts.Logger = &badgerLoggerAdapter{logger: cfg.logger}
 
 	db, err := badger.Open(opts)
 	if err != nil {
@@ -114,9 +160,33 @@ func OpenBackend(filePath string, inMemory bool) (*Backend, error) {
 
 	backend := &Backend{
 		db:         db,
-		logger:     slog.Default(),
+		logger:     cfg.logger,
 		ctx:        ctx,
 		cancelFunc: cancel,
+
+		gcInterval:     cfg.gcInterval,
+		gcDiscardRatio: cfg.gcDiscardRatio,
+
+		quantization: cfg.quantization,
+		oversample:   cfg.oversample,
+	}
+	if backend.oversample <= 0 {
+		backend.oversample = defaultOversample
+	}
+
+	if err := backend.syncQuantization(ctx); err != nil {
+		cancel()
+		db.Close()
+		return nil, err
+	}
+
+	if cfg.vectorCache {
+		backend.cache = newVectorCache(cfg.searchWorkers)
+		if err := backend.cache.load(db); err != nil {
+			cancel()
+			db.Close()
+			return nil, err
+		}
 	}
 
 	// Start garbage collection goroutine only for persistent databases
@@ -128,14 +198,14 @@ func OpenBackend(filePath string, inMemory bool) (*Backend, error) {
 }
 
 // StartGC starts a background goroutine that periodically runs value log garbage collection.
-// The goroutine runs every 5 minutes and continues to run GC in a loop as long as it makes progress.
+// The goroutine runs every GC interval (5 minutes by default) and continues to run GC in a loop as long as it makes progress.
 // Call Close() to stop the GC goroutine cleanly.
 func (b *Backend) StartGC() {
 	b.wg.Add(1)
 	go func() {
 		defer b.wg.Done()
 
-		ticker := time.NewTicker(5 * time.Minute)
+		ticker := time.NewTicker(b.gcInterval)
 		defer ticker.Stop()
 
 		for {
@@ -146,7 +216,7 @@ func (b *Backend) StartGC() {
 			case <-ticker.C:
 				// Run GC in a loop as long as it makes progress
 				for {
-					err := b.db.RunValueLogGC(0.5)
+					err := b.db.RunValueLogGC(b.gcDiscardRatio)
 					if err != nil {
 						// nil error means GC ran successfully and found something to collect
 						// any other error (including ErrNoRewrite) means we should stop
@@ -184,6 +254,34 @@ func (b *Backend) WithTx(fn func(tx *badger.Txn) error, isWrite bool) error {
 	return fn(tx)
 }
 
+// commit commits a write transaction and advances the version. When the
+// vector cache is enabled, update is applied under the cache lock only if the
+// commit succeeds, so searches never observe vectors that are not durable and
+// concurrent writers update the cache in commit order.
+func (b *Backend) commit(tx *badger.Txn, update func(c *vectorCache)) error {
+	if b.cache == nil || update == nil {
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+		b.version.Add(1)
+		return nil
+	}
+	b.cache.mu.Lock()
+	defer b.cache.mu.Unlock()
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	update(b.cache)
+	b.version.Add(1)
+	return nil
+}
+
+// Version returns a counter that advances after every write to chat records,
+// chunks or concepts. Implements storage.ChangeTracker.
+func (b *Backend) Version() uint64 {
+	return b.version.Load()
+}
+
 // GetSequence returns a BadgerDB sequence for generating sequential IDs.
 func (b *Backend) GetSequence(name string) (*badger.Sequence, error) {
 	return b.db.GetSequence([]byte(name), defaultSequenceBandwidth)
@@ -198,16 +296,78 @@ func (b *Backend) WithTransaction(ctx context.Context, fn func(ctx context.Conte
 			return err
 		}
 		// Commit the transaction
-		return tx.Commit()
+		return b.commit(tx, nil)
 	}, true)
 }
 
 // FindSimilar finds chat records similar to the given vector.
 // Implements storage.VectorSearcher interface.
+// A record is scored by the better of its whole-record vector and its best chunk vector.
 func (b *Backend) FindSimilar(ctx context.Context, vector []float32, minSimilarity float32, limit int) ([]*core.SearchResult, error) {
+	return b.FindSimilarWithFilter(ctx, vector, minSimilarity, limit, nil)
+}
+
+// FindSimilarWithFilter is FindSimilar restricted to records matching filter.
+// Records are filtered before they compete for the limit. Filters with a
+// time range only read the records in range, through the date index.
+func (b *Backend) FindSimilarWithFilter(ctx context.Context, vector []float32, minSimilarity float32, limit int, filter *storage.RecordFilter) ([]*core.SearchResult, error) {
+	if filter.IsZero() {
+		filter = nil
+	}
+	if b.cache != nil {
+		return b.findSimilarCached(vector, minSimilarity, limit, filter)
+	}
+	if b.quantization != QuantizationNone {
+		return b.findSimilarQuantized(vector, minSimilarity, limit, filter)
+	}
+
 	var results []*core.SearchResult
 
 	err := b.WithTx(func(tx *badger.Txn) error {
+		// Score chunk vectors first so each record can be compared against its best chunk
+		bestChunks, err := scoreChunks(tx, vector)
+		if err != nil {
+			return err
+		}
+
+		score := func(record *core.ChatRecord) {
+			if record == nil || !filter.Matches(record) {
+				return
+			}
+
+			chunk, hasChunk := bestChunks[record.Id]
+
+			// Skip records without embeddings
+			if len(record.Vector) == 0 && !hasChunk {
+				return
+			}
+
+			// Calculate cosine similarity (dot product for normalized vectors)
+			var similarity float32
+			var span *core.Span
+			if len(record.Vector) > 0 {
+				similarity = dotProduct(vector, record.Vector)
+			}
+			if hasChunk && (len(record.Vector) == 0 || chunk.score > similarity) {
+				similarity = chunk.score
+				span = &chunk.span
+			}
+
+			// Filter by threshold
+			if similarity >= minSimilarity {
+				results = append(results, &core.SearchResult{
+					Record: record,
+					Score:  similarity,
+					Span:   span,
+				})
+			}
+		}
+
+		// Time-bounded filters only read the records in range from the date index
+		if timeBounded(filter) {
+			return scanDateRange(tx, filter.Start, filter.End, score)
+		}
+
 		// Iterate through all chat records
 		opts := badger.DefaultIteratorOptions
 		opts.Prefix = []byte(chatRecordPrefix)
@@ -219,9 +379,7 @@ func (b *Backend) FindSimilar(ctx context.Context, vector []float32, minSimilari
 			key := item.Key()
 
 			// Skip index keys (date index, concept index, and sequence key)
-			if bytes.Equal(key, []byte(chatRecordIDSeq)) ||
-				bytes.HasPrefix(key, []byte(chatRecordDatePrefix)) ||
-				bytes.HasPrefix(key, []byte(chatRecordConceptPrefix)) {
+			if isChatIndexKey(key) {
 				continue
 			}
 
@@ -235,51 +393,189 @@ func (b *Backend) FindSimilar(ctx context.Context, vector []float32, minSimilari
 			if err != nil {
 				return err
 			}
-			if record == nil {
-				continue
-			}
+			score(record)
+		}
 
-			// Skip records without embeddings
-			if len(record.Vector) == 0 {
-				continue
-			}
+		return nil
+	}, false)
 
-			// Calculate cosine similarity (dot product for normalized vectors)
-			similarity := dotProduct(vector, record.Vector)
+	if err != nil {
+		return nil, err
+	}
 
-			// Filter by threshold
-			if similarity >= minSimilarity {
-				results = append(results, &core.SearchResult{
-					Record: record,
-					Score:  similarity,
-				})
+	// Sort by similarity descending
+	sortResults(results)
+
+	// Limit to maxHits
+	if len(results) > limit {
+		results = results[:limit]
+	}
+
+	return results, nil
+}
+
+// timeBounded reports whether filter restricts timestamps, so the records
+// it can match are found through the date index.
+func timeBounded(filter *storage.RecordFilter) bool {
+	return filter != nil && (!filter.Start.IsZero() || !filter.End.IsZero())
+}
+
+// scanDateIndex passes the ID of each chat record with a timestamp in
+// [start, end) to fn, in timestamp order, reading only the date index. A
+// zero start or end is unbounded. Keys hold microseconds, so records just
+// outside the range may be passed too.
+func scanDateIndex(tx *badger.Txn, start, end time.Time, fn func(core.ID) error) error {
+	opts := badger.DefaultIteratorOptions
+	opts.Prefix = []byte(chatRecordDatePrefix + ":")
+	opts.PrefetchValues = false
+	iter := tx.NewIterator(opts)
+	defer iter.Close()
+
+	// Keys of timestamps before the epoch don't sort, so scan those from the start
+	startKey := opts.Prefix
+	if !start.IsZero() && start.UnixMicro() > 0 {
+		startKey = makePartialChatDateKey(start)
+	}
+	var endKey []byte
+	if !end.IsZero() {
+		endKey = makePartialChatDateKey(end.Add(time.Microsecond))
+	}
+
+	for iter.Seek(startKey); iter.Valid(); iter.Next() {
+		if endKey != nil && slices.Compare(iter.Item().Key(), endKey) >= 0 {
+			break
+		}
+
+		var recordID core.ID
+		if err := iter.Item().Value(func(val []byte) error {
+			var err error
+			recordID, err = storage.UnmarshalID(val)
+			return err
+		}); err != nil {
+			return err
+		}
+		if err := fn(recordID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dateRangeIDs returns the IDs of the chat records that scanDateIndex finds.
+func dateRangeIDs(tx *badger.Txn, start, end time.Time) ([]core.ID, error) {
+	ids := []core.ID{}
+	err := scanDateIndex(tx, start, end, func(id core.ID) error {
+		ids = append(ids, id)
+		return nil
+	})
+	return ids, err
+}
+
+// scanDateRange reads the chat records with timestamps in [start, end) from
+// the date index and passes each to fn, as scanDateIndex does.
+func scanDateRange(tx *badger.Txn, start, end time.Time, fn func(*core.ChatRecord)) error {
+	return scanDateIndex(tx, start, end, func(id core.ID) error {
+		record, err := readRecord(tx, id)
+		if err != nil {
+			return err
+		}
+		if record != nil {
+			fn(record)
+		}
+		return nil
+	})
+}
+
+// findSimilarCached scores the in-memory vector matrix and loads only the winning records.
+// Time-bounded filters only score the records in range, found through the date index.
+func (b *Backend) findSimilarCached(vector []float32, minSimilarity float32, limit int, filter *storage.RecordFilter) ([]*core.SearchResult, error) {
+	var results []*core.SearchResult
+	err := b.WithTx(func(tx *badger.Txn) error {
+		var ids []core.ID
+		if timeBounded(filter) {
+			var err error
+			if ids, err = dateRangeIDs(tx, filter.Start, filter.End); err != nil {
+				return err
 			}
 		}
+		hits := b.cache.searchRecords(vector, minSimilarity, limit, filter, ids)
+		results = make([]*core.SearchResult, 0, len(hits))
+		for _, hit := range hits {
+			record, err := readRecord(tx, hit.id)
+			if err != nil {
+				return err
+			}
+			if record == nil {
+				// Deleted after the scan
+				continue
+			}
 
+			result := &core.SearchResult{Record: record, Score: hit.score}
+			if hit.span != nil {
+				span := *hit.span
+				result.Span = &span
+			}
+			results = append(results, result)
+		}
 		return nil
 	}, false)
-
 	if err != nil {
 		return nil, err
 	}
 
-	// Sort by similarity descending
+	return results, nil
+}
+
+// sortResults orders search results by score descending, breaking ties by
+// record ID so equal scores come back in the same order.
+func sortResults(results []*core.SearchResult) {
 	slices.SortFunc(results, func(a, b *core.SearchResult) int {
-		if a.Score > b.Score {
-			return -1
-		}
-		if a.Score < b.Score {
+		if a.Score != b.Score {
+			if a.Score > b.Score {
+				return -1
+			}
 			return 1
 		}
-		return 0
+		return cmp.Compare(a.Record.Id, b.Record.Id)
 	})
+}
 
-	// Limit to maxHits
-	if len(results) > limit {
-		results = results[:limit]
-	}
+// chunkScore is the best-scoring chunk of a record.
+type chunkScore struct {
+	score float32
+	span  core.Span
+}
 
-	return results, nil
+// scoreChunks compares the vector against every stored chunk and
+// returns the best-scoring chunk for each record that has chunks.
+func scoreChunks(tx *badger.Txn, vector []float32) (map[core.ID]chunkScore, error) {
+	best := make(map[core.ID]chunkScore)
+
+	opts := badger.DefaultIteratorOptions
+	opts.Prefix = []byte(chatChunkPrefix + ":")
+	iter := tx.NewIterator(opts)
+	defer iter.Close()
+
+	for iter.Rewind(); iter.Valid(); iter.Next() {
+		var chunk *core.Chunk
+		err := iter.Item().Value(func(val []byte) error {
+			var err error
+			chunk, err = storage.UnmarshalChunk(val)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		if len(chunk.Vector) == 0 {
+			continue
+		}
+
+		score := dotProduct(vector, chunk.Vector)
+		if current, ok := best[chunk.RecordId]; !ok || score > current.score {
+			best[chunk.RecordId] = chunkScore{score: score, span: chunk.Span()}
+		}
+	}
+	return best, nil
 }
 
 // dotProduct calculates the dot product of two vectors.
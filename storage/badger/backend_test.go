@@ -2,10 +2,14 @@ package badger
 
 import (
 	"context"
+	"io"
+	"log/slog"
 	"testing"
 	"time"
 
+	"github.com/dgraph-io/badger/v4/options"
 	"github.com/poiesic/memorit/core"
+	"github.com/poiesic/memorit/storage"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -129,6 +133,57 @@ func TestFindSimilar_WithRecords(t *testing.T) {
 	assert.Greater(t, results[0].Score, float32(0.8))
 }
 
+func TestFindSimilar_Chunks(t *testing.T) {
+	chatRepo, conceptRepo, backend, err := NewMemoryRepositories()
+	require.NoError(t, err)
+	defer func() {
+		conceptRepo.Close()
+		chatRepo.Close()
+		backend.Close()
+	}()
+
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	records := []*core.ChatRecord{
+		{
+			Speaker:   core.SpeakerTypeHuman,
+			Contents:  "long message about many things",
+			Timestamp: now,
+			Vector:    []float32{0.0, 1.0, 0.0}, // Whole message is not similar
+		},
+		{
+			Speaker:   core.SpeakerTypeHuman,
+			Contents:  "short message",
+			Timestamp: now,
+			Vector:    []float32{0.9, 0.1, 0.0},
+		},
+	}
+	added, err := chatRepo.AddChatRecords(ctx, records...)
+	require.NoError(t, err)
+
+	// One chunk of the long message matches the query exactly
+	err = chatRepo.ReplaceChunks(ctx, added[0].Id, []core.Chunk{
+		{Index: 0, Start: 0, End: 12, Vector: []float32{0.0, 0.0, 1.0}},
+		{Index: 1, Start: 13, End: 30, Vector: []float32{1.0, 0.0, 0.0}},
+	})
+	require.NoError(t, err)
+
+	results, err := backend.FindSimilar(ctx, []float32{1.0, 0.0, 0.0}, 0.5, 10)
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+
+	// The long message is scored by its best chunk
+	assert.Equal(t, added[0].Id, results[0].Record.Id)
+	assert.InDelta(t, 1.0, results[0].Score, 0.0001)
+	require.NotNil(t, results[0].Span)
+	assert.Equal(t, "about many things", results[0].Span.Of(results[0].Record.Contents))
+
+	// Whole-record matches carry no span
+	assert.Equal(t, added[1].Id, results[1].Record.Id)
+	assert.Nil(t, results[1].Span)
+}
+
 func TestFindSimilar_ThresholdFiltering(t *testing.T) {
 	chatRepo, conceptRepo, backend, err := NewMemoryRepositories()
 	require.NoError(t, err)
@@ -237,6 +292,98 @@ func TestFindSimilar_LimitResults(t *testing.T) {
 	})
 }
 
+func TestFindSimilarWithFilter(t *testing.T) {
+	backends := map[string][]BackendOption{
+		"scan":      nil,
+		"cache":     {WithVectorCache(2)},
+		"quantized": {WithQuantization(QuantizationInt8, 0)},
+	}
+	for name, opts := range backends {
+		t.Run(name, func(t *testing.T) {
+			backend, err := OpenBackend("", true, opts...)
+			require.NoError(t, err)
+			chatRepo, err := NewChatRepository(backend)
+			require.NoError(t, err)
+			defer func() {
+				chatRepo.Close()
+				backend.Close()
+			}()
+
+			ctx := context.Background()
+			base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+			records := make([]*core.ChatRecord, 20)
+			for i := range records {
+				speaker := core.SpeakerTypeAI
+				if i%2 == 1 {
+					speaker = core.SpeakerTypeHuman
+				}
+				// Similarity to the query decreases with i
+				records[i] = &core.ChatRecord{
+					Speaker:   speaker,
+					Contents:  "message",
+					Timestamp: base.Add(time.Duration(i) * time.Hour),
+					Vector:    []float32{1 - float32(i)*0.01, float32(i) * 0.01},
+					Metadata:  map[string]string{"provider": "slack"},
+				}
+			}
+			added, err := chatRepo.AddChatRecords(ctx, records...)
+			require.NoError(t, err)
+			query := []float32{1, 0}
+
+			ids := func(results []*core.SearchResult) []core.ID {
+				out := make([]core.ID, len(results))
+				for i, result := range results {
+					out[i] = result.Record.Id
+				}
+				return out
+			}
+
+			// Filtered records don't use up the limit
+			filter := &storage.RecordFilter{Speakers: []core.SpeakerType{core.SpeakerTypeHuman}}
+			results, err := chatRepo.FindSimilarWithFilter(ctx, query, 0, 3, filter)
+			require.NoError(t, err)
+			assert.Equal(t, []core.ID{added[1].Id, added[3].Id, added[5].Id}, ids(results))
+
+			filter = &storage.RecordFilter{
+				Start:      base.Add(4 * time.Hour),
+				End:        base.Add(10 * time.Hour),
+				ExcludeIDs: []core.ID{added[5].Id},
+			}
+			results, err = chatRepo.FindSimilarWithFilter(ctx, query, 0, 3, filter)
+			require.NoError(t, err)
+			assert.Equal(t, []core.ID{added[4].Id, added[6].Id, added[7].Id}, ids(results))
+
+			// Open-ended time ranges
+			filter = &storage.RecordFilter{Start: base.Add(17 * time.Hour)}
+			results, err = chatRepo.FindSimilarWithFilter(ctx, query, 0, 5, filter)
+			require.NoError(t, err)
+			assert.Equal(t, []core.ID{added[17].Id, added[18].Id, added[19].Id}, ids(results))
+			filter = &storage.RecordFilter{End: base.Add(2*time.Hour + time.Nanosecond)}
+			results, err = chatRepo.FindSimilarWithFilter(ctx, query, 0, 5, filter)
+			require.NoError(t, err)
+			assert.Equal(t, []core.ID{added[0].Id, added[1].Id, added[2].Id}, ids(results))
+
+			filter = &storage.RecordFilter{Metadata: []storage.MetadataPredicate{{Key: "provider", Value: "email"}}}
+			results, err = chatRepo.FindSimilarWithFilter(ctx, query, 0, 3, filter)
+			require.NoError(t, err)
+			assert.Empty(t, results)
+
+			// Updates are visible to the filter
+			added[0].Metadata = map[string]string{"provider": "email"}
+			_, err = chatRepo.UpdateChatRecords(ctx, added[0])
+			require.NoError(t, err)
+			results, err = chatRepo.FindSimilarWithFilter(ctx, query, 0, 3, filter)
+			require.NoError(t, err)
+			assert.Equal(t, []core.ID{added[0].Id}, ids(results))
+
+			// A nil filter behaves like FindSimilar
+			results, err = chatRepo.FindSimilarWithFilter(ctx, query, 0, 2, nil)
+			require.NoError(t, err)
+			assert.Equal(t, []core.ID{added[0].Id, added[1].Id}, ids(results))
+		})
+	}
+}
+
 func TestDotProduct(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -403,3 +550,108 @@ func TestStartGC_MultipleClose(t *testing.T) {
 	// but the GC goroutine shutdown logic should be safe
 	backend.Close()
 }
+
+func TestOpenBackend_Options(t *testing.T) {
+	t.Run("tuning options are applied", func(t *testing.T) {
+		backend, err := OpenBackend(t.TempDir(), false,
+			WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+			WithBlockCacheSize(16<<20),
+			WithIndexCacheSize(8<<20),
+			WithCompression(CompressionZSTD),
+			WithSyncWrites(true),
+			WithValueLogFileSize(16<<20),
+			WithMemTableSize(8<<20),
+			WithGCInterval(time.Minute),
+			WithGCDiscardRatio(0.7),
+		)
+		require.NoError(t, err)
+		defer backend.Close()
+
+		opts := backend.db.Opts()
+		assert.Equal(t, int64(16<<20), opts.BlockCacheSize)
+		assert.Equal(t, int64(8<<20), opts.IndexCacheSize)
+		assert.Equal(t, options.ZSTD, opts.Compression)
+		assert.True(t, opts.SyncWrites)
+		assert.Equal(t, int64(16<<20), opts.ValueLogFileSize)
+		assert.Equal(t, int64(8<<20), opts.MemTableSize)
+		assert.Equal(t, time.Minute, backend.gcInterval)
+		assert.Equal(t, 0.7, backend.gcDiscardRatio)
+	})
+
+	t.Run("defaults", func(t *testing.T) {
+		backend, err := OpenBackend(t.TempDir(), false)
+		require.NoError(t, err)
+		defer backend.Close()
+
+		opts := backend.db.Opts()
+		assert.Equal(t, int64(512<<20), opts.BlockCacheSize)
+		assert.Equal(t, options.None, opts.Compression)
+		assert.Equal(t, defaultGCInterval, backend.gcInterval)
+		assert.Equal(t, defaultGCDiscardRatio, backend.gcDiscardRatio)
+	})
+
+	t.Run("compression requires block cache", func(t *testing.T) {
+		_, err := OpenBackend("", true, WithCompression(CompressionSnappy), WithBlockCacheSize(0))
+		assert.Error(t, err)
+	})
+
+	t.Run("invalid GC settings", func(t *testing.T) {
+		_, err := OpenBackend("", true, WithGCInterval(0))
+		assert.Error(t, err)
+		_, err = OpenBackend("", true, WithGCDiscardRatio(1.5))
+		assert.Error(t, err)
+	})
+}
+
+func TestBackend_Version(t *testing.T) {
+	chatRepo, conceptRepo, backend, err := NewMemoryRepositories()
+	require.NoError(t, err)
+	defer func() {
+		conceptRepo.Close()
+		chatRepo.Close()
+		backend.Close()
+	}()
+	ctx := context.Background()
+
+	version := backend.Version()
+	advanced := func() bool {
+		next := backend.Version()
+		changed := next > version
+		version = next
+		return changed
+	}
+
+	added, err := chatRepo.AddChatRecords(ctx, &core.ChatRecord{
+		Speaker:   core.SpeakerTypeHuman,
+		Contents:  "hello",
+		Timestamp: time.Now(),
+		Vector:    []float32{1, 0},
+	})
+	require.NoError(t, err)
+	assert.True(t, advanced(), "add")
+
+	_, err = chatRepo.GetChatRecords(ctx, added[0].Id)
+	require.NoError(t, err)
+	assert.False(t, advanced(), "reads leave the version unchanged")
+
+	added[0].Contents = "hello again"
+	_, err = chatRepo.UpdateChatRecords(ctx, added[0])
+	require.NoError(t, err)
+	assert.True(t, advanced(), "update")
+
+	require.NoError(t, chatRepo.ReplaceChunks(ctx, added[0].Id, []core.Chunk{{Index: 0, End: 5, Vector: []float32{0, 1}}}))
+	assert.True(t, advanced(), "chunks")
+
+	_, err = conceptRepo.AddConcepts(ctx, &core.Concept{Name: "greeting", Type: "thing"})
+	require.NoError(t, err)
+	assert.True(t, advanced(), "concept")
+
+	_, err = chatRepo.UpdateChatRecords(ctx, &core.ChatRecord{Id: 999, Contents: "missing"})
+	require.Error(t, err)
+	assert.False(t, advanced(), "failed writes leave the version unchanged")
+
+	require.NoError(t, chatRepo.DeleteChatRecords(ctx, added[0].Id))
+	assert.True(t, advanced(), "delete")
+	assert.Equal(t, version, chatRepo.(storage.ChangeTracker).Version())
+	assert.Equal(t, version, conceptRepo.(storage.ChangeTracker).Version())
+}
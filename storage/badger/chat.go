@@ -16,6 +16,7 @@
 package badger
 
 import (
+	"bytes"
 	"context"
 	"slices"
 	"time"
@@ -31,7 +32,10 @@ type ChatRepository struct {
 	idSeq   *badger.Sequence
 }
 
-var _ storage.ChatRepository = (*ChatRepository)(nil)
+var (
+	_ storage.ChatRepository = (*ChatRepository)(nil)
+	_ storage.ChangeTracker  = (*ChatRepository)(nil)
+)
 
 // NewChatRepository creates a new ChatRepository.
 func NewChatRepository(backend *Backend) (*ChatRepository, error) {
@@ -56,6 +60,16 @@ func (r *ChatRepository) FindSimilar(ctx context.Context, vector []float32, minS
 	return r.backend.FindSimilar(ctx, vector, minSimilarity, limit)
 }
 
+// FindSimilarWithFilter delegates to the backend.
+func (r *ChatRepository) FindSimilarWithFilter(ctx context.Context, vector []float32, minSimilarity float32, limit int, filter *storage.RecordFilter) ([]*core.SearchResult, error) {
+	return r.backend.FindSimilarWithFilter(ctx, vector, minSimilarity, limit, filter)
+}
+
+// Version delegates to the backend.
+func (r *ChatRepository) Version() uint64 {
+	return r.backend.Version()
+}
+
 // WithTransaction delegates to the backend.
 func (r *ChatRepository) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
 	return r.backend.WithTransaction(ctx, fn)
@@ -100,8 +114,22 @@ func (r *ChatRepository) AddChatRecords(ctx context.Context, records ...*core.Ch
 			if err := r.updateConceptIndex(tx, record); err != nil {
 				return err
 			}
+
+			// Update quantized vector
+			if len(record.Vector) > 0 {
+				if err := r.backend.setQuantized(tx, record.Id, 0, record.Vector); err != nil {
+					return err
+				}
+			}
 		}
-		return tx.Commit()
+		return r.backend.commit(tx, func(c *vectorCache) {
+			for _, record := range records {
+				c.setAttributes(record)
+				if len(record.Vector) > 0 {
+					c.setRecordVector(record.Id, record.Vector)
+				}
+			}
+		})
 	}, true)
 
 	return records, err
@@ -110,6 +138,7 @@ func (r *ChatRepository) AddChatRecords(ctx context.Context, records ...*core.Ch
 // UpdateChatRecords updates existing chat records.
 func (r *ChatRepository) UpdateChatRecords(ctx context.Context, records ...*core.ChatRecord) ([]*core.ChatRecord, error) {
 	err := r.backend.WithTx(func(tx *badger.Txn) error {
+		rewritten := make(map[core.ID]bool)
 		for _, record := range records {
 			key := makeChatRecordKey(record.Id)
 
@@ -152,8 +181,32 @@ func (r *ChatRepository) UpdateChatRecords(ctx context.Context, records ...*core
 					return err
 				}
 			}
+
+			// Chunks cover the old contents, so drop them until they are re-embedded
+			if old.Contents != record.Contents {
+				if err := r.deleteChunks(tx, record.Id); err != nil {
+					return err
+				}
+				if err := r.backend.deleteQuantized(tx, record.Id, 1); err != nil {
+					return err
+				}
+				rewritten[record.Id] = true
+			}
+
+			// Update quantized vector
+			if err := r.backend.setQuantized(tx, record.Id, 0, record.Vector); err != nil {
+				return err
+			}
 		}
-		return tx.Commit()
+		return r.backend.commit(tx, func(c *vectorCache) {
+			for _, record := range records {
+				if rewritten[record.Id] {
+					c.setChunks(record.Id, nil)
+				}
+				c.setAttributes(record)
+				c.setRecordVector(record.Id, record.Vector)
+			}
+		})
 	}, true)
 
 	return records, err
@@ -185,12 +238,26 @@ func (r *ChatRepository) DeleteChatRecords(ctx context.Context, ids ...core.ID)
 				return err
 			}
 
+			// Delete chunk vectors
+			if err := r.deleteChunks(tx, record.Id); err != nil {
+				return err
+			}
+
+			// Delete quantized vectors
+			if err := r.backend.deleteQuantized(tx, record.Id, 0); err != nil {
+				return err
+			}
+
 			// Delete primary record
 			if err := tx.Delete(key); err != nil {
 				return err
 			}
 		}
-		return tx.Commit()
+		return r.backend.commit(tx, func(c *vectorCache) {
+			for _, id := range ids {
+				c.remove(id)
+			}
+		})
 	}, true)
 }
 
@@ -409,6 +476,95 @@ func (r *ChatRepository) GetChatRecordsBeforeID(ctx context.Context, beforeID co
 	return results, err
 }
 
+// GetAdjacentChatRecords walks the date index outward from a record in both directions.
+func (r *ChatRepository) GetAdjacentChatRecords(ctx context.Context, id core.ID, before, after int, filter *storage.RecordFilter) ([]*core.ChatRecord, error) {
+	var preceding, following []*core.ChatRecord
+	var ref *core.ChatRecord
+
+	err := r.backend.WithTx(func(tx *badger.Txn) error {
+		var err error
+		ref, err = r.readChatRecord(tx, makeChatRecordKey(id))
+		if err != nil {
+			return err
+		}
+		if ref == nil {
+			return storage.ErrNotFound
+		}
+
+		startKey := makeChatDateKey(ref.Timestamp, id)
+		if preceding, err = r.walkDateIndex(tx, startKey, true, before, filter); err != nil {
+			return err
+		}
+		following, err = r.walkDateIndex(tx, startKey, false, after, filter)
+		return err
+	}, false)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]*core.ChatRecord, 0, len(preceding)+1+len(following))
+	for i := len(preceding) - 1; i >= 0; i-- {
+		results = append(results, preceding[i])
+	}
+	results = append(results, ref)
+	return append(results, following...), nil
+}
+
+// maxAdjacentScan bounds the date index entries walkDateIndex examines in
+// each direction, so a filter matching few records does not read them all.
+const maxAdjacentScan = 1000
+
+// walkDateIndex reads up to limit records matching filter from the date index,
+// starting just past startKey and moving backward in time if reverse is set.
+// It stops after maxAdjacentScan entries or once past the filter's time range.
+func (r *ChatRepository) walkDateIndex(tx *badger.Txn, startKey []byte, reverse bool, limit int, filter *storage.RecordFilter) ([]*core.ChatRecord, error) {
+	var results []*core.ChatRecord
+	if limit <= 0 {
+		return results, nil
+	}
+
+	opts := badger.DefaultIteratorOptions
+	opts.Reverse = reverse
+	opts.Prefix = []byte(chatRecordDatePrefix + ":")
+	iter := tx.NewIterator(opts)
+	defer iter.Close()
+
+	scanned := 0
+	for iter.Seek(startKey); iter.Valid() && len(results) < limit && scanned < maxAdjacentScan; iter.Next() {
+		// The start key is the reference record itself
+		if bytes.Equal(iter.Item().Key(), startKey) {
+			continue
+		}
+		scanned++
+
+		var recordID core.ID
+		if err := iter.Item().Value(func(val []byte) error {
+			var err error
+			recordID, err = storage.UnmarshalID(val)
+			return err
+		}); err != nil {
+			return nil, err
+		}
+
+		record, err := r.readChatRecord(tx, makeChatRecordKey(recordID))
+		if err != nil {
+			return nil, err
+		}
+		if record == nil {
+			continue
+		}
+		// Records only move further from the filter's time range from here
+		if filter != nil && ((reverse && !filter.Start.IsZero() && record.Timestamp.Before(filter.Start)) ||
+			(!reverse && !filter.End.IsZero() && !record.Timestamp.Before(filter.End))) {
+			break
+		}
+		if filter.Matches(record) {
+			results = append(results, record)
+		}
+	}
+	return results, nil
+}
+
 // GetChatRecordsByConcept retrieves IDs of chat records associated with a concept.
 func (r *ChatRepository) GetChatRecordsByConcept(ctx context.Context, conceptID core.ID) ([]core.ID, error) {
 	var recordIDs []core.ID
@@ -536,6 +692,70 @@ func (r *ChatRepository) GetConceptsByDateRange(ctx context.Context, start, end
 	return result, err
 }
 
+// ReplaceChunks stores the chunk vectors for a chat record, removing any existing chunks.
+func (r *ChatRepository) ReplaceChunks(ctx context.Context, recordID core.ID, chunks []core.Chunk) error {
+	return r.backend.WithTx(func(tx *badger.Txn) error {
+		// Make sure the record exists so chunks are never orphaned
+		record, err := r.readChatRecord(tx, makeChatRecordKey(recordID))
+		if err != nil {
+			return err
+		}
+		if record == nil {
+			return storage.ErrNotFound
+		}
+
+		if err := r.deleteChunks(tx, recordID); err != nil {
+			return err
+		}
+		if err := r.backend.deleteQuantized(tx, recordID, 1); err != nil {
+			return err
+		}
+
+		for i := range chunks {
+			chunk := &chunks[i]
+			chunk.RecordId = recordID
+			key := makeChatChunkKey(recordID, chunk.Index)
+			if err := tx.Set(key, storage.MarshalChunk(chunk)); err != nil {
+				return err
+			}
+			if len(chunk.Vector) > 0 {
+				if err := r.backend.setQuantized(tx, recordID, chunk.Index+1, chunk.Vector); err != nil {
+					return err
+				}
+			}
+		}
+		return r.backend.commit(tx, func(c *vectorCache) {
+			c.setChunks(recordID, chunks)
+		})
+	}, true)
+}
+
+// GetChunks retrieves the chunk vectors for a chat record, ordered by chunk index.
+func (r *ChatRepository) GetChunks(ctx context.Context, recordID core.ID) ([]core.Chunk, error) {
+	chunks := []core.Chunk{}
+	err := r.backend.WithTx(func(tx *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.Prefix = makePartialChatChunkKey(recordID)
+		iter := tx.NewIterator(opts)
+		defer iter.Close()
+
+		for iter.Rewind(); iter.Valid(); iter.Next() {
+			var chunk *core.Chunk
+			err := iter.Item().Value(func(val []byte) error {
+				var unmarshalErr error
+				chunk, unmarshalErr = storage.UnmarshalChunk(val)
+				return unmarshalErr
+			})
+			if err != nil {
+				return err
+			}
+			chunks = append(chunks, *chunk)
+		}
+		return nil
+	}, false)
+	return chunks, err
+}
+
 // Helper methods
 
 // readChatRecord reads a chat record from the transaction.
@@ -586,6 +806,26 @@ func (r *ChatRepository) deleteConceptIndex(tx *badger.Txn, record *core.ChatRec
 	return nil
 }
 
+// deleteChunks removes all chunk vectors for a record.
+func (r *ChatRepository) deleteChunks(tx *badger.Txn, recordID core.ID) error {
+	opts := badger.DefaultIteratorOptions
+	opts.Prefix = makePartialChatChunkKey(recordID)
+	opts.PrefetchValues = false
+	iter := tx.NewIterator(opts)
+	defer iter.Close()
+
+	var keys [][]byte
+	for iter.Rewind(); iter.Valid(); iter.Next() {
+		keys = append(keys, iter.Item().KeyCopy(nil))
+	}
+	for _, key := range keys {
+		if err := tx.Delete(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // conceptsEqual compares two concept slices for equality.
 func conceptsEqual(a, b []core.ConceptRef) bool {
 	if len(a) != len(b) {
@@ -2,10 +2,12 @@ package badger
 
 import (
 	"context"
+	"fmt"
 	"testing"
 	"time"
 
 	"github.com/poiesic/memorit/core"
+	"github.com/poiesic/memorit/storage"
 	"github.com/stretchr/testify/require"
 )
 
@@ -374,6 +376,112 @@ func TestGetChatRecordsBeforeID(t *testing.T) {
 	}
 }
 
+func TestGetAdjacentChatRecords(t *testing.T) {
+	chatRepo, conceptRepo, backend, err := NewMemoryRepositories()
+	require.NoError(t, err)
+	defer func() { conceptRepo.Close(); chatRepo.Close(); backend.Close() }()
+
+	ctx := context.Background()
+
+	// Two interleaved conversations, one message per minute
+	now := time.Now().UTC()
+	records := []*core.ChatRecord{}
+	for i := 0; i < 10; i++ {
+		conversation := "a"
+		if i%2 == 1 {
+			conversation = "b"
+		}
+		records = append(records, &core.ChatRecord{
+			Speaker:   core.SpeakerTypeHuman,
+			Contents:  "Message " + string(rune('0'+i)),
+			Timestamp: now.Add(time.Duration(i) * time.Minute),
+			Metadata:  map[string]string{core.MetadataConversation: conversation},
+		})
+	}
+	added, err := chatRepo.AddChatRecords(ctx, records...)
+	require.NoError(t, err)
+
+	contents := func(records []*core.ChatRecord) []string {
+		out := make([]string, len(records))
+		for i, record := range records {
+			out[i] = record.Contents
+		}
+		return out
+	}
+
+	// By timestamp
+	window, err := chatRepo.GetAdjacentChatRecords(ctx, added[5].Id, 2, 1, nil)
+	require.NoError(t, err)
+	require.Equal(t, []string{"Message 3", "Message 4", "Message 5", "Message 6"}, contents(window))
+
+	// Within a conversation
+	filter := &storage.RecordFilter{Conversations: []string{"a"}}
+	window, err = chatRepo.GetAdjacentChatRecords(ctx, added[4].Id, 1, 2, filter)
+	require.NoError(t, err)
+	require.Equal(t, []string{"Message 2", "Message 4", "Message 6", "Message 8"}, contents(window))
+
+	// Clipped at the ends of the history
+	window, err = chatRepo.GetAdjacentChatRecords(ctx, added[0].Id, 3, 0, nil)
+	require.NoError(t, err)
+	require.Equal(t, []string{"Message 0"}, contents(window))
+	window, err = chatRepo.GetAdjacentChatRecords(ctx, added[9].Id, 0, 3, nil)
+	require.NoError(t, err)
+	require.Equal(t, []string{"Message 9"}, contents(window))
+
+	_, err = chatRepo.GetAdjacentChatRecords(ctx, core.ID(99999), 1, 1, nil)
+	require.ErrorIs(t, err, storage.ErrNotFound)
+}
+
+func TestGetAdjacentChatRecords_Bounded(t *testing.T) {
+	chatRepo, conceptRepo, backend, err := NewMemoryRepositories()
+	require.NoError(t, err)
+	defer func() { conceptRepo.Close(); chatRepo.Close(); backend.Close() }()
+
+	ctx := context.Background()
+
+	// Two messages of conversation "a" separated by more than the scan bound
+	// of messages from conversation "b"
+	now := time.Date(2025, time.June, 1, 12, 0, 0, 0, time.UTC)
+	message := func(i int, conversation string) *core.ChatRecord {
+		return &core.ChatRecord{
+			Speaker:   core.SpeakerTypeHuman,
+			Contents:  fmt.Sprintf("Message %d", i),
+			Timestamp: now.Add(time.Duration(i) * time.Second),
+			Metadata:  map[string]string{core.MetadataConversation: conversation},
+		}
+	}
+	records := []*core.ChatRecord{message(0, "a")}
+	for i := 1; i <= maxAdjacentScan; i++ {
+		records = append(records, message(i, "b"))
+	}
+	records = append(records, message(maxAdjacentScan+1, "a"))
+	added, err := chatRepo.AddChatRecords(ctx, records...)
+	require.NoError(t, err)
+	first, last := added[0], added[len(added)-1]
+
+	filter := &storage.RecordFilter{Conversations: []string{"a"}}
+	window, err := chatRepo.GetAdjacentChatRecords(ctx, last.Id, 1, 0, filter)
+	require.NoError(t, err)
+	require.Len(t, window, 1, "the earlier message is past the scan bound")
+	require.Equal(t, last.Id, window[0].Id)
+
+	window, err = chatRepo.GetAdjacentChatRecords(ctx, first.Id, 0, 1, filter)
+	require.NoError(t, err)
+	require.Len(t, window, 1, "the later message is past the scan bound")
+
+	// Unfiltered neighbors are found right away
+	window, err = chatRepo.GetAdjacentChatRecords(ctx, last.Id, 1, 0, nil)
+	require.NoError(t, err)
+	require.Len(t, window, 2)
+
+	// The walk stops at the filter's time range
+	filter = &storage.RecordFilter{Start: added[10].Timestamp, End: added[12].Timestamp}
+	window, err = chatRepo.GetAdjacentChatRecords(ctx, added[11].Id, 5, 5, filter)
+	require.NoError(t, err)
+	require.Len(t, window, 2)
+	require.Equal(t, []core.ID{added[10].Id, added[11].Id}, []core.ID{window[0].Id, window[1].Id})
+}
+
 func TestGetConceptsByDateRange(t *testing.T) {
 	chatRepo, conceptRepo, backend, err := NewMemoryRepositories()
 	require.NoError(t, err, "Failed to create repositories")
@@ -559,3 +667,85 @@ func TestGetConceptsByDateRange(t *testing.T) {
 		require.Equal(t, "practice", results[0].Type)
 	})
 }
+
+func TestChunks(t *testing.T) {
+	chatRepo, conceptRepo, backend, err := NewMemoryRepositories()
+	require.NoError(t, err)
+	defer func() {
+		conceptRepo.Close()
+		chatRepo.Close()
+		backend.Close()
+	}()
+
+	ctx := context.Background()
+
+	added, err := chatRepo.AddChatRecords(ctx, &core.ChatRecord{
+		Speaker:   core.SpeakerTypeHuman,
+		Contents:  "first part second part",
+		Timestamp: time.Now().UTC(),
+	})
+	require.NoError(t, err)
+	recordID := added[0].Id
+
+	// No chunks yet
+	chunks, err := chatRepo.GetChunks(ctx, recordID)
+	require.NoError(t, err)
+	require.Empty(t, chunks)
+
+	err = chatRepo.ReplaceChunks(ctx, recordID, []core.Chunk{
+		{Index: 0, Start: 0, End: 10, Vector: []float32{1, 0}},
+		{Index: 1, Start: 11, End: 22, Vector: []float32{0, 1}},
+	})
+	require.NoError(t, err)
+
+	chunks, err = chatRepo.GetChunks(ctx, recordID)
+	require.NoError(t, err)
+	require.Len(t, chunks, 2)
+	require.Equal(t, recordID, chunks[0].RecordId)
+	require.Equal(t, "first part", chunks[0].Span().Of(added[0].Contents))
+	require.Equal(t, "second part", chunks[1].Span().Of(added[0].Contents))
+
+	// Replacing removes chunks that are no longer present
+	err = chatRepo.ReplaceChunks(ctx, recordID, []core.Chunk{
+		{Index: 0, Start: 0, End: 22, Vector: []float32{1, 1}},
+	})
+	require.NoError(t, err)
+	chunks, err = chatRepo.GetChunks(ctx, recordID)
+	require.NoError(t, err)
+	require.Len(t, chunks, 1)
+
+	// Updating a record without changing its contents keeps its chunks
+	record := added[0]
+	record.Vector = []float32{1, 0}
+	_, err = chatRepo.UpdateChatRecords(ctx, record)
+	require.NoError(t, err)
+	chunks, err = chatRepo.GetChunks(ctx, recordID)
+	require.NoError(t, err)
+	require.Len(t, chunks, 1)
+
+	// Changing the contents drops the chunks, which covered the old contents
+	err = chatRepo.ReplaceChunks(ctx, recordID, []core.Chunk{
+		{Index: 0, Start: 0, End: 10, Vector: []float32{1, 0}},
+		{Index: 1, Start: 11, End: 22, Vector: []float32{0, 1}},
+	})
+	require.NoError(t, err)
+	record.Contents = "short"
+	_, err = chatRepo.UpdateChatRecords(ctx, record)
+	require.NoError(t, err)
+	chunks, err = chatRepo.GetChunks(ctx, recordID)
+	require.NoError(t, err)
+	require.Empty(t, chunks)
+	results, err := backend.FindSimilar(ctx, []float32{0, 1}, 0.5, 10)
+	require.NoError(t, err)
+	require.Empty(t, results, "the dropped chunk vector no longer matches")
+
+	// Chunks for unknown records are rejected
+	err = chatRepo.ReplaceChunks(ctx, recordID+100, []core.Chunk{{Index: 0}})
+	require.ErrorIs(t, err, storage.ErrNotFound)
+
+	// Deleting the record deletes its chunks
+	require.NoError(t, chatRepo.DeleteChatRecords(ctx, recordID))
+	chunks, err = chatRepo.GetChunks(ctx, recordID)
+	require.NoError(t, err)
+	require.Empty(t, chunks)
+}
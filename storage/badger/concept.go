@@ -16,7 +16,9 @@
 package badger
 
 import (
+	"cmp"
 	"context"
+	"slices"
 	"time"
 
 	"github.com/dgraph-io/badger/v4"
@@ -29,7 +31,10 @@ type ConceptRepository struct {
 	backend *Backend
 }
 
-var _ storage.ConceptRepository = (*ConceptRepository)(nil)
+var (
+	_ storage.ConceptRepository = (*ConceptRepository)(nil)
+	_ storage.ChangeTracker     = (*ConceptRepository)(nil)
+)
 
 // NewConceptRepository creates a new ConceptRepository.
 func NewConceptRepository(backend *Backend) (*ConceptRepository, error) {
@@ -48,6 +53,11 @@ func (r *ConceptRepository) FindSimilar(ctx context.Context, vector []float32, m
 	return r.backend.FindSimilar(ctx, vector, minSimilarity, limit)
 }
 
+// Version delegates to the backend.
+func (r *ConceptRepository) Version() uint64 {
+	return r.backend.Version()
+}
+
 // WithTransaction delegates to the backend.
 func (r *ConceptRepository) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
 	return r.backend.WithTransaction(ctx, fn)
@@ -79,7 +89,7 @@ func (r *ConceptRepository) AddConcepts(ctx context.Context, concepts ...*core.C
 				return err
 			}
 		}
-		return tx.Commit()
+		return r.backend.commit(tx, nil)
 	}, true)
 
 	return concepts, err
@@ -121,7 +131,7 @@ func (r *ConceptRepository) UpdateConcepts(ctx context.Context, concepts ...*cor
 				}
 			}
 		}
-		return tx.Commit()
+		return r.backend.commit(tx, nil)
 	}, true)
 
 	return concepts, err
@@ -153,7 +163,7 @@ func (r *ConceptRepository) DeleteConcepts(ctx context.Context, ids ...core.ID)
 				return err
 			}
 		}
-		return tx.Commit()
+		return r.backend.commit(tx, nil)
 	}, true)
 }
 
@@ -264,6 +274,59 @@ func (r *ConceptRepository) GetOrCreateConcept(ctx context.Context, name, concep
 	return added[0], nil
 }
 
+// FindSimilarConcepts scans every concept vector and returns the closest matches.
+func (r *ConceptRepository) FindSimilarConcepts(ctx context.Context, vector []float32, minSimilarity float32, limit int) ([]*core.ConceptSearchResult, error) {
+	if limit <= 0 {
+		return []*core.ConceptSearchResult{}, nil
+	}
+
+	results := []*core.ConceptSearchResult{}
+	err := r.backend.WithTx(func(tx *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.Prefix = []byte(conceptRecordPrefix + ":")
+		iter := tx.NewIterator(opts)
+		defer iter.Close()
+
+		for iter.Rewind(); iter.Valid(); iter.Next() {
+			var concept *core.Concept
+			err := iter.Item().Value(func(val []byte) error {
+				var err error
+				concept, err = storage.UnmarshalConcept(val)
+				return err
+			})
+			if err != nil {
+				return err
+			}
+			if concept == nil || len(concept.Vector) == 0 {
+				continue
+			}
+
+			score := dotProduct(vector, concept.Vector)
+			if score >= minSimilarity {
+				results = append(results, &core.ConceptSearchResult{Concept: concept, Score: score})
+			}
+		}
+		return nil
+	}, false)
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(results, func(a, b *core.ConceptSearchResult) int {
+		if a.Score != b.Score {
+			if a.Score > b.Score {
+				return -1
+			}
+			return 1
+		}
+		return cmp.Compare(a.Concept.Id, b.Concept.Id)
+	})
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
+
 // GetAllConcepts retrieves all concepts from storage.
 func (r *ConceptRepository) GetAllConcepts(ctx context.Context) ([]*core.Concept, error) {
 	var results []*core.Concept
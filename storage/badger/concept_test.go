@@ -250,3 +250,50 @@ func TestConceptRepository_FindSimilar(t *testing.T) {
 		}
 	}
 }
+
+func TestConceptRepository_FindSimilarConcepts(t *testing.T) {
+	chatRepo, conceptRepo, backend, err := NewMemoryRepositories()
+	if err != nil {
+		t.Fatalf("Failed to create repositories: %v", err)
+	}
+	defer func() { conceptRepo.Close(); chatRepo.Close(); backend.Close() }()
+
+	ctx := context.Background()
+
+	dog, err := conceptRepo.GetOrCreateConcept(ctx, "dog", "animal", []float32{1.0, 0.0, 0.0})
+	if err != nil {
+		t.Fatalf("Failed to create concept: %v", err)
+	}
+	cat, err := conceptRepo.GetOrCreateConcept(ctx, "cat", "animal", []float32{0.8, 0.6, 0.0})
+	if err != nil {
+		t.Fatalf("Failed to create concept: %v", err)
+	}
+	if _, err := conceptRepo.GetOrCreateConcept(ctx, "terraform", "software", []float32{0.0, 0.0, 1.0}); err != nil {
+		t.Fatalf("Failed to create concept: %v", err)
+	}
+	if _, err := conceptRepo.GetOrCreateConcept(ctx, "unembedded", "thing", nil); err != nil {
+		t.Fatalf("Failed to create concept: %v", err)
+	}
+
+	results, err := conceptRepo.FindSimilarConcepts(ctx, []float32{0.96, 0.28, 0.0}, 0.5, 10)
+	if err != nil {
+		t.Fatalf("Failed to find similar concepts: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("Expected 2 concepts, got %d", len(results))
+	}
+	if results[0].Concept.Id != dog.Id || results[1].Concept.Id != cat.Id {
+		t.Errorf("Expected dog then cat, got %s then %s", results[0].Concept.Tuple(), results[1].Concept.Tuple())
+	}
+	if results[0].Score < results[1].Score {
+		t.Error("Results should be sorted by score descending")
+	}
+
+	results, err = conceptRepo.FindSimilarConcepts(ctx, []float32{0.96, 0.28, 0.0}, 0.5, 1)
+	if err != nil {
+		t.Fatalf("Failed to find similar concepts: %v", err)
+	}
+	if len(results) != 1 || results[0].Concept.Id != dog.Id {
+		t.Errorf("Expected only dog with limit 1, got %d results", len(results))
+	}
+}
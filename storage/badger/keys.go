@@ -29,6 +29,9 @@ const (
 	chatRecordDatePrefix    = "charecd"
 	chatRecordConceptPrefix = "charecc"
 	chatRecordIDSeq         = "charecseq"
+	chatChunkPrefix         = "chachk"
+	chatQuantPrefix         = "chaqnt"
+	quantizationModeKey     = "chaqntmode"
 	conceptRecordPrefix     = "conrec"
 	conceptTypeNamePrefix   = "contyna"
 )
@@ -98,6 +101,59 @@ func makePartialChatConceptKey(conceptID core.ID) []byte {
 	return buf
 }
 
+// makeChatChunkKey generates a composite key for a chunk of a chat record.
+// Format: prefix:recordID:index
+func makeChatChunkKey(recordID core.ID, index int) []byte {
+	buf := makePartialChatChunkKey(recordID)
+	// Write in BigEndian order so chunks sort by index within a record
+	return binary.BigEndian.AppendUint32(buf, uint32(index))
+}
+
+// makePartialChatChunkKey generates a partial key covering all chunks of a record.
+// Format: prefix:recordID
+func makePartialChatChunkKey(recordID core.ID) []byte {
+	prefix := chatChunkPrefix + ":"
+	prefixBytes := []byte(prefix)
+	prefixSize := len(prefixBytes)
+	totalSize := prefixSize + 8 // 8 bytes for recordID
+	buf := make([]byte, totalSize, totalSize+4)
+	offset := copy(buf, prefixBytes)
+	// Write in BigEndian order so lexicographic sort works correctly
+	binary.BigEndian.PutUint64(buf[offset:], uint64(recordID))
+	return buf
+}
+
+// makeChatQuantKey generates a composite key for a quantized vector row of a chat record.
+// Row 0 is the whole-record vector and row i+1 is chunk i.
+// Format: prefix:recordID:row
+func makeChatQuantKey(recordID core.ID, row int) []byte {
+	buf := makePartialChatQuantKey(recordID)
+	// Write in BigEndian order so rows sort within a record
+	return binary.BigEndian.AppendUint32(buf, uint32(row))
+}
+
+// makePartialChatQuantKey generates a partial key covering all quantized rows of a record.
+// Format: prefix:recordID
+func makePartialChatQuantKey(recordID core.ID) []byte {
+	prefix := chatQuantPrefix + ":"
+	prefixBytes := []byte(prefix)
+	prefixSize := len(prefixBytes)
+	totalSize := prefixSize + 8 // 8 bytes for recordID
+	buf := make([]byte, totalSize, totalSize+4)
+	offset := copy(buf, prefixBytes)
+	// Write in BigEndian order so lexicographic sort works correctly
+	binary.BigEndian.PutUint64(buf[offset:], uint64(recordID))
+	return buf
+}
+
+// parseChatQuantKey extracts the record ID and row from a quantized vector key.
+func parseChatQuantKey(key []byte) (core.ID, int) {
+	offset := len(chatQuantPrefix) + 1
+	id := core.ID(binary.BigEndian.Uint64(key[offset:]))
+	row := int(binary.BigEndian.Uint32(key[offset+8:]))
+	return id, row
+}
+
 // makeConceptKey generates a key for a concept by ID.
 func makeConceptKey(id core.ID) []byte {
 	return []byte(fmt.Sprintf("%s:%d", conceptRecordPrefix, id))
@@ -0,0 +1,141 @@
+// Copyright 2025 Poiesic Systems
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+
+package badger
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/dgraph-io/badger/v4/options"
+)
+
+const (
+	defaultGCInterval     = 5 * time.Minute
+	defaultGCDiscardRatio = 0.5
+)
+
+// Compression selects the block compression algorithm used by BadgerDB.
+type Compression int
+
+const (
+	// CompressionNone stores blocks uncompressed.
+	CompressionNone Compression = iota
+	// CompressionSnappy compresses blocks with Snappy.
+	CompressionSnappy
+	// CompressionZSTD compresses blocks with ZSTD.
+	CompressionZSTD
+)
+
+// BackendOption configures optional Backend behavior.
+type BackendOption func(*backendOptions)
+
+// backendOptions holds the configuration applied by BackendOptions.
+type backendOptions struct {
+	logger         *slog.Logger
+	tuning         []func(*badger.Options)
+	gcInterval     time.Duration
+	gcDiscardRatio float64
+	vectorCache    bool
+	searchWorkers  int
+	quantization   QuantizationMode
+	oversample     int
+}
+
+// WithLogger sets the logger used by the backend and BadgerDB.
+func WithLogger(logger *slog.Logger) BackendOption {
+	return func(o *backendOptions) {
+		o.logger = logger
+	}
+}
+
+// WithBlockCacheSize sets the size in bytes of the block cache.
+// The block cache is required when compression is enabled.
+func WithBlockCacheSize(size int64) BackendOption {
+	return func(o *backendOptions) {
+		o.tuning = append(o.tuning, func(opts *badger.Options) {
+			opts.BlockCacheSize = size
+		})
+	}
+}
+
+// WithIndexCacheSize sets the size in bytes of the table index cache.
+// Zero keeps all indices in memory.
+func WithIndexCacheSize(size int64) BackendOption {
+	return func(o *backendOptions) {
+		o.tuning = append(o.tuning, func(opts *badger.Options) {
+			opts.IndexCacheSize = size
+		})
+	}
+}
+
+// WithCompression sets the block compression algorithm.
+func WithCompression(compression Compression) BackendOption {
+	return func(o *backendOptions) {
+		o.tuning = append(o.tuning, func(opts *badger.Options) {
+			switch compression {
+			case CompressionSnappy:
+				opts.Compression = options.Snappy
+			case CompressionZSTD:
+				opts.Compression = options.ZSTD
+			default:
+				opts.Compression = options.None
+			}
+		})
+	}
+}
+
+// WithSyncWrites makes every write wait for the data to be synced to disk.
+func WithSyncWrites(sync bool) BackendOption {
+	return func(o *backendOptions) {
+		o.tuning = append(o.tuning, func(opts *badger.Options) {
+			opts.SyncWrites = sync
+		})
+	}
+}
+
+// WithValueLogFileSize sets the maximum size in bytes of a single value log file.
+func WithValueLogFileSize(size int64) BackendOption {
+	return func(o *backendOptions) {
+		o.tuning = append(o.tuning, func(opts *badger.Options) {
+			opts.ValueLogFileSize = size
+		})
+	}
+}
+
+// WithMemTableSize sets the size in bytes of each memtable.
+func WithMemTableSize(size int64) BackendOption {
+	return func(o *backendOptions) {
+		o.tuning = append(o.tuning, func(opts *badger.Options) {
+			opts.MemTableSize = size
+		})
+	}
+}
+
+// WithGCInterval sets how often value log garbage collection runs. Default is 5 minutes.
+func WithGCInterval(interval time.Duration) BackendOption {
+	return func(o *backendOptions) {
+		o.gcInterval = interval
+	}
+}
+
+// WithGCDiscardRatio sets the fraction of a value log file that must be
+// discardable before garbage collection rewrites it. Default is 0.5.
+func WithGCDiscardRatio(ratio float64) BackendOption {
+	return func(o *backendOptions) {
+		o.gcDiscardRatio = ratio
+	}
+}
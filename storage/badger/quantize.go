@@ -0,0 +1,577 @@
+// Copyright 2025 Poiesic Systems
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+
+package badger
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"math/bits"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/poiesic/memorit/core"
+	"github.com/poiesic/memorit/storage"
+)
+
+// QuantizationMode selects how vectors are compressed for the first search pass.
+type QuantizationMode uint8
+
+const (
+	// QuantizationNone disables quantized search.
+	QuantizationNone QuantizationMode = iota
+	// QuantizationInt8 stores each dimension as a signed byte with a per-vector scale.
+	QuantizationInt8
+	// QuantizationBinary stores the sign of each dimension as a single bit.
+	QuantizationBinary
+)
+
+// defaultOversample is the number of candidates per requested result that
+// are rescored with full-precision vectors.
+const defaultOversample = 4
+
+// String returns the name of the quantization mode.
+func (m QuantizationMode) String() string {
+	switch m {
+	case QuantizationNone:
+		return "none"
+	case QuantizationInt8:
+		return "int8"
+	case QuantizationBinary:
+		return "binary"
+	default:
+		return fmt.Sprintf("QuantizationMode(%d)", m)
+	}
+}
+
+// ParseQuantizationMode converts a mode name ("none", "int8", "binary") to a QuantizationMode.
+func ParseQuantizationMode(name string) (QuantizationMode, error) {
+	switch name {
+	case "none", "":
+		return QuantizationNone, nil
+	case "int8":
+		return QuantizationInt8, nil
+	case "binary":
+		return QuantizationBinary, nil
+	default:
+		return QuantizationNone, fmt.Errorf("unknown quantization mode %q", name)
+	}
+}
+
+// quantCode is a quantized vector.
+// Encoded format: mode (1 byte), dim (4 bytes), scale (4 bytes), code bytes.
+type quantCode struct {
+	mode  QuantizationMode
+	dim   int
+	scale float32
+	code  []byte
+}
+
+// quantize compresses a vector using the given mode.
+func quantize(mode QuantizationMode, vector []float32) quantCode {
+	qc := quantCode{mode: mode, dim: len(vector)}
+	switch mode {
+	case QuantizationInt8:
+		var maxAbs float32
+		for _, v := range vector {
+			maxAbs = max(maxAbs, float32(math.Abs(float64(v))))
+		}
+		qc.code = make([]byte, len(vector))
+		if maxAbs == 0 {
+			return qc
+		}
+		qc.scale = maxAbs / 127
+		for i, v := range vector {
+			qc.code[i] = byte(int8(math.Round(float64(v / qc.scale))))
+		}
+	case QuantizationBinary:
+		qc.code = make([]byte, (len(vector)+7)/8)
+		for i, v := range vector {
+			if v > 0 {
+				qc.code[i/8] |= 1 << (i % 8)
+			}
+		}
+	}
+	return qc
+}
+
+// similarity approximates the cosine similarity of two codes of the same mode.
+func (qc *quantCode) similarity(other *quantCode) float32 {
+	switch qc.mode {
+	case QuantizationInt8:
+		n := min(len(qc.code), len(other.code))
+		var sum int32
+		for i := 0; i < n; i++ {
+			sum += int32(int8(qc.code[i])) * int32(int8(other.code[i]))
+		}
+		return float32(sum) * qc.scale * other.scale
+	case QuantizationBinary:
+		n := min(qc.dim, other.dim)
+		if n == 0 {
+			return 0
+		}
+		hamming := hammingDistance(qc.code[:(n+7)/8], other.code[:(n+7)/8])
+		// Sign agreement maps to [-1, 1], roughly tracking cosine similarity
+		return 1 - 2*float32(hamming)/float32(n)
+	default:
+		return 0
+	}
+}
+
+// hammingDistance counts the differing bits of two equal-length bit strings.
+func hammingDistance(a, b []byte) int {
+	distance := 0
+	i := 0
+	for ; i+8 <= len(a); i += 8 {
+		distance += bits.OnesCount64(binary.LittleEndian.Uint64(a[i:]) ^ binary.LittleEndian.Uint64(b[i:]))
+	}
+	for ; i < len(a); i++ {
+		distance += bits.OnesCount8(a[i] ^ b[i])
+	}
+	return distance
+}
+
+// marshal encodes the code for storage.
+func (qc *quantCode) marshal() []byte {
+	buf := make([]byte, 9, 9+len(qc.code))
+	buf[0] = byte(qc.mode)
+	binary.BigEndian.PutUint32(buf[1:], uint32(qc.dim))
+	binary.BigEndian.PutUint32(buf[5:], math.Float32bits(qc.scale))
+	return append(buf, qc.code...)
+}
+
+// unmarshalQuantCode decodes a stored code. The code bytes alias data.
+func unmarshalQuantCode(data []byte) (quantCode, error) {
+	if len(data) < 9 {
+		return quantCode{}, fmt.Errorf("quantized vector too short: %d bytes", len(data))
+	}
+	return quantCode{
+		mode:  QuantizationMode(data[0]),
+		dim:   int(binary.BigEndian.Uint32(data[1:])),
+		scale: math.Float32frombits(binary.BigEndian.Uint32(data[5:])),
+		code:  data[9:],
+	}, nil
+}
+
+// WithQuantization enables quantized vector search. Every record and chunk
+// vector also gets a compact code; FindSimilar ranks the codes first and
+// rescores the best limit*oversample candidates with full-precision vectors.
+// oversample <= 0 uses the default of 4. The codes are stored in addition to
+// the full-precision vectors, which rescoring needs, so quantization makes the
+// database larger; it speeds up search rather than saving space. When the
+// stored codes were written with a different mode, or the database was last
+// opened without quantization or while regenerating them, they are
+// regenerated while the backend opens.
+func WithQuantization(mode QuantizationMode, oversample int) BackendOption {
+	return func(o *backendOptions) {
+		o.quantization = mode
+		o.oversample = oversample
+	}
+}
+
+// Quantization returns the quantization mode used by the backend.
+func (b *Backend) Quantization() QuantizationMode {
+	return b.quantization
+}
+
+// QuantizeVectors regenerates the quantized codes of every stored record and
+// chunk vector using the backend's quantization mode, replacing existing codes.
+// With QuantizationNone the codes are removed. Returns the number of vectors quantized.
+// It should not run concurrently with repository writes.
+func (b *Backend) QuantizeVectors(ctx context.Context) (int, error) {
+	// Clear the stored mode before touching the codes, so a run that does
+	// not finish leaves them stale and the next open regenerates them.
+	err := b.db.Update(func(tx *badger.Txn) error {
+		return tx.Delete([]byte(quantizationModeKey))
+	})
+	if err != nil {
+		return 0, err
+	}
+	if err := b.db.DropPrefix([]byte(chatQuantPrefix + ":")); err != nil {
+		return 0, err
+	}
+	if b.quantization == QuantizationNone {
+		return 0, nil
+	}
+
+	batch := b.db.NewWriteBatch()
+	defer batch.Cancel()
+
+	count := 0
+	write := func(id core.ID, row int, vector []float32) error {
+		if len(vector) == 0 {
+			return nil
+		}
+		count++
+		code := quantize(b.quantization, vector)
+		return batch.Set(makeChatQuantKey(id, row), code.marshal())
+	}
+
+	err = b.db.View(func(tx *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.Prefix = []byte(chatRecordPrefix)
+		iter := tx.NewIterator(opts)
+		defer iter.Close()
+
+		for iter.Rewind(); iter.Valid(); iter.Next() {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if isChatIndexKey(iter.Item().Key()) {
+				continue
+			}
+			var record *core.ChatRecord
+			err := iter.Item().Value(func(val []byte) error {
+				var err error
+				record, err = storage.UnmarshalChatRecord(val)
+				return err
+			})
+			if err != nil {
+				return err
+			}
+			if err := write(record.Id, 0, record.Vector); err != nil {
+				return err
+			}
+		}
+
+		chunkOpts := badger.DefaultIteratorOptions
+		chunkOpts.Prefix = []byte(chatChunkPrefix + ":")
+		chunkIter := tx.NewIterator(chunkOpts)
+		defer chunkIter.Close()
+
+		for chunkIter.Rewind(); chunkIter.Valid(); chunkIter.Next() {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			var chunk *core.Chunk
+			err := chunkIter.Item().Value(func(val []byte) error {
+				var err error
+				chunk, err = storage.UnmarshalChunk(val)
+				return err
+			})
+			if err != nil {
+				return err
+			}
+			if err := write(chunk.RecordId, chunk.Index+1, chunk.Vector); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	if err := batch.Flush(); err != nil {
+		return 0, err
+	}
+
+	// Only record the mode once every code is written
+	err = b.db.Update(func(tx *badger.Txn) error {
+		return tx.Set([]byte(quantizationModeKey), []byte{byte(b.quantization)})
+	})
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// syncQuantization regenerates codes when the stored mode differs from the configured one.
+func (b *Backend) syncQuantization(ctx context.Context) error {
+	stored := QuantizationNone
+	err := b.db.View(func(tx *badger.Txn) error {
+		item, err := tx.Get([]byte(quantizationModeKey))
+		if err == badger.ErrKeyNotFound {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		return item.Value(func(val []byte) error {
+			if len(val) == 1 {
+				stored = QuantizationMode(val[0])
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return err
+	}
+	if stored == b.quantization {
+		return nil
+	}
+
+	if b.quantization == QuantizationNone {
+		// Writes made without quantization won't maintain the codes, so mark
+		// them stale; they are regenerated the next time quantization is enabled.
+		// QuantizeVectors removes them outright.
+		return b.db.Update(func(tx *badger.Txn) error {
+			return tx.Delete([]byte(quantizationModeKey))
+		})
+	}
+
+	b.logger.Info("quantizing stored vectors", "from", stored.String(), "to", b.quantization.String())
+	count, err := b.QuantizeVectors(ctx)
+	if err != nil {
+		return err
+	}
+	b.logger.Info("quantized stored vectors", "vectors", count)
+	return nil
+}
+
+// setQuantized stores the code for one vector row of a record.
+// An empty vector removes the row. No-op when quantization is disabled.
+func (b *Backend) setQuantized(tx *badger.Txn, id core.ID, row int, vector []float32) error {
+	if b.quantization == QuantizationNone {
+		return nil
+	}
+	key := makeChatQuantKey(id, row)
+	if len(vector) == 0 {
+		return tx.Delete(key)
+	}
+	code := quantize(b.quantization, vector)
+	return tx.Set(key, code.marshal())
+}
+
+// deleteQuantized removes the codes of a record starting at fromRow.
+// No-op when quantization is disabled.
+func (b *Backend) deleteQuantized(tx *badger.Txn, id core.ID, fromRow int) error {
+	if b.quantization == QuantizationNone {
+		return nil
+	}
+	opts := badger.DefaultIteratorOptions
+	opts.Prefix = makePartialChatQuantKey(id)
+	opts.PrefetchValues = false
+	iter := tx.NewIterator(opts)
+	defer iter.Close()
+
+	var keys [][]byte
+	for iter.Rewind(); iter.Valid(); iter.Next() {
+		key := iter.Item().KeyCopy(nil)
+		if _, row := parseChatQuantKey(key); row >= fromRow {
+			keys = append(keys, key)
+		}
+	}
+	for _, key := range keys {
+		if err := tx.Delete(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// findSimilarQuantized ranks records by their quantized codes, then rescores
+// the best candidates with full-precision record and chunk vectors.
+// Time-bounded filters only score the codes of records in range.
+func (b *Backend) findSimilarQuantized(vector []float32, minSimilarity float32, limit int, filter *storage.RecordFilter) ([]*core.SearchResult, error) {
+	if limit <= 0 {
+		return nil, nil
+	}
+	query := quantize(b.quantization, vector)
+	candidates := limit * b.oversample
+
+	var results []*core.SearchResult
+	err := b.WithTx(func(tx *badger.Txn) error {
+		// First pass: best approximate score per record. With a filter, only
+		// records that would enter the candidate heap are read and tested.
+		var top hitHeap
+		records := make(map[core.ID]*core.ChatRecord)
+		offer := func(hit cacheHit) error {
+			if filter != nil {
+				if top.Len() >= candidates && !top[0].worse(hit) {
+					return nil
+				}
+				record, err := readRecord(tx, hit.id)
+				if err != nil {
+					return err
+				}
+				if record == nil || !filter.Matches(record) {
+					return nil
+				}
+				records[hit.id] = record
+			}
+			top.offer(hit, candidates)
+			return nil
+		}
+
+		score := func(item *badger.Item) (float32, error) {
+			var score float32
+			err := item.Value(func(val []byte) error {
+				code, err := unmarshalQuantCode(val)
+				if err != nil {
+					return err
+				}
+				score = query.similarity(&code)
+				return nil
+			})
+			return score, err
+		}
+
+		opts := badger.DefaultIteratorOptions
+		opts.Prefix = []byte(chatQuantPrefix + ":")
+		iter := tx.NewIterator(opts)
+		defer iter.Close()
+
+		// Time-bounded filters only score the records in range, found through the date index
+		if timeBounded(filter) {
+			err := scanDateIndex(tx, filter.Start, filter.End, func(id core.ID) error {
+				prefix := makePartialChatQuantKey(id)
+				hit := cacheHit{id: id}
+				found := false
+				for iter.Seek(prefix); iter.ValidForPrefix(prefix); iter.Next() {
+					s, err := score(iter.Item())
+					if err != nil {
+						return err
+					}
+					if !found || s > hit.score {
+						hit.score = s
+						found = true
+					}
+				}
+				if !found {
+					return nil
+				}
+				return offer(hit)
+			})
+			if err != nil {
+				return err
+			}
+		} else {
+			current := cacheHit{}
+			for iter.Rewind(); iter.Valid(); iter.Next() {
+				id, _ := parseChatQuantKey(iter.Item().Key())
+				s, err := score(iter.Item())
+				if err != nil {
+					return err
+				}
+				// Codes are grouped by record, so each record is offered once
+				if id != current.id {
+					if current.id != 0 {
+						if err := offer(current); err != nil {
+							return err
+						}
+					}
+					current = cacheHit{id: id, score: s}
+				} else if s > current.score {
+					current.score = s
+				}
+			}
+			if current.id != 0 {
+				if err := offer(current); err != nil {
+					return err
+				}
+			}
+		}
+
+		// Second pass: exact scores for the candidates
+		for _, hit := range top {
+			record, ok := records[hit.id]
+			if !ok {
+				var err error
+				if record, err = readRecord(tx, hit.id); err != nil {
+					return err
+				}
+			}
+			if record == nil {
+				continue
+			}
+			chunks, err := readChunks(tx, hit.id)
+			if err != nil {
+				return err
+			}
+			score, span, ok := scoreRecord(vector, record, chunks)
+			if ok && score >= minSimilarity {
+				results = append(results, &core.SearchResult{Record: record, Score: score, Span: span})
+			}
+		}
+		return nil
+	}, false)
+	if err != nil {
+		return nil, err
+	}
+
+	sortResults(results)
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
+
+// readRecord reads a chat record by ID, returning nil if it does not exist.
+func readRecord(tx *badger.Txn, id core.ID) (*core.ChatRecord, error) {
+	item, err := tx.Get(makeChatRecordKey(id))
+	if err == badger.ErrKeyNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var record *core.ChatRecord
+	err = item.Value(func(val []byte) error {
+		var err error
+		record, err = storage.UnmarshalChatRecord(val)
+		return err
+	})
+	return record, err
+}
+
+// readChunks reads the chunks of a record ordered by index.
+func readChunks(tx *badger.Txn, id core.ID) ([]core.Chunk, error) {
+	var chunks []core.Chunk
+	opts := badger.DefaultIteratorOptions
+	opts.Prefix = makePartialChatChunkKey(id)
+	iter := tx.NewIterator(opts)
+	defer iter.Close()
+
+	for iter.Rewind(); iter.Valid(); iter.Next() {
+		var chunk *core.Chunk
+		err := iter.Item().Value(func(val []byte) error {
+			var err error
+			chunk, err = storage.UnmarshalChunk(val)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		chunks = append(chunks, *chunk)
+	}
+	return chunks, nil
+}
+
+// scoreRecord scores a record by the better of its own vector and its best chunk.
+// Returns false if the record has no vectors.
+func scoreRecord(vector []float32, record *core.ChatRecord, chunks []core.Chunk) (float32, *core.Span, bool) {
+	var best float32
+	var span *core.Span
+	found := false
+	if len(record.Vector) > 0 {
+		best = dotProduct(vector, record.Vector)
+		found = true
+	}
+	for i := range chunks {
+		if len(chunks[i].Vector) == 0 {
+			continue
+		}
+		score := dotProduct(vector, chunks[i].Vector)
+		if !found || score > best {
+			best = score
+			chunkSpan := chunks[i].Span()
+			span = &chunkSpan
+			found = true
+		}
+	}
+	return best, span, found
+}
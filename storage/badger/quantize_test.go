@@ -0,0 +1,233 @@
+package badger
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/poiesic/memorit/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func randomUnitVector(rng *rand.Rand, dim int) []float32 {
+	v := make([]float32, dim)
+	var norm float64
+	for i := range v {
+		v[i] = float32(rng.NormFloat64())
+		norm += float64(v[i] * v[i])
+	}
+	for i := range v {
+		v[i] /= float32(math.Sqrt(norm))
+	}
+	return v
+}
+
+func countQuantCodes(t *testing.T, backend *Backend) int {
+	t.Helper()
+	count := 0
+	err := backend.WithTx(func(tx *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.Prefix = []byte(chatQuantPrefix + ":")
+		iter := tx.NewIterator(opts)
+		defer iter.Close()
+		for iter.Rewind(); iter.Valid(); iter.Next() {
+			count++
+		}
+		return nil
+	}, false)
+	require.NoError(t, err)
+	return count
+}
+
+func TestQuantize_Similarity(t *testing.T) {
+	rng := rand.New(rand.NewSource(7))
+	a := randomUnitVector(rng, 64)
+	b := randomUnitVector(rng, 64)
+	exact := dotProduct(a, b)
+
+	t.Run("int8", func(t *testing.T) {
+		qa := quantize(QuantizationInt8, a)
+		qb := quantize(QuantizationInt8, b)
+		assert.InDelta(t, exact, qa.similarity(&qb), 0.02)
+		self := quantize(QuantizationInt8, a)
+		assert.InDelta(t, 1.0, qa.similarity(&self), 0.02)
+	})
+
+	t.Run("binary", func(t *testing.T) {
+		qa := quantize(QuantizationBinary, a)
+		neg := make([]float32, len(a))
+		for i := range a {
+			neg[i] = -a[i]
+		}
+		qn := quantize(QuantizationBinary, neg)
+		self := quantize(QuantizationBinary, a)
+		assert.Equal(t, float32(1), qa.similarity(&self))
+		assert.Equal(t, float32(-1), qa.similarity(&qn))
+	})
+
+	t.Run("zero vector", func(t *testing.T) {
+		qz := quantize(QuantizationInt8, make([]float32, 4))
+		qa := quantize(QuantizationInt8, a[:4])
+		assert.Equal(t, float32(0), qz.similarity(&qa))
+	})
+}
+
+func TestQuantCode_MarshalRoundTrip(t *testing.T) {
+	code := quantize(QuantizationInt8, []float32{0.5, -0.25, 1})
+	decoded, err := unmarshalQuantCode(code.marshal())
+	require.NoError(t, err)
+	assert.Equal(t, code, decoded)
+
+	_, err = unmarshalQuantCode([]byte{1, 2})
+	assert.Error(t, err)
+}
+
+func TestParseQuantizationMode(t *testing.T) {
+	for _, mode := range []QuantizationMode{QuantizationNone, QuantizationInt8, QuantizationBinary} {
+		parsed, err := ParseQuantizationMode(mode.String())
+		require.NoError(t, err)
+		assert.Equal(t, mode, parsed)
+	}
+	_, err := ParseQuantizationMode("int4")
+	assert.Error(t, err)
+}
+
+func TestFindSimilar_Quantized(t *testing.T) {
+	for _, mode := range []QuantizationMode{QuantizationInt8, QuantizationBinary} {
+		t.Run(mode.String(), func(t *testing.T) {
+			backend, err := OpenBackend("", true, WithQuantization(mode, 8))
+			require.NoError(t, err)
+			chatRepo, err := NewChatRepository(backend)
+			require.NoError(t, err)
+			defer func() {
+				chatRepo.Close()
+				backend.Close()
+			}()
+
+			ctx := context.Background()
+			rng := rand.New(rand.NewSource(3))
+			records := make([]*core.ChatRecord, 200)
+			for i := range records {
+				records[i] = &core.ChatRecord{
+					Speaker:   core.SpeakerTypeHuman,
+					Contents:  "message",
+					Timestamp: time.Now().UTC(),
+					Vector:    randomUnitVector(rng, 32),
+				}
+			}
+			added, err := chatRepo.AddChatRecords(ctx, records...)
+			require.NoError(t, err)
+			assert.Equal(t, len(records), countQuantCodes(t, backend))
+
+			// Querying with a stored vector returns that record with its exact score
+			query := added[42].Vector
+			results, err := backend.FindSimilar(ctx, query, 0.5, 3)
+			require.NoError(t, err)
+			require.NotEmpty(t, results)
+			assert.Equal(t, added[42].Id, results[0].Record.Id)
+			assert.InDelta(t, 1.0, results[0].Score, 0.0001)
+
+			// Chunks are quantized and rescored
+			err = chatRepo.ReplaceChunks(ctx, added[7].Id, []core.Chunk{
+				{Index: 0, Start: 0, End: 3, Vector: randomUnitVector(rng, 32)},
+				{Index: 1, Start: 4, End: 7, Vector: query},
+			})
+			require.NoError(t, err)
+			assert.Equal(t, len(records)+2, countQuantCodes(t, backend))
+
+			results, err = backend.FindSimilar(ctx, query, 0.99, 10)
+			require.NoError(t, err)
+			require.Len(t, results, 2)
+			for _, result := range results {
+				if result.Record.Id == added[7].Id {
+					assert.Equal(t, &core.Span{Start: 4, End: 7}, result.Span)
+				}
+			}
+
+			// Deleting removes codes
+			require.NoError(t, chatRepo.DeleteChatRecords(ctx, added[7].Id))
+			assert.Equal(t, len(records)-1, countQuantCodes(t, backend))
+		})
+	}
+}
+
+func TestQuantizeVectors_Migration(t *testing.T) {
+	path := t.TempDir()
+	ctx := context.Background()
+
+	// Write records without quantization
+	backend, err := OpenBackend(path, false)
+	require.NoError(t, err)
+	chatRepo, err := NewChatRepository(backend)
+	require.NoError(t, err)
+	added, err := chatRepo.AddChatRecords(ctx,
+		&core.ChatRecord{Speaker: core.SpeakerTypeHuman, Contents: "one", Timestamp: time.Now().UTC(), Vector: []float32{1, 0}},
+		&core.ChatRecord{Speaker: core.SpeakerTypeHuman, Contents: "two", Timestamp: time.Now().UTC(), Vector: []float32{0, 1}},
+	)
+	require.NoError(t, err)
+	require.NoError(t, chatRepo.ReplaceChunks(ctx, added[1].Id, []core.Chunk{
+		{Index: 0, Start: 0, End: 3, Vector: []float32{0.6, 0.8}},
+	}))
+	assert.Equal(t, 0, countQuantCodes(t, backend))
+	chatRepo.Close()
+	require.NoError(t, backend.Close())
+
+	// Opening with quantization migrates existing vectors
+	backend, err = OpenBackend(path, false, WithQuantization(QuantizationInt8, 0))
+	require.NoError(t, err)
+	assert.Equal(t, 3, countQuantCodes(t, backend))
+	results, err := backend.FindSimilar(ctx, []float32{0, 1}, 0.5, 10)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, added[1].Id, results[0].Record.Id)
+
+	// Running the migration again is idempotent
+	count, err := backend.QuantizeVectors(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 3, count)
+	assert.Equal(t, 3, countQuantCodes(t, backend))
+
+	// An interrupted run leaves the codes stale, so the next open regenerates
+	// them even though the mode is unchanged
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = backend.QuantizeVectors(canceled)
+	require.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 0, countQuantCodes(t, backend))
+	require.NoError(t, backend.Close())
+	backend, err = OpenBackend(path, false, WithQuantization(QuantizationInt8, 0))
+	require.NoError(t, err)
+	assert.Equal(t, 3, countQuantCodes(t, backend))
+	require.NoError(t, backend.Close())
+
+	// Writes without quantization leave the codes stale until the next migration
+	backend, err = OpenBackend(path, false)
+	require.NoError(t, err)
+	chatRepo, err = NewChatRepository(backend)
+	require.NoError(t, err)
+	_, err = chatRepo.AddChatRecords(ctx,
+		&core.ChatRecord{Speaker: core.SpeakerTypeHuman, Contents: "three", Timestamp: time.Now().UTC(), Vector: []float32{0.8, 0.6}},
+	)
+	require.NoError(t, err)
+	assert.Equal(t, 3, countQuantCodes(t, backend))
+	chatRepo.Close()
+	require.NoError(t, backend.Close())
+
+	backend, err = OpenBackend(path, false, WithQuantization(QuantizationBinary, 0))
+	require.NoError(t, err)
+	assert.Equal(t, 4, countQuantCodes(t, backend))
+	require.NoError(t, backend.Close())
+
+	// Migrating to no quantization removes the codes
+	backend, err = OpenBackend(path, false)
+	require.NoError(t, err)
+	count, err = backend.QuantizeVectors(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, count)
+	assert.Equal(t, 0, countQuantCodes(t, backend))
+	require.NoError(t, backend.Close())
+}
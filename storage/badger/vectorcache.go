@@ -0,0 +1,444 @@
+// Copyright 2025 Poiesic Systems
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+
+package badger
+
+import (
+	"bytes"
+	"container/heap"
+	"maps"
+	"runtime"
+	"slices"
+	"sync"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/poiesic/memorit/core"
+	"github.com/poiesic/memorit/storage"
+)
+
+const (
+	// minRowsPerWorker keeps small scans on a single goroutine where
+	// the cost of spawning workers would outweigh the parallel speedup.
+	minRowsPerWorker = 4096
+
+	// minCompactRows is the number of dead rows tolerated before compaction is considered.
+	minCompactRows = 1024
+)
+
+// vectorCache is an in-memory matrix of every record and chunk vector.
+// Rows are stored contiguously in a single []float32 so exact search can
+// scan them without touching BadgerDB or unmarshalling records.
+//
+// The rows of a record (its own vector followed by its chunk vectors) are
+// always adjacent. Replacing a record's vectors tombstones its old group
+// and appends a new one; dead rows are reclaimed by compaction.
+type vectorCache struct {
+	mu       sync.RWMutex
+	dim      int
+	data     []float32
+	groups   []vectorGroup
+	index    map[core.ID]int
+	attrs    map[core.ID]*core.ChatRecord // Filterable fields of every record, vectors or not
+	deadRows int
+	workers  int
+}
+
+// vectorGroup describes the adjacent rows belonging to a single record.
+type vectorGroup struct {
+	id        core.ID
+	row       int
+	hasVector bool        // first row is the whole-record vector
+	spans     []core.Span // one per chunk row
+	live      bool
+}
+
+// rows returns the number of matrix rows used by the group.
+func (g *vectorGroup) rows() int {
+	n := len(g.spans)
+	if g.hasVector {
+		n++
+	}
+	return n
+}
+
+// cacheHit is the best-scoring row of a record.
+type cacheHit struct {
+	id    core.ID
+	score float32
+	span  *core.Span
+}
+
+// newVectorCache creates an empty cache. workers <= 0 uses GOMAXPROCS.
+func newVectorCache(workers int) *vectorCache {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	return &vectorCache{
+		index:   make(map[core.ID]int),
+		attrs:   make(map[core.ID]*core.ChatRecord),
+		workers: workers,
+	}
+}
+
+// load fills the cache from the records and chunks stored in the database.
+func (c *vectorCache) load(db *badger.DB) error {
+	return db.View(func(tx *badger.Txn) error {
+		chunks := make(map[core.ID][]core.Chunk)
+
+		opts := badger.DefaultIteratorOptions
+		opts.Prefix = []byte(chatChunkPrefix + ":")
+		iter := tx.NewIterator(opts)
+		for iter.Rewind(); iter.Valid(); iter.Next() {
+			var chunk *core.Chunk
+			err := iter.Item().Value(func(val []byte) error {
+				var err error
+				chunk, err = storage.UnmarshalChunk(val)
+				return err
+			})
+			if err != nil {
+				iter.Close()
+				return err
+			}
+			chunks[chunk.RecordId] = append(chunks[chunk.RecordId], *chunk)
+		}
+		iter.Close()
+
+		opts = badger.DefaultIteratorOptions
+		opts.Prefix = []byte(chatRecordPrefix)
+		iter = tx.NewIterator(opts)
+		defer iter.Close()
+
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		for iter.Rewind(); iter.Valid(); iter.Next() {
+			key := iter.Item().Key()
+			if isChatIndexKey(key) {
+				continue
+			}
+			var record *core.ChatRecord
+			err := iter.Item().Value(func(val []byte) error {
+				var err error
+				record, err = storage.UnmarshalChatRecord(val)
+				return err
+			})
+			if err != nil {
+				return err
+			}
+			c.setAttributes(record)
+			c.putLocked(record.Id, record.Vector, chunks[record.Id])
+		}
+		return nil
+	})
+}
+
+// setAttributes records the fields of a record inspected by storage.RecordFilter.
+// Callers must hold the write lock.
+func (c *vectorCache) setAttributes(record *core.ChatRecord) {
+	c.attrs[record.Id] = &core.ChatRecord{
+		Id:        record.Id,
+		Speaker:   record.Speaker,
+		Timestamp: record.Timestamp,
+		Metadata:  maps.Clone(record.Metadata),
+	}
+}
+
+// setRecordVector replaces the whole-record vector of a record, keeping its chunks.
+// Callers must hold the write lock.
+func (c *vectorCache) setRecordVector(id core.ID, vector []float32) {
+	var chunks []core.Chunk
+	if idx, ok := c.index[id]; ok {
+		chunks = c.chunksLocked(&c.groups[idx])
+	}
+	c.putLocked(id, vector, chunks)
+}
+
+// setChunks replaces the chunk vectors of a record, keeping its whole-record vector.
+// Callers must hold the write lock.
+func (c *vectorCache) setChunks(id core.ID, chunks []core.Chunk) {
+	var vector []float32
+	if idx, ok := c.index[id]; ok && c.groups[idx].hasVector {
+		g := &c.groups[idx]
+		vector = slices.Clone(c.data[g.row*c.dim : (g.row+1)*c.dim])
+	}
+	c.putLocked(id, vector, chunks)
+}
+
+// remove drops all vectors of a record. Callers must hold the write lock.
+func (c *vectorCache) remove(id core.ID) {
+	delete(c.attrs, id)
+	idx, ok := c.index[id]
+	if !ok {
+		return
+	}
+	g := &c.groups[idx]
+	g.live = false
+	c.deadRows += g.rows()
+	delete(c.index, id)
+	c.maybeCompact()
+}
+
+// chunksLocked copies the chunk rows of a group back into chunks.
+func (c *vectorCache) chunksLocked(g *vectorGroup) []core.Chunk {
+	row := g.row
+	if g.hasVector {
+		row++
+	}
+	chunks := make([]core.Chunk, len(g.spans))
+	for i, span := range g.spans {
+		chunks[i] = core.Chunk{
+			RecordId: g.id,
+			Index:    i,
+			Start:    span.Start,
+			End:      span.End,
+			Vector:   slices.Clone(c.data[(row+i)*c.dim : (row+i+1)*c.dim]),
+		}
+	}
+	return chunks
+}
+
+// putLocked appends a new row group for a record, tombstoning any previous one.
+func (c *vectorCache) putLocked(id core.ID, vector []float32, chunks []core.Chunk) {
+	if idx, ok := c.index[id]; ok {
+		g := &c.groups[idx]
+		g.live = false
+		c.deadRows += g.rows()
+		delete(c.index, id)
+	}
+
+	group := vectorGroup{id: id, hasVector: len(vector) > 0, live: true}
+	rows := [][]float32{}
+	if group.hasVector {
+		rows = append(rows, vector)
+	}
+	for _, chunk := range chunks {
+		if len(chunk.Vector) == 0 {
+			continue
+		}
+		rows = append(rows, chunk.Vector)
+		group.spans = append(group.spans, chunk.Span())
+	}
+	if len(rows) == 0 {
+		c.maybeCompact()
+		return
+	}
+
+	for _, row := range rows {
+		if len(row) > c.dim {
+			c.resize(len(row))
+		}
+	}
+
+	group.row = len(c.data) / max(c.dim, 1)
+	for _, row := range rows {
+		start := len(c.data)
+		c.data = append(c.data, make([]float32, c.dim)...)
+		copy(c.data[start:], row)
+	}
+	c.index[id] = len(c.groups)
+	c.groups = append(c.groups, group)
+	c.maybeCompact()
+}
+
+// resize widens every row to dim. Shorter vectors are zero padded,
+// which matches dotProduct's behavior of ignoring the missing dimensions.
+func (c *vectorCache) resize(dim int) {
+	if c.dim == 0 {
+		c.dim = dim
+		return
+	}
+	rows := len(c.data) / c.dim
+	data := make([]float32, rows*dim)
+	for r := 0; r < rows; r++ {
+		copy(data[r*dim:], c.data[r*c.dim:(r+1)*c.dim])
+	}
+	c.data = data
+	c.dim = dim
+}
+
+// maybeCompact rewrites the matrix without dead rows once they
+// make up more than half of it.
+func (c *vectorCache) maybeCompact() {
+	if c.deadRows < minCompactRows || c.dim == 0 || c.deadRows*2 < len(c.data)/c.dim {
+		return
+	}
+	data := make([]float32, 0, len(c.data)-c.deadRows*c.dim)
+	groups := make([]vectorGroup, 0, len(c.index))
+	for _, g := range c.groups {
+		if !g.live {
+			continue
+		}
+		rows := c.data[g.row*c.dim : (g.row+g.rows())*c.dim]
+		g.row = len(data) / c.dim
+		data = append(data, rows...)
+		c.index[g.id] = len(groups)
+		groups = append(groups, g)
+	}
+	c.data = data
+	c.groups = groups
+	c.deadRows = 0
+}
+
+// search returns the limit best-scoring records at or above minSimilarity
+// that match filter, ordered by score descending. Each record is scored by its best row.
+func (c *vectorCache) search(vector []float32, minSimilarity float32, limit int, filter *storage.RecordFilter) []cacheHit {
+	return c.searchRecords(vector, minSimilarity, limit, filter, nil)
+}
+
+// searchRecords is search restricted to the records in ids, or every record if ids is nil.
+func (c *vectorCache) searchRecords(vector []float32, minSimilarity float32, limit int, filter *storage.RecordFilter, ids []core.ID) []cacheHit {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	groups := c.groups
+	rows := len(c.data) / max(c.dim, 1)
+	if ids != nil {
+		groups = make([]vectorGroup, 0, len(ids))
+		rows = 0
+		for _, id := range ids {
+			if idx, ok := c.index[id]; ok {
+				groups = append(groups, c.groups[idx])
+				rows += c.groups[idx].rows()
+			}
+		}
+	}
+	if limit <= 0 || len(groups) == 0 {
+		return nil
+	}
+
+	// Pad or trim the query so every row can be scored with the same stride
+	query := make([]float32, c.dim)
+	copy(query, vector)
+	if filter.IsZero() {
+		filter = nil
+	}
+
+	workers := min(c.workers, max(1, rows/minRowsPerWorker))
+	per := (len(groups) + workers - 1) / workers
+
+	heaps := make([]hitHeap, workers)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		start := w * per
+		end := min(start+per, len(groups))
+		if start >= end {
+			continue
+		}
+		wg.Add(1)
+		go func(h *hitHeap, groups []vectorGroup) {
+			defer wg.Done()
+			c.scan(h, groups, query, minSimilarity, limit, filter)
+		}(&heaps[w], groups[start:end])
+	}
+	wg.Wait()
+
+	// Merge the per-worker heaps
+	var merged hitHeap
+	for _, h := range heaps {
+		for _, hit := range h {
+			merged.offer(hit, limit)
+		}
+	}
+
+	slices.SortFunc(merged, func(a, b cacheHit) int {
+		if b.worse(a) {
+			return -1
+		}
+		if a.worse(b) {
+			return 1
+		}
+		return 0
+	})
+	return merged
+}
+
+// scan scores a contiguous range of groups into a bounded heap.
+func (c *vectorCache) scan(h *hitHeap, groups []vectorGroup, query []float32, minSimilarity float32, limit int, filter *storage.RecordFilter) {
+	dim := c.dim
+	for i := range groups {
+		g := &groups[i]
+		if !g.live {
+			continue
+		}
+		if filter != nil {
+			attrs, ok := c.attrs[g.id]
+			if !ok || !filter.Matches(attrs) {
+				continue
+			}
+		}
+		hit := cacheHit{id: g.id}
+		row := g.row
+		first := true
+		if g.hasVector {
+			hit.score = dotProduct(query, c.data[row*dim:(row+1)*dim])
+			first = false
+			row++
+		}
+		for s := range g.spans {
+			score := dotProduct(query, c.data[(row+s)*dim:(row+s+1)*dim])
+			if first || score > hit.score {
+				hit.score = score
+				hit.span = &g.spans[s]
+				first = false
+			}
+		}
+		if hit.score >= minSimilarity {
+			h.offer(hit, limit)
+		}
+	}
+}
+
+// worse reports whether h ranks below other: it scores lower, or scores the
+// same with a higher ID, so equal scores always keep the same records.
+func (h cacheHit) worse(other cacheHit) bool {
+	if h.score != other.score {
+		return h.score < other.score
+	}
+	return h.id > other.id
+}
+
+// hitHeap is a min-heap of hits, bounded by the caller to the best limit entries.
+type hitHeap []cacheHit
+
+func (h hitHeap) Len() int           { return len(h) }
+func (h hitHeap) Less(i, j int) bool { return h[i].worse(h[j]) }
+func (h hitHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *hitHeap) Push(x any)        { *h = append(*h, x.(cacheHit)) }
+func (h *hitHeap) Pop() any {
+	old := *h
+	n := len(old)
+	hit := old[n-1]
+	*h = old[:n-1]
+	return hit
+}
+
+// offer adds the hit if the heap has room or it beats the current worst hit.
+func (h *hitHeap) offer(hit cacheHit, limit int) {
+	if h.Len() < limit {
+		heap.Push(h, hit)
+		return
+	}
+	if (*h)[0].worse(hit) {
+		(*h)[0] = hit
+		heap.Fix(h, 0)
+	}
+}
+
+// isChatIndexKey reports whether a key under the chat record prefix
+// belongs to an index or the ID sequence rather than a record.
+func isChatIndexKey(key []byte) bool {
+	return bytes.Equal(key, []byte(chatRecordIDSeq)) ||
+		bytes.HasPrefix(key, []byte(chatRecordDatePrefix)) ||
+		bytes.HasPrefix(key, []byte(chatRecordConceptPrefix))
+}
@@ -0,0 +1,230 @@
+package badger
+
+import (
+	"context"
+	"math/rand"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/poiesic/memorit/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func openCachedRepository(t *testing.T, path string) (*ChatRepository, *Backend) {
+	t.Helper()
+	backend, err := OpenBackend(path, path == "", WithVectorCache(0))
+	require.NoError(t, err)
+	chatRepo, err := NewChatRepository(backend)
+	require.NoError(t, err)
+	return chatRepo, backend
+}
+
+func TestVectorCache_FindSimilarMatchesScan(t *testing.T) {
+	chatRepo, backend := openCachedRepository(t, "")
+	defer func() {
+		chatRepo.Close()
+		backend.Close()
+	}()
+
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	added, err := chatRepo.AddChatRecords(ctx,
+		&core.ChatRecord{Speaker: core.SpeakerTypeHuman, Contents: "first", Timestamp: now, Vector: []float32{1, 0, 0}},
+		&core.ChatRecord{Speaker: core.SpeakerTypeHuman, Contents: "second", Timestamp: now, Vector: []float32{0.8, 0.6, 0}},
+		&core.ChatRecord{Speaker: core.SpeakerTypeHuman, Contents: "third", Timestamp: now, Vector: []float32{0, 0, 1}},
+		&core.ChatRecord{Speaker: core.SpeakerTypeHuman, Contents: "no vector yet", Timestamp: now},
+	)
+	require.NoError(t, err)
+
+	query := []float32{1, 0, 0}
+	compare := func() {
+		cached, err := backend.FindSimilar(ctx, query, 0.1, 10)
+		require.NoError(t, err)
+
+		// Same query without the cache
+		cache := backend.cache
+		backend.cache = nil
+		scanned, err := backend.FindSimilar(ctx, query, 0.1, 10)
+		backend.cache = cache
+		require.NoError(t, err)
+
+		require.Equal(t, len(scanned), len(cached))
+		for i := range scanned {
+			assert.Equal(t, scanned[i].Record.Id, cached[i].Record.Id)
+			assert.InDelta(t, scanned[i].Score, cached[i].Score, 0.0001)
+			assert.Equal(t, scanned[i].Span, cached[i].Span)
+		}
+	}
+	compare()
+
+	// Updates are reflected
+	added[3].Vector = []float32{0.9, 0.1, 0}
+	_, err = chatRepo.UpdateChatRecords(ctx, added[3])
+	require.NoError(t, err)
+	compare()
+
+	// Chunks are reflected and survive a record vector update
+	err = chatRepo.ReplaceChunks(ctx, added[2].Id, []core.Chunk{
+		{Index: 0, Start: 0, End: 3, Vector: []float32{1, 0, 0}},
+	})
+	require.NoError(t, err)
+	added[2].Vector = []float32{0, 1, 0}
+	_, err = chatRepo.UpdateChatRecords(ctx, added[2])
+	require.NoError(t, err)
+	compare()
+
+	results, err := backend.FindSimilar(ctx, query, 0.1, 10)
+	require.NoError(t, err)
+	for _, result := range results {
+		if result.Record.Id == added[2].Id {
+			assert.Equal(t, &core.Span{Start: 0, End: 3}, result.Span)
+		}
+	}
+
+	// Deletes are reflected
+	require.NoError(t, chatRepo.DeleteChatRecords(ctx, added[0].Id, added[2].Id))
+	compare()
+}
+
+func TestVectorCache_LoadedAtOpen(t *testing.T) {
+	path := t.TempDir()
+	ctx := context.Background()
+
+	chatRepo, backend := openCachedRepository(t, path)
+	added, err := chatRepo.AddChatRecords(ctx,
+		&core.ChatRecord{Speaker: core.SpeakerTypeHuman, Contents: "stored", Timestamp: time.Now().UTC(), Vector: []float32{0, 1}},
+	)
+	require.NoError(t, err)
+	require.NoError(t, chatRepo.ReplaceChunks(ctx, added[0].Id, []core.Chunk{
+		{Index: 0, Start: 0, End: 3, Vector: []float32{1, 0}},
+	}))
+	chatRepo.Close()
+	require.NoError(t, backend.Close())
+
+	chatRepo, backend = openCachedRepository(t, path)
+	defer func() {
+		chatRepo.Close()
+		backend.Close()
+	}()
+
+	results, err := backend.FindSimilar(ctx, []float32{1, 0}, 0.5, 10)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, added[0].Id, results[0].Record.Id)
+	assert.Equal(t, &core.Span{Start: 0, End: 3}, results[0].Span)
+}
+
+func TestVectorCache_ParallelTopK(t *testing.T) {
+	cache := newVectorCache(4)
+	rng := rand.New(rand.NewSource(1))
+
+	const rows = 3 * minRowsPerWorker
+	vectors := make(map[core.ID][]float32, rows)
+	cache.mu.Lock()
+	for i := 1; i <= rows; i++ {
+		v := make([]float32, 8)
+		for d := range v {
+			v[d] = rng.Float32()*2 - 1
+		}
+		vectors[core.ID(i)] = v
+		cache.setRecordVector(core.ID(i), v)
+	}
+	cache.mu.Unlock()
+
+	query := []float32{1, 0.5, -0.25, 0, 0.1, 0.2, -0.3, 0.4}
+	hits := cache.search(query, -10, 25, nil)
+	require.Len(t, hits, 25)
+
+	// Compare with a full sort
+	type scored struct {
+		id    core.ID
+		score float32
+	}
+	var all []scored
+	for id, v := range vectors {
+		all = append(all, scored{id, dotProduct(query, v)})
+	}
+	slices.SortFunc(all, func(a, b scored) int {
+		if a.score > b.score {
+			return -1
+		}
+		if a.score < b.score {
+			return 1
+		}
+		return 0
+	})
+	for i, hit := range hits {
+		assert.Equal(t, all[i].id, hit.id)
+	}
+}
+
+func TestVectorCache_TiesKeepLowestIDs(t *testing.T) {
+	cache := newVectorCache(4)
+
+	// Every record scores the same across several workers, inserted out of order
+	const rows = 3 * minRowsPerWorker
+	cache.mu.Lock()
+	for i := rows; i >= 1; i-- {
+		cache.setRecordVector(core.ID(i), []float32{1, 0})
+	}
+	cache.mu.Unlock()
+
+	for range 3 {
+		hits := cache.search([]float32{1, 0}, 0.5, 10, nil)
+		require.Len(t, hits, 10)
+		for i, hit := range hits {
+			assert.Equal(t, core.ID(i+1), hit.id)
+		}
+	}
+}
+
+func TestVectorCache_Compaction(t *testing.T) {
+	cache := newVectorCache(1)
+	cache.mu.Lock()
+	for i := 0; i < 2*minCompactRows+10; i++ {
+		cache.setRecordVector(core.ID(1), []float32{float32(i), 1})
+		cache.setRecordVector(core.ID(2), []float32{1, float32(i)})
+	}
+	cache.remove(core.ID(2))
+	cache.mu.Unlock()
+
+	assert.Less(t, cache.deadRows, minCompactRows)
+	assert.Less(t, len(cache.groups), minCompactRows*2)
+
+	hits := cache.search([]float32{1, 0}, 0, 10, nil)
+	require.Len(t, hits, 1)
+	assert.Equal(t, core.ID(1), hits[0].id)
+	assert.Equal(t, float32(2*minCompactRows+9), hits[0].score)
+}
+
+func TestVectorCache_DimensionGrowth(t *testing.T) {
+	cache := newVectorCache(1)
+	cache.mu.Lock()
+	cache.setRecordVector(core.ID(1), []float32{1, 0})
+	cache.setRecordVector(core.ID(2), []float32{0, 0, 1})
+	cache.mu.Unlock()
+
+	hits := cache.search([]float32{1, 0, 1}, 0.5, 10, nil)
+	require.Len(t, hits, 2)
+	assert.Equal(t, float32(1), hits[0].score)
+	assert.Equal(t, float32(1), hits[1].score)
+}
+
+func TestVectorCache_SearchRecords(t *testing.T) {
+	cache := newVectorCache(1)
+	cache.mu.Lock()
+	cache.setRecordVector(core.ID(1), []float32{1, 0})
+	cache.setRecordVector(core.ID(2), []float32{0.9, 0.1})
+	cache.setRecordVector(core.ID(3), []float32{0.8, 0.2})
+	cache.mu.Unlock()
+
+	hits := cache.searchRecords([]float32{1, 0}, 0, 10, nil, []core.ID{3, 2, 99})
+	require.Len(t, hits, 2)
+	assert.Equal(t, core.ID(2), hits[0].id)
+	assert.Equal(t, core.ID(3), hits[1].id)
+
+	assert.Empty(t, cache.searchRecords([]float32{1, 0}, 0, 10, nil, []core.ID{}))
+}
@@ -0,0 +1,108 @@
+// Copyright 2025 Poiesic Systems
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+
+package storage
+
+import (
+	"slices"
+	"time"
+
+	"github.com/poiesic/memorit/core"
+)
+
+// MetadataOp is the comparison performed by a MetadataPredicate.
+type MetadataOp int
+
+const (
+	// MetadataEquals matches records whose metadata value equals the predicate value.
+	MetadataEquals MetadataOp = iota
+	// MetadataNotEquals matches records whose metadata value is missing or differs from the predicate value.
+	MetadataNotEquals
+	// MetadataExists matches records that have the metadata key.
+	MetadataExists
+	// MetadataNotExists matches records that do not have the metadata key.
+	MetadataNotExists
+)
+
+// MetadataPredicate tests a single metadata key of a chat record.
+type MetadataPredicate struct {
+	Key   string
+	Op    MetadataOp
+	Value string // Ignored by MetadataExists and MetadataNotExists
+}
+
+// Matches reports whether the metadata satisfies the predicate.
+func (p MetadataPredicate) Matches(metadata map[string]string) bool {
+	value, ok := metadata[p.Key]
+	switch p.Op {
+	case MetadataEquals:
+		return ok && value == p.Value
+	case MetadataNotEquals:
+		return !ok || value != p.Value
+	case MetadataExists:
+		return ok
+	case MetadataNotExists:
+		return !ok
+	default:
+		return false
+	}
+}
+
+// RecordFilter restricts which chat records a similarity search considers.
+// Every set field must match; the zero value matches every record.
+type RecordFilter struct {
+	Start         time.Time           // Inclusive lower bound on Timestamp; zero means unbounded
+	End           time.Time           // Exclusive upper bound on Timestamp; zero means unbounded
+	Speakers      []core.SpeakerType  // Allowed speakers; empty allows any speaker
+	Metadata      []MetadataPredicate // Predicates that must all hold
+	Conversations []string            // Allowed core.MetadataConversation values; empty allows any conversation
+	ExcludeIDs    []core.ID           // Records that never match
+}
+
+// IsZero reports whether the filter matches every record.
+func (f *RecordFilter) IsZero() bool {
+	return f == nil || (f.Start.IsZero() && f.End.IsZero() &&
+		len(f.Speakers) == 0 && len(f.Metadata) == 0 &&
+		len(f.Conversations) == 0 && len(f.ExcludeIDs) == 0)
+}
+
+// Matches reports whether the record passes the filter.
+// Only Id, Speaker, Timestamp and Metadata are inspected.
+func (f *RecordFilter) Matches(record *core.ChatRecord) bool {
+	if f == nil {
+		return true
+	}
+	if !f.Start.IsZero() && record.Timestamp.Before(f.Start) {
+		return false
+	}
+	if !f.End.IsZero() && !record.Timestamp.Before(f.End) {
+		return false
+	}
+	if len(f.Speakers) > 0 && !slices.Contains(f.Speakers, record.Speaker) {
+		return false
+	}
+	for _, predicate := range f.Metadata {
+		if !predicate.Matches(record.Metadata) {
+			return false
+		}
+	}
+	if len(f.Conversations) > 0 {
+		conversation, ok := record.Metadata[core.MetadataConversation]
+		if !ok || !slices.Contains(f.Conversations, conversation) {
+			return false
+		}
+	}
+	return !slices.Contains(f.ExcludeIDs, record.Id)
+}
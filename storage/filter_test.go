@@ -0,0 +1,69 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/poiesic/memorit/core"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecordFilter_Matches(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	record := &core.ChatRecord{
+		Id:        7,
+		Speaker:   core.SpeakerTypeHuman,
+		Timestamp: now,
+		Metadata: map[string]string{
+			"provider":                "slack",
+			core.MetadataConversation: "standup",
+		},
+	}
+
+	tests := []struct {
+		name   string
+		filter *RecordFilter
+		want   bool
+	}{
+		{"nil filter", nil, true},
+		{"zero filter", &RecordFilter{}, true},
+		{"start inclusive", &RecordFilter{Start: now}, true},
+		{"before start", &RecordFilter{Start: now.Add(time.Second)}, false},
+		{"end exclusive", &RecordFilter{End: now}, false},
+		{"before end", &RecordFilter{End: now.Add(time.Second)}, true},
+		{"speaker allowed", &RecordFilter{Speakers: []core.SpeakerType{core.SpeakerTypeAI, core.SpeakerTypeHuman}}, true},
+		{"speaker rejected", &RecordFilter{Speakers: []core.SpeakerType{core.SpeakerTypeAI}}, false},
+		{"metadata equals", &RecordFilter{Metadata: []MetadataPredicate{{Key: "provider", Value: "slack"}}}, true},
+		{"metadata differs", &RecordFilter{Metadata: []MetadataPredicate{{Key: "provider", Value: "email"}}}, false},
+		{"metadata not equals", &RecordFilter{Metadata: []MetadataPredicate{{Key: "provider", Op: MetadataNotEquals, Value: "email"}}}, true},
+		{"metadata exists", &RecordFilter{Metadata: []MetadataPredicate{{Key: "provider", Op: MetadataExists}}}, true},
+		{"metadata not exists", &RecordFilter{Metadata: []MetadataPredicate{{Key: "model", Op: MetadataNotExists}}}, true},
+		{"all predicates must hold", &RecordFilter{Metadata: []MetadataPredicate{
+			{Key: "provider", Value: "slack"},
+			{Key: "model", Op: MetadataExists},
+		}}, false},
+		{"conversation allowed", &RecordFilter{Conversations: []string{"standup", "retro"}}, true},
+		{"conversation rejected", &RecordFilter{Conversations: []string{"retro"}}, false},
+		{"excluded", &RecordFilter{ExcludeIDs: []core.ID{3, 7}}, false},
+		{"not excluded", &RecordFilter{ExcludeIDs: []core.ID{3}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.filter.Matches(record))
+		})
+	}
+
+	t.Run("conversation requires metadata", func(t *testing.T) {
+		filter := &RecordFilter{Conversations: []string{"standup"}}
+		assert.False(t, filter.Matches(&core.ChatRecord{Id: 1}))
+	})
+}
+
+func TestRecordFilter_IsZero(t *testing.T) {
+	var nilFilter *RecordFilter
+	assert.True(t, nilFilter.IsZero())
+	assert.True(t, (&RecordFilter{}).IsZero())
+	assert.False(t, (&RecordFilter{ExcludeIDs: []core.ID{1}}).IsZero())
+	assert.False(t, (&RecordFilter{End: time.Now()}).IsZero())
+}
@@ -28,6 +28,8 @@ type Repository interface {
 	// FindSimilar finds chat records similar to the given vector.
 	// Returns records with similarity >= minSimilarity, up to limit results.
 	// Results are ordered by similarity score (highest first).
+	// Records with chunk vectors are scored by their best-matching vector;
+	// SearchResult.Span identifies the chunk when it beat the whole-record vector.
 	FindSimilar(ctx context.Context, vector []float32, minSimilarity float32, limit int) ([]*core.SearchResult, error)
 
 	// WithTransaction executes a function within a transaction.
@@ -51,6 +53,7 @@ type ChatRepository interface {
 
 	// UpdateChatRecords updates existing chat records.
 	// Updates the UpdatedAt timestamp automatically.
+	// Drops the chunks of records whose Contents changed, since they no longer match.
 	// Returns ErrNotFound if any record doesn't exist.
 	UpdateChatRecords(ctx context.Context, records ...*core.ChatRecord) ([]*core.ChatRecord, error)
 
@@ -80,6 +83,13 @@ type ChatRepository interface {
 	// Returns up to limit records. Returns ErrNotFound if beforeID doesn't exist.
 	GetChatRecordsBeforeID(ctx context.Context, beforeID core.ID, limit int) ([]*core.ChatRecord, error)
 
+	// GetAdjacentChatRecords retrieves a record with up to before preceding and up to
+	// after following records that match filter, ordered by timestamp ascending.
+	// A nil filter matches every record. Implementations may bound how far they look
+	// for matching records, returning fewer when matches are sparse.
+	// Returns ErrNotFound if the record doesn't exist.
+	GetAdjacentChatRecords(ctx context.Context, id core.ID, before, after int, filter *RecordFilter) ([]*core.ChatRecord, error)
+
 	// GetChatRecordsByConcept retrieves IDs of chat records associated with a concept.
 	// Returns only record IDs, not full records.
 	GetChatRecordsByConcept(ctx context.Context, conceptID core.ID) ([]core.ID, error)
@@ -92,6 +102,29 @@ type ChatRepository interface {
 	// GetChatRecordsAfterID retrieves chat records with ID greater than afterID.
 	// Returns records ordered by ID ascending. Used for checkpoint recovery.
 	GetChatRecordsAfterID(ctx context.Context, afterID core.ID) ([]*core.ChatRecord, error)
+
+	// FindSimilarWithFilter finds chat records similar to the given vector that match filter.
+	// The filter is applied while scanning, so up to limit matching records are returned
+	// even when most of the closest records are filtered out. A nil filter matches every record.
+	FindSimilarWithFilter(ctx context.Context, vector []float32, minSimilarity float32, limit int, filter *RecordFilter) ([]*core.SearchResult, error)
+
+	// ReplaceChunks stores the chunk vectors for a chat record, removing any existing chunks.
+	// Passing no chunks clears the record's chunks.
+	// Returns ErrNotFound if the record doesn't exist.
+	ReplaceChunks(ctx context.Context, recordID core.ID, chunks []core.Chunk) error
+
+	// GetChunks retrieves the chunk vectors for a chat record, ordered by chunk index.
+	// Returns an empty slice if the record has no chunks.
+	GetChunks(ctx context.Context, recordID core.ID) ([]core.Chunk, error)
+}
+
+// ChangeTracker is implemented by repositories that can report when their
+// contents change, so callers can invalidate derived data such as cached
+// search results.
+type ChangeTracker interface {
+	// Version returns a counter that increases after every committed write.
+	// Equal versions mean no write happened in between.
+	Version() uint64
 }
 
 // CheckpointRepository provides operations for managing processor checkpoints.
@@ -141,6 +174,11 @@ type ConceptRepository interface {
 	// Thread-safe: handles concurrent creation attempts.
 	GetOrCreateConcept(ctx context.Context, name, conceptType string, vector []float32) (*core.Concept, error)
 
+	// FindSimilarConcepts finds concepts whose vectors are similar to the given vector.
+	// Returns concepts with similarity >= minSimilarity, up to limit results,
+	// ordered by similarity score (highest first). Concepts without vectors are skipped.
+	FindSimilarConcepts(ctx context.Context, vector []float32, minSimilarity float32, limit int) ([]*core.ConceptSearchResult, error)
+
 	// GetAllConcepts retrieves all concepts from storage.
 	// Used for operations that need to process all concepts, such as reembedding.
 	GetAllConcepts(ctx context.Context) ([]*core.Concept, error)
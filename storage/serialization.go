@@ -65,6 +65,22 @@ func UnmarshalConcept(data []byte) (*core.Concept, error) {
 	return &concept, nil
 }
 
+// MarshalChunk serializes a Chunk to bytes.
+func MarshalChunk(chunk *core.Chunk) []byte {
+	buf := make([]byte, core.ChunkMUS.Size(*chunk))
+	core.ChunkMUS.Marshal(*chunk, buf)
+	return buf
+}
+
+// UnmarshalChunk deserializes a Chunk from bytes.
+func UnmarshalChunk(data []byte) (*core.Chunk, error) {
+	chunk, _, err := core.ChunkMUS.Unmarshal(data)
+	if err != nil {
+		return nil, err
+	}
+	return &chunk, nil
+}
+
 // MarshalCheckpoint serializes a Checkpoint to bytes.
 func MarshalCheckpoint(checkpoint *core.Checkpoint) []byte {
 	buf := make([]byte, core.CheckpointMUS.Size(*checkpoint))
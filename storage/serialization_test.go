@@ -345,6 +345,25 @@ func TestUnmarshalConcept_Invalid(t *testing.T) {
 	}
 }
 
+func TestMarshalUnmarshalChunk(t *testing.T) {
+	chunk := &core.Chunk{
+		RecordId: core.ID(42),
+		Index:    3,
+		Start:    10,
+		End:      25,
+		Vector:   []float32{0.1, 0.2, 0.3},
+	}
+
+	data := MarshalChunk(chunk)
+
+	decoded, err := UnmarshalChunk(data)
+	require.NoError(t, err)
+	assert.Equal(t, chunk, decoded)
+
+	_, err = UnmarshalChunk([]byte{0xFF, 0xFF, 0xFF})
+	assert.Error(t, err)
+}
+
 func TestRoundTripConsistency(t *testing.T) {
 	t.Run("multiple marshal-unmarshal cycles", func(t *testing.T) {
 		now := time.Now().UTC().Truncate(time.Microsecond)